@@ -0,0 +1,110 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// Labels marking the ServerImages and BlockStorageSnapshots a BackupPolicy
+// created: the policy, the Provision backed up and the run, as Unix seconds.
+const (
+	BackupPolicyLabel    = "vm.cloudclub.io/backup-policy"
+	BackupProvisionLabel = "vm.cloudclub.io/backup-provision"
+	BackupRunLabel       = "vm.cloudclub.io/backup-run"
+)
+
+// BackupMethod is how a BackupPolicy backs up a server.
+// +kubebuilder:validation:Enum=ServerImage;Snapshot
+type BackupMethod string
+
+const (
+	// BackupMethodServerImage creates a ServerImage of the server.
+	BackupMethodServerImage BackupMethod = "ServerImage"
+	// BackupMethodSnapshot creates a BlockStorageSnapshot of each disk.
+	BackupMethodSnapshot BackupMethod = "Snapshot"
+)
+
+// BackupRetention keeps the newest backup of each of the last Daily days and
+// of the last Weekly weeks. Backups kept by either rule are not deleted.
+// +kubebuilder:validation:XValidation:rule="self.daily + self.weekly > 0",message="at least one backup must be kept"
+type BackupRetention struct {
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=7
+	Daily int `json:"daily"`
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=4
+	Weekly int `json:"weekly"`
+}
+
+// BackupPolicySpec defines the desired state of BackupPolicy
+type BackupPolicySpec struct {
+	// Selector selects the Provisions of the namespace to back up.
+	Selector metav1.LabelSelector `json:"selector"`
+	// Schedule is a cron expression, e.g. "0 2 * * *" for 02:00 every day.
+	Schedule string `json:"schedule"`
+	// TimeZone is the IANA time zone the schedule and the days and weeks of
+	// the retention are evaluated in.
+	// +kubebuilder:default=UTC
+	TimeZone string `json:"timeZone,omitempty"`
+	// +kubebuilder:default=Snapshot
+	Method BackupMethod `json:"method,omitempty"`
+	// +kubebuilder:default={daily: 7, weekly: 4}
+	Retention BackupRetention `json:"retention,omitempty"`
+	// Suspend stops creating backups. Existing ones are still pruned.
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// BackupPolicyStatus defines the observed state of BackupPolicy
+type BackupPolicyStatus struct {
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	NextScheduleTime *metav1.Time `json:"nextScheduleTime,omitempty"`
+	// Provisions is how many Provisions the last run backed up.
+	Provisions int `json:"provisions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+//+kubebuilder:printcolumn:name="Method",type=string,JSONPath=`.spec.method`
+//+kubebuilder:printcolumn:name="Last",type=date,JSONPath=`.status.lastScheduleTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// BackupPolicy is the Schema for the backuppolicies API. It backs up the
+// servers of the Provisions it selects on a cron schedule, as ServerImages
+// or BlockStorageSnapshots, and prunes old backups by its retention. Backups
+// are not owned by the policy and outlive it.
+type BackupPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BackupPolicySpec   `json:"spec,omitempty"`
+	Status BackupPolicyStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// BackupPolicyList contains a list of BackupPolicy
+type BackupPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BackupPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BackupPolicy{}, &BackupPolicyList{})
+}
@@ -0,0 +1,101 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// BlockStorageSource is the volume a snapshot is taken of: a disk of a
+// Provision's server or a Data volume.
+// +kubebuilder:validation:XValidation:rule="has(self.provisionName) != has(self.dataName)",message="exactly one of provisionName and dataName must be set"
+type BlockStorageSource struct {
+	// ProvisionName is a Provision in the same namespace.
+	ProvisionName string `json:"provisionName,omitempty"`
+	// Order picks the disk of the Provision's server by its block storage
+	// mapping order. Order 0 is the boot volume.
+	// +kubebuilder:validation:Minimum=0
+	Order int `json:"order,omitempty"`
+	// DataName is a Data in the same namespace.
+	DataName string `json:"dataName,omitempty"`
+}
+
+// BlockStorageSnapshotSpec defines the desired state of BlockStorageSnapshot
+type BlockStorageSnapshotSpec struct {
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="source is immutable"
+	Source      BlockStorageSource `json:"source"`
+	Description string             `json:"description,omitempty"`
+}
+
+type BlockStorageSnapshotPhase string
+
+const (
+	// BlockStorageSnapshotPhasePending waits for the source volume to exist.
+	BlockStorageSnapshotPhasePending   BlockStorageSnapshotPhase = "Pending"
+	BlockStorageSnapshotPhaseCreating  BlockStorageSnapshotPhase = "Creating"
+	BlockStorageSnapshotPhaseAvailable BlockStorageSnapshotPhase = "Available"
+)
+
+// BlockStorageSnapshotStatus defines the observed state of BlockStorageSnapshot
+type BlockStorageSnapshotStatus struct {
+	Phase BlockStorageSnapshotPhase `json:"phase,omitempty"`
+	// SnapshotInstanceNo is the number of the NCP block storage snapshot.
+	SnapshotInstanceNo string `json:"snapshotInstanceNo,omitempty"`
+	// RegionCode and BlockStorageInstanceNo record where the snapshot was
+	// taken, so it can be deleted after its source is gone.
+	RegionCode             string `json:"regionCode,omitempty"`
+	BlockStorageInstanceNo string `json:"blockStorageInstanceNo,omitempty"`
+	SnapshotName           string `json:"snapshotName,omitempty"`
+	// Size in GiB.
+	BlockStorageSize int `json:"blockStorageSize,omitempty"`
+	// Conditions include ConditionTypeFailed.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Snapshot",type=string,JSONPath=`.status.snapshotInstanceNo`
+//+kubebuilder:printcolumn:name="Size",type=integer,JSONPath=`.status.blockStorageSize`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// BlockStorageSnapshot is the Schema for the blockstoragesnapshots API. It
+// creates an NCP snapshot of a volume, which Data volumes and block storage
+// mappings of Provisions restore through snapshotRef. The snapshot is
+// deleted with the BlockStorageSnapshot.
+type BlockStorageSnapshot struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BlockStorageSnapshotSpec   `json:"spec,omitempty"`
+	Status BlockStorageSnapshotStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// BlockStorageSnapshotList contains a list of BlockStorageSnapshot
+type BlockStorageSnapshotList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BlockStorageSnapshot `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BlockStorageSnapshot{}, &BlockStorageSnapshotList{})
+}
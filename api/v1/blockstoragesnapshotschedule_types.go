@@ -0,0 +1,81 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// SnapshotScheduleLabel marks the BlockStorageSnapshots a schedule created
+// with the schedule's name.
+const SnapshotScheduleLabel = "vm.cloudclub.io/snapshot-schedule"
+
+// BlockStorageSnapshotScheduleSpec defines the desired state of BlockStorageSnapshotSchedule
+type BlockStorageSnapshotScheduleSpec struct {
+	// Schedule is a cron expression, e.g. "0 3 * * *" for 03:00 every day.
+	Schedule string `json:"schedule"`
+	// TimeZone is the IANA time zone the schedule is evaluated in.
+	// +kubebuilder:default=UTC
+	TimeZone string             `json:"timeZone,omitempty"`
+	Source   BlockStorageSource `json:"source"`
+	// Retention is how many of the schedule's snapshots are kept. The oldest
+	// are deleted once a new one is available.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=7
+	Retention int `json:"retention,omitempty"`
+	// Suspend stops creating snapshots. Existing ones are kept.
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// BlockStorageSnapshotScheduleStatus defines the observed state of BlockStorageSnapshotSchedule
+type BlockStorageSnapshotScheduleStatus struct {
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+	NextScheduleTime *metav1.Time `json:"nextScheduleTime,omitempty"`
+	// LastSnapshotName is the BlockStorageSnapshot created last.
+	LastSnapshotName string `json:"lastSnapshotName,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+//+kubebuilder:printcolumn:name="Retention",type=integer,JSONPath=`.spec.retention`
+//+kubebuilder:printcolumn:name="Last",type=date,JSONPath=`.status.lastScheduleTime`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// BlockStorageSnapshotSchedule is the Schema for the
+// blockstoragesnapshotschedules API. It creates BlockStorageSnapshots of a
+// volume on a cron schedule and keeps the newest of them.
+type BlockStorageSnapshotSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   BlockStorageSnapshotScheduleSpec   `json:"spec,omitempty"`
+	Status BlockStorageSnapshotScheduleStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// BlockStorageSnapshotScheduleList contains a list of BlockStorageSnapshotSchedule
+type BlockStorageSnapshotScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []BlockStorageSnapshotSchedule `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&BlockStorageSnapshotSchedule{}, &BlockStorageSnapshotScheduleList{})
+}
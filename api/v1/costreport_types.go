@@ -0,0 +1,86 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CostReportSpec defines the desired state of CostReport
+type CostReportSpec struct {
+	// Selector limits the report to the Provisions of its namespace with
+	// matching labels. All Provisions of the namespace are reported when unset.
+	Selector *metav1.LabelSelector `json:"selector,omitempty"`
+}
+
+// CostTotal sums the estimated costs in one currency.
+type CostTotal struct {
+	Currency string `json:"currency"`
+	Hourly   string `json:"hourly"`
+	Monthly  string `json:"monthly"`
+}
+
+// ProvisionCost is the estimated cost of one reported Provision.
+type ProvisionCost struct {
+	Name     string `json:"name"`
+	Currency string `json:"currency"`
+	Hourly   string `json:"hourly"`
+	Monthly  string `json:"monthly"`
+}
+
+// CostReportStatus defines the observed state of CostReport
+type CostReportStatus struct {
+	// +listType=map
+	// +listMapKey=currency
+	Totals []CostTotal `json:"totals,omitempty"`
+	// +listType=map
+	// +listMapKey=name
+	Provisions []ProvisionCost `json:"provisions,omitempty"`
+	// Unpriced lists the selected Provisions without a cost estimate, e.g.
+	// because no price is known for their server product.
+	Unpriced       []string     `json:"unpriced,omitempty"`
+	LastUpdateTime *metav1.Time `json:"lastUpdateTime,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Monthly",type=string,JSONPath=`.status.totals[0].monthly`
+//+kubebuilder:printcolumn:name="Currency",type=string,JSONPath=`.status.totals[0].currency`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// CostReport is the Schema for the costreports API. It sums the estimated
+// costs of the Provisions of its namespace.
+type CostReport struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CostReportSpec   `json:"spec,omitempty"`
+	Status CostReportStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// CostReportList contains a list of CostReport
+type CostReportList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CostReport `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CostReport{}, &CostReportList{})
+}
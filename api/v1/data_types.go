@@ -20,28 +20,77 @@ import (
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-// EDIT THIS FILE!  THIS IS SCAFFOLDING FOR YOU TO OWN!
-// NOTE: json tags are required.  Any new fields you add must have json tags for the fields to be serialized.
-
 // DataSpec defines the desired state of Data
+// +kubebuilder:validation:XValidation:rule="!(has(self.snapshotRef) && has(self.snapshotInstanceNo))",message="snapshotRef and snapshotInstanceNo are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="has(self.blockStorageSize) || has(self.snapshotRef) || has(self.snapshotInstanceNo)",message="blockStorageSize is required unless restoring a snapshot"
 type DataSpec struct {
-	// INSERT ADDITIONAL SPEC FIELDS - desired state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
-
-	// Foo is an example field of Data. Edit data_types.go to remove/update
-	Foo string `json:"foo,omitempty"`
+	// ProvisionName is the Provision in the same namespace whose server the
+	// volume is attached to.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="provisionName is immutable"
+	ProvisionName string `json:"provisionName"`
+	// SnapshotRef names a BlockStorageSnapshot in the same namespace to
+	// restore the volume from.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="snapshotRef is immutable"
+	SnapshotRef string `json:"snapshotRef,omitempty"`
+	// SnapshotInstanceNo restores the volume from an NCP snapshot that is not
+	// managed by a BlockStorageSnapshot.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="snapshotInstanceNo is immutable"
+	SnapshotInstanceNo string `json:"snapshotInstanceNo,omitempty"`
+	// Size in GiB. Defaults to the size of the snapshot when restoring.
+	// Raising it expands the volume; volumes cannot shrink.
+	// +kubebuilder:validation:Minimum=10
+	// +kubebuilder:validation:Maximum=2000
+	BlockStorageSize int `json:"blockStorageSize,omitempty"`
+	// BlockStorageName is the NCP name of the volume. It is derived from the
+	// namespace and name of the Data when unset.
+	BlockStorageName           string                 `json:"blockStorageName,omitempty"`
+	BlockStorageVolumeTypeCode BlockStorageVolumeType `json:"blockStorageVolumeTypeCode,omitempty"`
+	Description                string                 `json:"description,omitempty"`
 }
 
+type DataPhase string
+
+const (
+	// DataPhasePending waits for the Provision to have a server or for the
+	// snapshot to become available.
+	DataPhasePending   DataPhase = "Pending"
+	DataPhaseCreating  DataPhase = "Creating"
+	DataPhaseAvailable DataPhase = "Available"
+	// DataPhaseExpanding means the volume is growing to the spec's size.
+	DataPhaseExpanding DataPhase = "Expanding"
+	// DataPhaseLost means the volume no longer exists, e.g. because its
+	// server was terminated.
+	DataPhaseLost DataPhase = "Lost"
+)
+
 // DataStatus defines the observed state of Data
 type DataStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	Phase                  DataPhase `json:"phase,omitempty"`
+	BlockStorageInstanceNo string    `json:"blockStorageInstanceNo,omitempty"`
+	// RegionCode and ServerInstanceNo record where the volume was created,
+	// so it can be deleted after the Provision is gone.
+	RegionCode       string `json:"regionCode,omitempty"`
+	ServerInstanceNo string `json:"serverInstanceNo,omitempty"`
+	DeviceName       string `json:"deviceName,omitempty"`
+	// Size in GiB.
+	BlockStorageSize int `json:"blockStorageSize,omitempty"`
+	// Conditions include ConditionTypeFailed.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
 //+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Provision",type=string,JSONPath=`.spec.provisionName`
+//+kubebuilder:printcolumn:name="Volume",type=string,JSONPath=`.status.blockStorageInstanceNo`
+//+kubebuilder:printcolumn:name="Size",type=integer,JSONPath=`.status.blockStorageSize`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 
-// Data is the Schema for the data API
+// Data is the Schema for the data API. It is an additional NCP block
+// storage volume attached to the server of a Provision, empty or restored
+// from a snapshot. The volume is deleted with the Data.
 type Data struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -0,0 +1,132 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// LoadBalancerType is the NCP load balancer type code.
+// +kubebuilder:validation:Enum=APPLICATION;NETWORK;NETWORK_PROXY
+type LoadBalancerType string
+
+const (
+	LoadBalancerTypeApplication  LoadBalancerType = "APPLICATION"
+	LoadBalancerTypeNetwork      LoadBalancerType = "NETWORK"
+	LoadBalancerTypeNetworkProxy LoadBalancerType = "NETWORK_PROXY"
+)
+
+// LoadBalancerNetworkType decides whether a load balancer gets a public address.
+// +kubebuilder:validation:Enum=PUBLIC;PRIVATE
+type LoadBalancerNetworkType string
+
+// Listener forwards a port of the load balancer to a TargetGroup.
+// +kubebuilder:validation:XValidation:rule="!(self.protocol in ['HTTPS', 'TLS']) || has(self.sslCertificateNo)",message="sslCertificateNo is required for HTTPS and TLS"
+type Listener struct {
+	// +kubebuilder:validation:Enum=HTTP;HTTPS;TCP;UDP;TLS
+	Protocol string `json:"protocol"`
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65534
+	Port int32 `json:"port"`
+	// TargetGroupRef names a TargetGroup in the same namespace.
+	TargetGroupRef   string `json:"targetGroupRef"`
+	SSLCertificateNo string `json:"sslCertificateNo,omitempty"`
+}
+
+// LoadBalancerPhase is where a LoadBalancer is in its life cycle.
+type LoadBalancerPhase string
+
+const (
+	// LoadBalancerPhasePending waits for the target groups of the listeners.
+	LoadBalancerPhasePending LoadBalancerPhase = "Pending"
+	// LoadBalancerPhaseCreating waits for NCP to finish the load balancer.
+	LoadBalancerPhaseCreating  LoadBalancerPhase = "Creating"
+	LoadBalancerPhaseAvailable LoadBalancerPhase = "Available"
+)
+
+// LoadBalancerSpec defines the desired state of LoadBalancer. The load
+// balancer is created with it and does not follow later changes.
+// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="spec is immutable"
+type LoadBalancerSpec struct {
+	RegionCode string `json:"regionCode,omitempty"`
+	VpcNo      string `json:"vpcNo"`
+	// LoadBalancerName is the NCP name of the load balancer. It is derived
+	// from the namespace and name of the LoadBalancer when unset. A load
+	// balancer that already exists under the name is not taken over.
+	LoadBalancerName string `json:"loadBalancerName,omitempty"`
+	Description      string `json:"description,omitempty"`
+	// +kubebuilder:default=APPLICATION
+	Type LoadBalancerType `json:"type,omitempty"`
+	// +kubebuilder:default=PUBLIC
+	NetworkType LoadBalancerNetworkType `json:"networkType,omitempty"`
+	// SubnetNos are the load balancer subnets, one per zone.
+	// +kubebuilder:validation:MinItems=1
+	SubnetNos []string `json:"subnetNos"`
+	// +kubebuilder:validation:MinItems=1
+	Listeners []Listener `json:"listeners"`
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=3600
+	// +kubebuilder:default=60
+	IdleTimeoutSeconds int32 `json:"idleTimeoutSeconds,omitempty"`
+}
+
+// LoadBalancerStatus defines the observed state of LoadBalancer
+type LoadBalancerStatus struct {
+	Phase                  LoadBalancerPhase `json:"phase,omitempty"`
+	LoadBalancerInstanceNo string            `json:"loadBalancerInstanceNo,omitempty"`
+	// RegionCode records where the load balancer was created, so it can be
+	// deleted after the spec changed.
+	RegionCode       string   `json:"regionCode,omitempty"`
+	LoadBalancerName string   `json:"loadBalancerName,omitempty"`
+	Domain           string   `json:"domain,omitempty"`
+	IPs              []string `json:"ips,omitempty"`
+	// Conditions include ConditionTypeFailed.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.type`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Domain",type=string,JSONPath=`.status.domain`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// LoadBalancer is the Schema for the loadbalancers API. It creates an NCP
+// load balancer whose listeners forward to TargetGroups, and deletes it with
+// the LoadBalancer.
+type LoadBalancer struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   LoadBalancerSpec   `json:"spec,omitempty"`
+	Status LoadBalancerStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// LoadBalancerList contains a list of LoadBalancer
+type LoadBalancerList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []LoadBalancer `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&LoadBalancer{}, &LoadBalancerList{})
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// PlacementGroupType is the NCP placement group type code.
+// +kubebuilder:validation:Enum=AA
+type PlacementGroupType string
+
+const (
+	// PlacementGroupTypeAntiAffinity places each server on a different host.
+	PlacementGroupTypeAntiAffinity PlacementGroupType = "AA"
+)
+
+// PlacementGroupSpec defines the desired state of PlacementGroup
+type PlacementGroupSpec struct {
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="regionCode is immutable"
+	RegionCode string `json:"regionCode,omitempty"`
+	// PlacementGroupName is the NCP name of the group. It is derived from
+	// the namespace and name of the PlacementGroup when unset. A group that
+	// already exists under the name is not taken over.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="placementGroupName is immutable"
+	PlacementGroupName string `json:"placementGroupName,omitempty"`
+	// +kubebuilder:default=AA
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="placementGroupType is immutable"
+	PlacementGroupType PlacementGroupType `json:"placementGroupType,omitempty"`
+}
+
+// PlacementGroupStatus defines the observed state of PlacementGroup
+type PlacementGroupStatus struct {
+	PlacementGroupNo   string `json:"placementGroupNo,omitempty"`
+	PlacementGroupName string `json:"placementGroupName,omitempty"`
+	// RegionCode records where the group was created, so it can be deleted
+	// after the spec changed.
+	RegionCode string `json:"regionCode,omitempty"`
+	// Conditions include ConditionTypeFailed.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Type",type=string,JSONPath=`.spec.placementGroupType`
+//+kubebuilder:printcolumn:name="Number",type=string,JSONPath=`.status.placementGroupNo`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// PlacementGroup is the Schema for the placementgroups API. It creates an
+// NCP placement group, which Provisions join through placementGroupRef.
+// The group is deleted with the PlacementGroup once its servers are gone.
+type PlacementGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   PlacementGroupSpec   `json:"spec,omitempty"`
+	Status PlacementGroupStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// PlacementGroupList contains a list of PlacementGroup
+type PlacementGroupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []PlacementGroup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&PlacementGroup{}, &PlacementGroupList{})
+}
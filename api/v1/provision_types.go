@@ -31,13 +31,33 @@ type Server struct {
 	SpecCode         string `json:"serverSpecCode,omitempty"`
 }
 
+// BlockStorageVolumeType is the NCP volume type of a block storage.
+// +kubebuilder:validation:Enum=SSD;HDD;FB1
+type BlockStorageVolumeType string
+
+const (
+	BlockStorageVolumeTypeSSD BlockStorageVolumeType = "SSD"
+	BlockStorageVolumeTypeHDD BlockStorageVolumeType = "HDD"
+	BlockStorageVolumeTypeFB1 BlockStorageVolumeType = "FB1"
+)
+
+// BlockStorageMapping describes a disk created together with the server.
+// Order 0 is the boot volume, additional disks start at 1.
 type BlockStorageMapping struct {
-	BlockStorageName           string `json:"blockStorageMappingBlockStorageName,omitempty"`
-	BlockStorageSize           string `json:"blockStorageMappingBlockStorageSize,omitempty"`
-	BlockStorageVolumeTypeCode string `json:"blockStorageMappingBlockStorageVolumeTypeCode,omitempty"`
-	Encrypted                  string `json:"blockStorageMappingEncrypted,omitempty"`
-	Order                      int    `json:"blockStorageMappingList,omitempty"`
-	SnapshotInstanceNo         string `json:"blockStorageMappingSnapshotInstanceNo,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	Order              int    `json:"order"`
+	SnapshotInstanceNo string `json:"snapshotInstanceNo,omitempty"`
+	// SnapshotRef names a BlockStorageSnapshot in the same namespace to
+	// restore the disk from. It takes precedence over snapshotInstanceNo.
+	SnapshotRef string `json:"snapshotRef,omitempty"`
+	// Size in GiB. Raising it expands an additional disk of a created
+	// server; disks cannot shrink.
+	// +kubebuilder:validation:Minimum=10
+	// +kubebuilder:validation:Maximum=2000
+	BlockStorageSize           int                    `json:"blockStorageSize,omitempty"`
+	BlockStorageName           string                 `json:"blockStorageName,omitempty"`
+	BlockStorageVolumeTypeCode BlockStorageVolumeType `json:"blockStorageVolumeTypeCode,omitempty"`
+	Encrypted                  bool                   `json:"encrypted,omitempty"`
 }
 
 type NetworkInterface struct {
@@ -47,32 +67,214 @@ type NetworkInterface struct {
 	SubnetNo string `json:"networkInterfaceSubnetNo,omitempty"`
 }
 
+// AdoptSource identifies an existing NCP server a Provision takes ownership of
+// instead of creating a new one.
+// +kubebuilder:validation:XValidation:rule="has(self.serverInstanceNo) || has(self.serverName)",message="either serverInstanceNo or serverName must be set"
+type AdoptSource struct {
+	ServerInstanceNo string `json:"serverInstanceNo,omitempty"`
+	ServerName       string `json:"serverName,omitempty"`
+}
+
+// ZoneStrategy decides which allowed zone a new server is created in.
+// +kubebuilder:validation:Enum=Spread;Ordered
+type ZoneStrategy string
+
+const (
+	// ZoneStrategySpread picks the zone with the fewest servers among the
+	// Provisions the placement spreads across.
+	ZoneStrategySpread ZoneStrategy = "Spread"
+	// ZoneStrategyOrdered picks the first zone with a candidate subnet.
+	ZoneStrategyOrdered ZoneStrategy = "Ordered"
+)
+
+// ZonePlacement picks the zone of a new server and the subnet in that zone
+// from candidate subnets, so that a fleet spread across zones survives the
+// outage of one.
+type ZonePlacement struct {
+	// Zones are the zones servers may be created in, in order of
+	// preference, e.g. KR-1 and KR-2. Empty allows the zones of all
+	// candidate subnets, in their order.
+	Zones []string `json:"zones,omitempty"`
+	// SubnetNos are the candidate subnets. The zone of each is read from NCP.
+	// +kubebuilder:validation:MinItems=1
+	SubnetNos []string `json:"subnetNos"`
+	// +kubebuilder:default=Spread
+	Strategy ZoneStrategy `json:"strategy,omitempty"`
+	// SpreadSelector selects the Provisions in the namespace whose servers
+	// are counted when spreading. It defaults to the other replicas of the
+	// Provision's ProvisionSet.
+	SpreadSelector *metav1.LabelSelector `json:"spreadSelector,omitempty"`
+}
+
 // ProvisionSpec defines the desired state of Provision
+// +kubebuilder:validation:XValidation:rule="!has(self.placement) || !has(self.subnetNo)",message="placement and subnetNo are mutually exclusive"
 type ProvisionSpec struct {
-	RegionCode                        string              `json:"regionCode,omitempty"`
-	ServerInstanceNo                  string              `json:"serverInstanceNo,omitempty"`
-	ServerNo                          string              `json:"serverInstanceNoList.1,omitempty"`
-	AccessControlGroupNoListN         string              `json:"accessControlGroupNoList,omitempty"`
-	AssociateWithPublicIp             bool                `json:"associateWithPublicIp,omitempty"`
-	BlockDevicePartitionMountPoint    string              `json:"blockDevicePartitionMountPoint,omitempty"`
-	BlockDevicePartitionSize          string              `json:"blockDevicePartitionSize,omitempty"`
-	FeeSystemTypeCode                 string              `json:"feeSystemTypeCode,omitempty"`
-	InitScriptNo                      string              `json:"initScriptNo,omitempty"`
-	IsEncryptedBaseBlockStorageVolume bool                `json:"isEncryptedBaseBlockStorageVolume,omitempty"`
-	IsProtectServerTermination        bool                `json:"isProtectServerTermination,omitempty"`
-	LoginKeyName                      string              `json:"loginKeyName,omitempty"`
-	MemberServerImageInstanceNo       string              `json:"memberServerImageInstanceNo,omitempty"`
-	PlacementGroupNo                  string              `json:"placementGroupNo,omitempty"`
-	RAIDTypeName                      string              `json:"raidTypeName,omitempty"`
-	ResponseFormatType                string              `json:"responseFormatType,omitempty"`
-	SubnetNo                          string              `json:"subnetNo,omitempty"`
-	VpcNo                             string              `json:"vpcNo,omitempty"`
-	Server                            Server              `json:"server,omitempty"`
-	Phase                             ProvisionPhase      `json:"phase,omitempty"`
-	BlockStorageMapping               BlockStorageMapping `json:"blockStorageMapping,omitempty"`
-	NetworkInterface                  NetworkInterface    `json:"networkInterface,omitempty"`
+	RegionCode                        string                `json:"regionCode,omitempty"`
+	ServerInstanceNo                  string                `json:"serverInstanceNo,omitempty"`
+	ServerNo                          string                `json:"serverInstanceNoList.1,omitempty"`
+	AccessControlGroupNoListN         string                `json:"accessControlGroupNoList,omitempty"`
+	AssociateWithPublicIp             bool                  `json:"associateWithPublicIp,omitempty"`
+	BlockDevicePartitionMountPoint    string                `json:"blockDevicePartitionMountPoint,omitempty"`
+	BlockDevicePartitionSize          string                `json:"blockDevicePartitionSize,omitempty"`
+	FeeSystemTypeCode                 string                `json:"feeSystemTypeCode,omitempty"`
+	InitScriptNo                      string                `json:"initScriptNo,omitempty"`
+	IsEncryptedBaseBlockStorageVolume bool                  `json:"isEncryptedBaseBlockStorageVolume,omitempty"`
+	IsProtectServerTermination        bool                  `json:"isProtectServerTermination,omitempty"`
+	LoginKeyName                      string                `json:"loginKeyName,omitempty"`
+	MemberServerImageInstanceNo       string                `json:"memberServerImageInstanceNo,omitempty"`
+	PlacementGroupNo                  string                `json:"placementGroupNo,omitempty"`
+	RAIDTypeName                      string                `json:"raidTypeName,omitempty"`
+	ResponseFormatType                string                `json:"responseFormatType,omitempty"`
+	SubnetNo                          string                `json:"subnetNo,omitempty"`
+	VpcNo                             string                `json:"vpcNo,omitempty"`
+	Server                            Server                `json:"server,omitempty"`
+	Phase                             ProvisionPhase        `json:"phase,omitempty"`
+	BlockStorageMappings              []BlockStorageMapping `json:"blockStorageMappingList,omitempty"`
+	NetworkInterface                  NetworkInterface      `json:"networkInterface,omitempty"`
+	// Adopt takes over an existing server; unset spec fields are filled from it.
+	Adopt *AdoptSource `json:"adopt,omitempty"`
+	// DriftPolicy decides whether differences found on resync are only reported or also corrected.
+	// +kubebuilder:default=Report
+	DriftPolicy DriftPolicy `json:"driftPolicy,omitempty"`
+	// PowerSchedule starts and stops the server on a schedule while the
+	// phase is Create or Get. The Stop phase keeps the server stopped.
+	PowerSchedule *PowerSchedule `json:"powerSchedule,omitempty"`
+	// Expiry stops or terminates the server of an ephemeral Provision.
+	Expiry *Expiry `json:"expiry,omitempty"`
+	// ServerImageRef names a ServerImage in the same namespace to boot the
+	// server from. It takes precedence over memberServerImageInstanceNo.
+	ServerImageRef string `json:"serverImageRef,omitempty"`
+	// Resize controls how the Update phase applies a change of
+	// server.serverProductCode.
+	Resize *ResizePolicy `json:"resize,omitempty"`
+	// PlacementGroupRef names a PlacementGroup in the same namespace to
+	// create the server in. It takes precedence over placementGroupNo.
+	PlacementGroupRef string `json:"placementGroupRef,omitempty"`
+	// Placement chooses the zone and subnet of a new server instead of
+	// subnetNo.
+	Placement *ZonePlacement `json:"placement,omitempty"`
+}
+
+// ExpiryAction is what happens to the server of an expired Provision.
+// +kubebuilder:validation:Enum=Stop;Terminate
+type ExpiryAction string
+
+const (
+	ExpiryActionStop      ExpiryAction = "Stop"
+	ExpiryActionTerminate ExpiryAction = "Terminate"
+)
+
+// Expiry describes when a Provision expires and what happens then. With
+// both TTL and ExpiresAt set, the earlier applies. The ExpiresAtAnnotation
+// overrides both, e.g. to extend the life of a Provision.
+type Expiry struct {
+	// TTL is how long after its creation the Provision expires, e.g. 72h.
+	TTL *metav1.Duration `json:"ttl,omitempty"`
+	// ExpiresAt is when the Provision expires.
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	// +kubebuilder:default=Stop
+	Action ExpiryAction `json:"action,omitempty"`
+	// DeleteProvision deletes the Provision once its server is being
+	// terminated. It only applies to the Terminate action.
+	DeleteProvision bool `json:"deleteProvision,omitempty"`
+	// WarnBefore is how long before expiry a warning event is recorded.
+	// +kubebuilder:default="1h"
+	WarnBefore *metav1.Duration `json:"warnBefore,omitempty"`
+}
+
+// PowerSchedule describes when a server runs. The server is kept running
+// from each time Start fires until Stop fires next, and stopped otherwise.
+type PowerSchedule struct {
+	// Start is a standard five field cron expression, e.g. "0 8 * * 1-5".
+	Start string `json:"start"`
+	// Stop is a standard five field cron expression, e.g. "0 20 * * 1-5".
+	Stop string `json:"stop"`
+	// TimeZone is the IANA time zone the expressions are in, e.g. Asia/Seoul.
+	// +kubebuilder:default=UTC
+	TimeZone string `json:"timeZone,omitempty"`
+}
+
+// ResizePolicy controls a resize, which stops the server, changes its
+// product and starts it again if it was running.
+type ResizePolicy struct {
+	// PreStopHook is called before the server is stopped.
+	PreStopHook *PreStopHook `json:"preStopHook,omitempty"`
+	// MaintenanceWindow gates when a resize may begin. A resize that began
+	// runs to completion even after the window closed.
+	MaintenanceWindow *MaintenanceWindow `json:"maintenanceWindow,omitempty"`
+}
+
+// PreStopHook is an HTTP endpoint a resize POSTs to before stopping the
+// server, with the Provision, the server and both product codes as JSON.
+// The resize waits until the hook answers with a 2xx status.
+type PreStopHook struct {
+	// +kubebuilder:validation:Pattern=`^https?://`
+	URL string `json:"url"`
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=30
+	TimeoutSeconds int32 `json:"timeoutSeconds,omitempty"`
+	// IgnoreFailure stops the server even when the hook failed.
+	IgnoreFailure bool `json:"ignoreFailure,omitempty"`
+}
+
+// MaintenanceWindow opens each time Start fires and stays open for Duration.
+type MaintenanceWindow struct {
+	// Start is a standard five field cron expression, e.g. "0 2 * * 6".
+	Start string `json:"start"`
+	// Duration is how long the window stays open, e.g. 4h.
+	Duration metav1.Duration `json:"duration"`
+	// TimeZone is the IANA time zone Start is in, e.g. Asia/Seoul.
+	// +kubebuilder:default=UTC
+	TimeZone string `json:"timeZone,omitempty"`
 }
 
+// ResizeStep is the step a resize in progress is at.
+type ResizeStep string
+
+const (
+	ResizeStepWaitingForWindow ResizeStep = "WaitingForWindow"
+	ResizeStepPreStop          ResizeStep = "PreStop"
+	ResizeStepStopping         ResizeStep = "Stopping"
+	ResizeStepChangingSpec     ResizeStep = "ChangingSpec"
+	ResizeStepStarting         ResizeStep = "Starting"
+)
+
+// ResizeStatus tracks a resize in progress.
+type ResizeStatus struct {
+	Step            ResizeStep `json:"step"`
+	FromProductCode string     `json:"fromProductCode"`
+	ToProductCode   string     `json:"toProductCode"`
+	// Restart is whether the server was running and is started again.
+	Restart   bool        `json:"restart,omitempty"`
+	StartTime metav1.Time `json:"startTime"`
+	// NextWindowTime is when the maintenance window opens next while the
+	// resize waits for it.
+	NextWindowTime *metav1.Time `json:"nextWindowTime,omitempty"`
+}
+
+// PowerAction is a change of the power state of a server.
+type PowerAction string
+
+const (
+	PowerActionStart PowerAction = "Start"
+	PowerActionStop  PowerAction = "Stop"
+)
+
+// PowerTransition is a scheduled change of the power state of a server.
+type PowerTransition struct {
+	Action PowerAction `json:"action"`
+	Time   metav1.Time `json:"time"`
+}
+
+// DriftPolicy is what the controller does when the live server differs from the spec.
+// +kubebuilder:validation:Enum=Report;Correct
+type DriftPolicy string
+
+const (
+	DriftPolicyReport  DriftPolicy = "Report"
+	DriftPolicyCorrect DriftPolicy = "Correct"
+)
+
 type ProvisionPhase string
 
 const (
@@ -84,9 +286,81 @@ const (
 	JobIsSuccess         ProvisionPhase = "Success"
 )
 
+// BlockStorageStatus reports a volume created for one of the spec's block storage mappings.
+type BlockStorageStatus struct {
+	Order                  int    `json:"order"`
+	BlockStorageInstanceNo string `json:"blockStorageInstanceNo,omitempty"`
+	BlockStorageName       string `json:"blockStorageName,omitempty"`
+	DeviceName             string `json:"deviceName,omitempty"`
+	// Size in GiB.
+	BlockStorageSize int `json:"blockStorageSize,omitempty"`
+}
+
+// Condition types reported on a Provision.
+const (
+	// ConditionTypeDrifted is True while the live server differs from the spec.
+	ConditionTypeDrifted = "Drifted"
+	// ConditionTypeFailed is True when NCP rejected a request in a way retrying
+	// cannot fix, e.g. an exhausted quota, or the spec breaks a ProvisionPolicy.
+	// It is cleared by changing the spec.
+	ConditionTypeFailed = "Failed"
+	// ConditionTypeExpired is True once the server of an expired Provision
+	// was stopped or terminated. Extending the expiry resumes reconciling.
+	ConditionTypeExpired = "Expired"
+)
+
+// CreationTokenAnnotation holds the name of the server a Provision is
+// creating. It is written before the server is requested from NCP, so a
+// retried create finds the server instead of requesting a second one.
+const CreationTokenAnnotation = "vm.cloudclub.io/creation-token"
+
+// ExpiresAtAnnotation overrides when a Provision with an expiry expires, as
+// an RFC 3339 timestamp, e.g. "2024-01-31T18:00:00+09:00".
+const ExpiresAtAnnotation = "vm.cloudclub.io/expires-at"
+
+// CostEstimate is what a server and its additional block storages are
+// estimated to cost at list or price file prices while running. Amounts are
+// decimal strings in Currency.
+type CostEstimate struct {
+	Currency string `json:"currency"`
+	Hourly   string `json:"hourly"`
+	Monthly  string `json:"monthly"`
+	// EstimatedTime is when the amounts last changed.
+	EstimatedTime metav1.Time `json:"estimatedTime"`
+}
+
 // ProvisionStatus defines the observed state of Provision
 type ProvisionStatus struct {
-	Phase ProvisionPhase `json:"phase,omitempty"`
+	Phase            ProvisionPhase       `json:"phase,omitempty"`
+	ServerInstanceNo string               `json:"serverInstanceNo,omitempty"`
+	BlockStorages    []BlockStorageStatus `json:"blockStorageList,omitempty"`
+	// ZoneCode is the zone of the server.
+	ZoneCode string `json:"zoneCode,omitempty"`
+	// SubnetNo is the subnet the placement chose for the server.
+	SubnetNo string `json:"subnetNo,omitempty"`
+	// ServerTagsHash identifies the tags last applied to the server. They
+	// are only read back and synced again when it changes.
+	ServerTagsHash string `json:"serverTagsHash,omitempty"`
+	// ServerStatus is the NCP server instance status code seen on the last resync, e.g. RUN or NSTOP.
+	ServerStatus string       `json:"serverStatus,omitempty"`
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+	// NextPowerTransition is when the power schedule next starts or stops the server.
+	NextPowerTransition *PowerTransition `json:"nextPowerTransition,omitempty"`
+	// LastBackupTime is when the newest complete backup a BackupPolicy took
+	// of the server was scheduled.
+	LastBackupTime *metav1.Time `json:"lastBackupTime,omitempty"`
+	// ExpiresAt is when the Provision expires, from its expiry or the
+	// ExpiresAtAnnotation.
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+	// ExpiryWarningTime is when the warning about ExpiresAt was recorded.
+	ExpiryWarningTime *metav1.Time `json:"expiryWarningTime,omitempty"`
+	// Resize is the resize in progress, if any.
+	Resize *ResizeStatus `json:"resize,omitempty"`
+	// Cost is the estimated price of the server and its additional block storages.
+	Cost *CostEstimate `json:"cost,omitempty"`
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
 //+kubebuilder:object:root=true
@@ -0,0 +1,82 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ProvisionPolicyField is the Provision spec field a rule restricts.
+// +kubebuilder:validation:Enum=RegionCode;ServerImageProductCode;ServerProductCode;VpcNo;FeeSystemTypeCode
+type ProvisionPolicyField string
+
+const (
+	ProvisionPolicyFieldRegionCode             ProvisionPolicyField = "RegionCode"
+	ProvisionPolicyFieldServerImageProductCode ProvisionPolicyField = "ServerImageProductCode"
+	ProvisionPolicyFieldServerProductCode      ProvisionPolicyField = "ServerProductCode"
+	ProvisionPolicyFieldVpcNo                  ProvisionPolicyField = "VpcNo"
+	ProvisionPolicyFieldFeeSystemTypeCode      ProvisionPolicyField = "FeeSystemTypeCode"
+)
+
+// ProvisionPolicyRule restricts the values of one field. Patterns are
+// path.Match patterns, and an unset field is matched as the empty string.
+// +kubebuilder:validation:XValidation:rule="has(self.allow) || has(self.deny)",message="either allow or deny must be set"
+type ProvisionPolicyRule struct {
+	// Name identifies the rule in rejection messages.
+	Name  string               `json:"name"`
+	Field ProvisionPolicyField `json:"field"`
+	// Allow, when set, lists the only values the field may have.
+	Allow []string `json:"allow,omitempty"`
+	// Deny lists values the field may not have, even when allowed.
+	Deny []string `json:"deny,omitempty"`
+}
+
+// ProvisionPolicySpec defines the desired state of ProvisionPolicy
+type ProvisionPolicySpec struct {
+	// NamespaceSelector selects the namespaces whose Provisions the policy
+	// applies to. It applies to every namespace when unset.
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+	// +listType=map
+	// +listMapKey=name
+	Rules []ProvisionPolicyRule `json:"rules"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:scope=Cluster
+
+// ProvisionPolicy is the Schema for the provisionpolicies API. It restricts
+// the regions, images, server products, VPCs and fee types Provisions may
+// use; a Provision has to satisfy every policy that selects its namespace.
+type ProvisionPolicy struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec ProvisionPolicySpec `json:"spec,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ProvisionPolicyList contains a list of ProvisionPolicy
+type ProvisionPolicyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProvisionPolicy `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ProvisionPolicy{}, &ProvisionPolicyList{})
+}
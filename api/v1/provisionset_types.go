@@ -0,0 +1,92 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ProvisionSetLabel marks the Provisions of a ProvisionSet with its name.
+const ProvisionSetLabel = "vm.cloudclub.io/provision-set"
+
+// ProvisionTemplate is the Provision each replica of a ProvisionSet is created from.
+// +kubebuilder:validation:XValidation:rule="!has(self.spec.serverInstanceNo) && !has(self.spec.adopt)",message="a template cannot name an existing server"
+type ProvisionTemplate struct {
+	// Labels and Annotations are copied to each replica.
+	Labels      map[string]string `json:"labels,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+	Spec        ProvisionSpec     `json:"spec"`
+}
+
+// ProvisionSetSpec defines the desired state of ProvisionSet
+type ProvisionSetSpec struct {
+	// Replicas is how many Provisions the set keeps, named <set>-<index>.
+	// +kubebuilder:validation:Minimum=0
+	// +kubebuilder:default=1
+	Replicas int32 `json:"replicas"`
+	// Template is copied into replicas when they are created. Changing it
+	// does not affect existing replicas.
+	Template ProvisionTemplate `json:"template"`
+	// PlacementGroupRef names a PlacementGroup in the same namespace to
+	// spread the replicas across. When it is unset and the template sets no
+	// placement group, the set creates an anti-affinity PlacementGroup of
+	// its own, unless SpreadReplicas is false.
+	PlacementGroupRef string `json:"placementGroupRef,omitempty"`
+	// +kubebuilder:default=true
+	SpreadReplicas *bool `json:"spreadReplicas,omitempty"`
+}
+
+// ProvisionSetStatus defines the observed state of ProvisionSet
+type ProvisionSetStatus struct {
+	Replicas int32 `json:"replicas"`
+	// ReadyReplicas counts the replicas whose server is running.
+	ReadyReplicas int32 `json:"readyReplicas"`
+	// PlacementGroupName is the PlacementGroup the replicas are spread across.
+	PlacementGroupName string `json:"placementGroupName,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:subresource:scale:specpath=.spec.replicas,statuspath=.status.replicas
+//+kubebuilder:printcolumn:name="Desired",type=integer,JSONPath=`.spec.replicas`
+//+kubebuilder:printcolumn:name="Current",type=integer,JSONPath=`.status.replicas`
+//+kubebuilder:printcolumn:name="Ready",type=integer,JSONPath=`.status.readyReplicas`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ProvisionSet is the Schema for the provisionsets API. It keeps a number of
+// Provisions created from a template, spread across a placement group. A
+// placement in the template also spreads them across zones.
+type ProvisionSet struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ProvisionSetSpec   `json:"spec,omitempty"`
+	Status ProvisionSetStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ProvisionSetList contains a list of ProvisionSet
+type ProvisionSetList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ProvisionSet `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ProvisionSet{}, &ProvisionSetList{})
+}
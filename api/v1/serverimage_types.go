@@ -0,0 +1,94 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// ServerImageSpec defines the desired state of ServerImage
+type ServerImageSpec struct {
+	// ProvisionName is the Provision in the same namespace whose server the
+	// image is created from.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="provisionName is immutable"
+	ProvisionName string `json:"provisionName"`
+	// ImageName is the NCP name of the image. It is derived from the
+	// namespace and name of the ServerImage when unset.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="imageName is immutable"
+	ImageName   string `json:"imageName,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+type ServerImagePhase string
+
+const (
+	// ServerImagePhasePending waits for the Provision to have a server.
+	ServerImagePhasePending   ServerImagePhase = "Pending"
+	ServerImagePhaseCreating  ServerImagePhase = "Creating"
+	ServerImagePhaseAvailable ServerImagePhase = "Available"
+	// ServerImagePhaseFailed means NCP failed to create the image, or
+	// reports it in a status the controller does not know.
+	ServerImagePhaseFailed ServerImagePhase = "Failed"
+)
+
+// ServerImageStatus defines the observed state of ServerImage
+type ServerImageStatus struct {
+	Phase ServerImagePhase `json:"phase,omitempty"`
+	// MemberServerImageInstanceNo is the number of the NCP member server image.
+	MemberServerImageInstanceNo string `json:"memberServerImageInstanceNo,omitempty"`
+	// RegionCode and ServerInstanceNo record where the image was created
+	// from, so it can be deleted after the Provision is gone.
+	RegionCode       string `json:"regionCode,omitempty"`
+	ServerInstanceNo string `json:"serverInstanceNo,omitempty"`
+	ImageName        string `json:"imageName,omitempty"`
+	// Conditions include ConditionTypeFailed.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Provision",type=string,JSONPath=`.spec.provisionName`
+//+kubebuilder:printcolumn:name="Image",type=string,JSONPath=`.status.memberServerImageInstanceNo`
+//+kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// ServerImage is the Schema for the serverimages API. It creates an NCP
+// member server image from the server of a Provision, which other
+// Provisions boot from through spec.serverImageRef. The image is deleted
+// with the ServerImage.
+type ServerImage struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   ServerImageSpec   `json:"spec,omitempty"`
+	Status ServerImageStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// ServerImageList contains a list of ServerImage
+type ServerImageList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []ServerImage `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&ServerImage{}, &ServerImageList{})
+}
@@ -0,0 +1,131 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// TargetGroupProtocol is the protocol a target group forwards with.
+// +kubebuilder:validation:Enum=TCP;UDP;HTTP;HTTPS;PROXY_TCP
+type TargetGroupProtocol string
+
+// HealthCheck is how a target group decides whether a target is healthy.
+type HealthCheck struct {
+	// +kubebuilder:validation:Enum=TCP;HTTP;HTTPS
+	// +kubebuilder:default=TCP
+	Protocol string `json:"protocol,omitempty"`
+	// Port defaults to the port of the target group.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65534
+	Port int32 `json:"port,omitempty"`
+	// URLPath and HTTPMethod apply to HTTP and HTTPS checks.
+	URLPath string `json:"urlPath,omitempty"`
+	// +kubebuilder:validation:Enum=HEAD;GET
+	HTTPMethod string `json:"httpMethod,omitempty"`
+	// +kubebuilder:validation:Minimum=5
+	// +kubebuilder:validation:Maximum=300
+	// +kubebuilder:default=30
+	CycleSeconds int32 `json:"cycleSeconds,omitempty"`
+	// +kubebuilder:validation:Minimum=2
+	// +kubebuilder:validation:Maximum=10
+	// +kubebuilder:default=2
+	UpThreshold int32 `json:"upThreshold,omitempty"`
+	// +kubebuilder:validation:Minimum=2
+	// +kubebuilder:validation:Maximum=10
+	// +kubebuilder:default=2
+	DownThreshold int32 `json:"downThreshold,omitempty"`
+}
+
+// TargetGroupSpec defines the desired state of TargetGroup. Only the
+// selector can change once the target group is created.
+type TargetGroupSpec struct {
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="regionCode is immutable"
+	RegionCode string `json:"regionCode,omitempty"`
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="vpcNo is immutable"
+	VpcNo string `json:"vpcNo"`
+	// TargetGroupName is the NCP name of the group. It is derived from the
+	// namespace and name of the TargetGroup when unset. A group that already
+	// exists under the name is not taken over.
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="targetGroupName is immutable"
+	TargetGroupName string `json:"targetGroupName,omitempty"`
+	Description     string `json:"description,omitempty"`
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="protocol is immutable"
+	Protocol TargetGroupProtocol `json:"protocol"`
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:validation:Maximum=65534
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="port is immutable"
+	Port int32 `json:"port"`
+	// +kubebuilder:default={protocol: TCP}
+	// +kubebuilder:validation:XValidation:rule="self == oldSelf",message="healthCheck is immutable"
+	HealthCheck HealthCheck `json:"healthCheck,omitempty"`
+	// Selector selects the Provisions in the namespace whose servers are
+	// targets. A server is a target while it is running and its Provision
+	// is not being deleted.
+	Selector metav1.LabelSelector `json:"selector"`
+}
+
+// Target is a server of the target group and the Provision it belongs to.
+type Target struct {
+	ProvisionName    string `json:"provisionName"`
+	ServerInstanceNo string `json:"serverInstanceNo"`
+}
+
+// TargetGroupStatus defines the observed state of TargetGroup
+type TargetGroupStatus struct {
+	TargetGroupNo   string `json:"targetGroupNo,omitempty"`
+	TargetGroupName string `json:"targetGroupName,omitempty"`
+	// RegionCode records where the group was created, so it can be deleted
+	// after the spec changed.
+	RegionCode string   `json:"regionCode,omitempty"`
+	Targets    []Target `json:"targets,omitempty"`
+	// Conditions include ConditionTypeFailed.
+	// +listType=map
+	// +listMapKey=type
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Protocol",type=string,JSONPath=`.spec.protocol`
+//+kubebuilder:printcolumn:name="Port",type=integer,JSONPath=`.spec.port`
+//+kubebuilder:printcolumn:name="Number",type=string,JSONPath=`.status.targetGroupNo`
+//+kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// TargetGroup is the Schema for the targetgroups API. It creates an NCP
+// target group and keeps the running servers of the Provisions it selects
+// as its targets. LoadBalancers forward to it from their listeners.
+type TargetGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   TargetGroupSpec   `json:"spec,omitempty"`
+	Status TargetGroupStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// TargetGroupList contains a list of TargetGroup
+type TargetGroupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []TargetGroup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&TargetGroup{}, &TargetGroupList{})
+}
@@ -0,0 +1,92 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// VMQuotaLimits caps what the Provisions of a namespace may use together.
+// Unset limits are not enforced.
+type VMQuotaLimits struct {
+	// +kubebuilder:validation:Minimum=0
+	Servers *int64 `json:"servers,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	VCPU *int64 `json:"vcpu,omitempty"`
+	// +kubebuilder:validation:Minimum=0
+	MemoryGiB *int64 `json:"memoryGiB,omitempty"`
+	// StorageGiB limits the boot and additional block storages together.
+	// +kubebuilder:validation:Minimum=0
+	StorageGiB *int64 `json:"storageGiB,omitempty"`
+}
+
+// VMQuotaSpec defines the desired state of VMQuota
+type VMQuotaSpec struct {
+	Hard VMQuotaLimits `json:"hard,omitempty"`
+	// AllowedServerProductCodes are the server products Provisions may use,
+	// as path.Match patterns such as SVR.VSVR.STAND.*. Any product is allowed
+	// when empty.
+	AllowedServerProductCodes []string `json:"allowedServerProductCodes,omitempty"`
+	// AllowedServerImageProductCodes are the server images Provisions may
+	// use, as path.Match patterns. Any image is allowed when empty.
+	AllowedServerImageProductCodes []string `json:"allowedServerImageProductCodes,omitempty"`
+}
+
+// VMQuotaUsage is what the Provisions of a namespace use. vCPU and memory
+// are read from the server product codes.
+type VMQuotaUsage struct {
+	Servers    int64 `json:"servers"`
+	VCPU       int64 `json:"vcpu"`
+	MemoryGiB  int64 `json:"memoryGiB"`
+	StorageGiB int64 `json:"storageGiB"`
+}
+
+// VMQuotaStatus defines the observed state of VMQuota
+type VMQuotaStatus struct {
+	Used VMQuotaUsage `json:"used,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+//+kubebuilder:resource:path=vmquotas
+//+kubebuilder:subresource:status
+//+kubebuilder:printcolumn:name="Servers",type=integer,JSONPath=`.status.used.servers`
+//+kubebuilder:printcolumn:name="Max Servers",type=integer,JSONPath=`.spec.hard.servers`
+//+kubebuilder:printcolumn:name="vCPU",type=integer,JSONPath=`.status.used.vcpu`
+//+kubebuilder:printcolumn:name="Max vCPU",type=integer,JSONPath=`.spec.hard.vcpu`
+
+// VMQuota is the Schema for the vmquotas API. It limits the servers the
+// Provisions of its namespace create; every VMQuota of a namespace applies.
+type VMQuota struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   VMQuotaSpec   `json:"spec,omitempty"`
+	Status VMQuotaStatus `json:"status,omitempty"`
+}
+
+//+kubebuilder:object:root=true
+
+// VMQuotaList contains a list of VMQuota
+type VMQuotaList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []VMQuota `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&VMQuota{}, &VMQuotaList{})
+}
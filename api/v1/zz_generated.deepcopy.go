@@ -21,45 +21,1345 @@ limitations under the License.
 package v1
 
 import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	runtime "k8s.io/apimachinery/pkg/runtime"
 )
 
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *AdoptSource) DeepCopyInto(out *AdoptSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new AdoptSource.
+func (in *AdoptSource) DeepCopy() *AdoptSource {
+	if in == nil {
+		return nil
+	}
+	out := new(AdoptSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupPolicy) DeepCopyInto(out *BackupPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupPolicy.
+func (in *BackupPolicy) DeepCopy() *BackupPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BackupPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupPolicyList) DeepCopyInto(out *BackupPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BackupPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupPolicyList.
+func (in *BackupPolicyList) DeepCopy() *BackupPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BackupPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupPolicySpec) DeepCopyInto(out *BackupPolicySpec) {
+	*out = *in
+	in.Selector.DeepCopyInto(&out.Selector)
+	out.Retention = in.Retention
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupPolicySpec.
+func (in *BackupPolicySpec) DeepCopy() *BackupPolicySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupPolicySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupPolicyStatus) DeepCopyInto(out *BackupPolicyStatus) {
+	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextScheduleTime != nil {
+		in, out := &in.NextScheduleTime, &out.NextScheduleTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupPolicyStatus.
+func (in *BackupPolicyStatus) DeepCopy() *BackupPolicyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupPolicyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BackupRetention) DeepCopyInto(out *BackupRetention) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BackupRetention.
+func (in *BackupRetention) DeepCopy() *BackupRetention {
+	if in == nil {
+		return nil
+	}
+	out := new(BackupRetention)
+	in.DeepCopyInto(out)
+	return out
+}
+
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
 func (in *BlockStorageMapping) DeepCopyInto(out *BlockStorageMapping) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlockStorageMapping.
-func (in *BlockStorageMapping) DeepCopy() *BlockStorageMapping {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlockStorageMapping.
+func (in *BlockStorageMapping) DeepCopy() *BlockStorageMapping {
+	if in == nil {
+		return nil
+	}
+	out := new(BlockStorageMapping)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlockStorageSnapshot) DeepCopyInto(out *BlockStorageSnapshot) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlockStorageSnapshot.
+func (in *BlockStorageSnapshot) DeepCopy() *BlockStorageSnapshot {
+	if in == nil {
+		return nil
+	}
+	out := new(BlockStorageSnapshot)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BlockStorageSnapshot) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlockStorageSnapshotList) DeepCopyInto(out *BlockStorageSnapshotList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BlockStorageSnapshot, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlockStorageSnapshotList.
+func (in *BlockStorageSnapshotList) DeepCopy() *BlockStorageSnapshotList {
+	if in == nil {
+		return nil
+	}
+	out := new(BlockStorageSnapshotList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BlockStorageSnapshotList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlockStorageSnapshotSchedule) DeepCopyInto(out *BlockStorageSnapshotSchedule) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlockStorageSnapshotSchedule.
+func (in *BlockStorageSnapshotSchedule) DeepCopy() *BlockStorageSnapshotSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(BlockStorageSnapshotSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BlockStorageSnapshotSchedule) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlockStorageSnapshotScheduleList) DeepCopyInto(out *BlockStorageSnapshotScheduleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]BlockStorageSnapshotSchedule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlockStorageSnapshotScheduleList.
+func (in *BlockStorageSnapshotScheduleList) DeepCopy() *BlockStorageSnapshotScheduleList {
+	if in == nil {
+		return nil
+	}
+	out := new(BlockStorageSnapshotScheduleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *BlockStorageSnapshotScheduleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlockStorageSnapshotScheduleSpec) DeepCopyInto(out *BlockStorageSnapshotScheduleSpec) {
+	*out = *in
+	out.Source = in.Source
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlockStorageSnapshotScheduleSpec.
+func (in *BlockStorageSnapshotScheduleSpec) DeepCopy() *BlockStorageSnapshotScheduleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BlockStorageSnapshotScheduleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlockStorageSnapshotScheduleStatus) DeepCopyInto(out *BlockStorageSnapshotScheduleStatus) {
+	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextScheduleTime != nil {
+		in, out := &in.NextScheduleTime, &out.NextScheduleTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlockStorageSnapshotScheduleStatus.
+func (in *BlockStorageSnapshotScheduleStatus) DeepCopy() *BlockStorageSnapshotScheduleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BlockStorageSnapshotScheduleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlockStorageSnapshotSpec) DeepCopyInto(out *BlockStorageSnapshotSpec) {
+	*out = *in
+	out.Source = in.Source
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlockStorageSnapshotSpec.
+func (in *BlockStorageSnapshotSpec) DeepCopy() *BlockStorageSnapshotSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(BlockStorageSnapshotSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlockStorageSnapshotStatus) DeepCopyInto(out *BlockStorageSnapshotStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlockStorageSnapshotStatus.
+func (in *BlockStorageSnapshotStatus) DeepCopy() *BlockStorageSnapshotStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BlockStorageSnapshotStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlockStorageSource) DeepCopyInto(out *BlockStorageSource) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlockStorageSource.
+func (in *BlockStorageSource) DeepCopy() *BlockStorageSource {
+	if in == nil {
+		return nil
+	}
+	out := new(BlockStorageSource)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *BlockStorageStatus) DeepCopyInto(out *BlockStorageStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new BlockStorageStatus.
+func (in *BlockStorageStatus) DeepCopy() *BlockStorageStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(BlockStorageStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CostEstimate) DeepCopyInto(out *CostEstimate) {
+	*out = *in
+	in.EstimatedTime.DeepCopyInto(&out.EstimatedTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CostEstimate.
+func (in *CostEstimate) DeepCopy() *CostEstimate {
+	if in == nil {
+		return nil
+	}
+	out := new(CostEstimate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CostReport) DeepCopyInto(out *CostReport) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CostReport.
+func (in *CostReport) DeepCopy() *CostReport {
+	if in == nil {
+		return nil
+	}
+	out := new(CostReport)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CostReport) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CostReportList) DeepCopyInto(out *CostReportList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]CostReport, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CostReportList.
+func (in *CostReportList) DeepCopy() *CostReportList {
+	if in == nil {
+		return nil
+	}
+	out := new(CostReportList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *CostReportList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CostReportSpec) DeepCopyInto(out *CostReportSpec) {
+	*out = *in
+	if in.Selector != nil {
+		in, out := &in.Selector, &out.Selector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CostReportSpec.
+func (in *CostReportSpec) DeepCopy() *CostReportSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(CostReportSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CostReportStatus) DeepCopyInto(out *CostReportStatus) {
+	*out = *in
+	if in.Totals != nil {
+		in, out := &in.Totals, &out.Totals
+		*out = make([]CostTotal, len(*in))
+		copy(*out, *in)
+	}
+	if in.Provisions != nil {
+		in, out := &in.Provisions, &out.Provisions
+		*out = make([]ProvisionCost, len(*in))
+		copy(*out, *in)
+	}
+	if in.Unpriced != nil {
+		in, out := &in.Unpriced, &out.Unpriced
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastUpdateTime != nil {
+		in, out := &in.LastUpdateTime, &out.LastUpdateTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CostReportStatus.
+func (in *CostReportStatus) DeepCopy() *CostReportStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(CostReportStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *CostTotal) DeepCopyInto(out *CostTotal) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new CostTotal.
+func (in *CostTotal) DeepCopy() *CostTotal {
+	if in == nil {
+		return nil
+	}
+	out := new(CostTotal)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Data) DeepCopyInto(out *Data) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Data.
+func (in *Data) DeepCopy() *Data {
+	if in == nil {
+		return nil
+	}
+	out := new(Data)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Data) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataList) DeepCopyInto(out *DataList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Data, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataList.
+func (in *DataList) DeepCopy() *DataList {
+	if in == nil {
+		return nil
+	}
+	out := new(DataList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *DataList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataSpec) DeepCopyInto(out *DataSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataSpec.
+func (in *DataSpec) DeepCopy() *DataSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(DataSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *DataStatus) DeepCopyInto(out *DataStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataStatus.
+func (in *DataStatus) DeepCopy() *DataStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(DataStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Expiry) DeepCopyInto(out *Expiry) {
+	*out = *in
+	if in.TTL != nil {
+		in, out := &in.TTL, &out.TTL
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.WarnBefore != nil {
+		in, out := &in.WarnBefore, &out.WarnBefore
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Expiry.
+func (in *Expiry) DeepCopy() *Expiry {
+	if in == nil {
+		return nil
+	}
+	out := new(Expiry)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *HealthCheck) DeepCopyInto(out *HealthCheck) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new HealthCheck.
+func (in *HealthCheck) DeepCopy() *HealthCheck {
+	if in == nil {
+		return nil
+	}
+	out := new(HealthCheck)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Listener) DeepCopyInto(out *Listener) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Listener.
+func (in *Listener) DeepCopy() *Listener {
+	if in == nil {
+		return nil
+	}
+	out := new(Listener)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoadBalancer) DeepCopyInto(out *LoadBalancer) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancer.
+func (in *LoadBalancer) DeepCopy() *LoadBalancer {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadBalancer)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LoadBalancer) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoadBalancerList) DeepCopyInto(out *LoadBalancerList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]LoadBalancer, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancerList.
+func (in *LoadBalancerList) DeepCopy() *LoadBalancerList {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadBalancerList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *LoadBalancerList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoadBalancerSpec) DeepCopyInto(out *LoadBalancerSpec) {
+	*out = *in
+	if in.SubnetNos != nil {
+		in, out := &in.SubnetNos, &out.SubnetNos
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Listeners != nil {
+		in, out := &in.Listeners, &out.Listeners
+		*out = make([]Listener, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancerSpec.
+func (in *LoadBalancerSpec) DeepCopy() *LoadBalancerSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadBalancerSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *LoadBalancerStatus) DeepCopyInto(out *LoadBalancerStatus) {
+	*out = *in
+	if in.IPs != nil {
+		in, out := &in.IPs, &out.IPs
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new LoadBalancerStatus.
+func (in *LoadBalancerStatus) DeepCopy() *LoadBalancerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(LoadBalancerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *MaintenanceWindow) DeepCopyInto(out *MaintenanceWindow) {
+	*out = *in
+	out.Duration = in.Duration
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new MaintenanceWindow.
+func (in *MaintenanceWindow) DeepCopy() *MaintenanceWindow {
+	if in == nil {
+		return nil
+	}
+	out := new(MaintenanceWindow)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *NetworkInterface) DeepCopyInto(out *NetworkInterface) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkInterface.
+func (in *NetworkInterface) DeepCopy() *NetworkInterface {
+	if in == nil {
+		return nil
+	}
+	out := new(NetworkInterface)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Operatingsystems) DeepCopyInto(out *Operatingsystems) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Operatingsystems.
+func (in *Operatingsystems) DeepCopy() *Operatingsystems {
+	if in == nil {
+		return nil
+	}
+	out := new(Operatingsystems)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Operatingsystems) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatingsystemsList) DeepCopyInto(out *OperatingsystemsList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Operatingsystems, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatingsystemsList.
+func (in *OperatingsystemsList) DeepCopy() *OperatingsystemsList {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatingsystemsList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *OperatingsystemsList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatingsystemsSpec) DeepCopyInto(out *OperatingsystemsSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatingsystemsSpec.
+func (in *OperatingsystemsSpec) DeepCopy() *OperatingsystemsSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatingsystemsSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *OperatingsystemsStatus) DeepCopyInto(out *OperatingsystemsStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatingsystemsStatus.
+func (in *OperatingsystemsStatus) DeepCopy() *OperatingsystemsStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(OperatingsystemsStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlacementGroup) DeepCopyInto(out *PlacementGroup) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlacementGroup.
+func (in *PlacementGroup) DeepCopy() *PlacementGroup {
+	if in == nil {
+		return nil
+	}
+	out := new(PlacementGroup)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PlacementGroup) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlacementGroupList) DeepCopyInto(out *PlacementGroupList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]PlacementGroup, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlacementGroupList.
+func (in *PlacementGroupList) DeepCopy() *PlacementGroupList {
+	if in == nil {
+		return nil
+	}
+	out := new(PlacementGroupList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PlacementGroupList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlacementGroupSpec) DeepCopyInto(out *PlacementGroupSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlacementGroupSpec.
+func (in *PlacementGroupSpec) DeepCopy() *PlacementGroupSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PlacementGroupSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlacementGroupStatus) DeepCopyInto(out *PlacementGroupStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlacementGroupStatus.
+func (in *PlacementGroupStatus) DeepCopy() *PlacementGroupStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PlacementGroupStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Plan) DeepCopyInto(out *Plan) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	out.Status = in.Status
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Plan.
+func (in *Plan) DeepCopy() *Plan {
+	if in == nil {
+		return nil
+	}
+	out := new(Plan)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Plan) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlanList) DeepCopyInto(out *PlanList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Plan, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlanList.
+func (in *PlanList) DeepCopy() *PlanList {
+	if in == nil {
+		return nil
+	}
+	out := new(PlanList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *PlanList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlanSpec) DeepCopyInto(out *PlanSpec) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlanSpec.
+func (in *PlanSpec) DeepCopy() *PlanSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(PlanSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PlanStatus) DeepCopyInto(out *PlanStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlanStatus.
+func (in *PlanStatus) DeepCopy() *PlanStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(PlanStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerSchedule) DeepCopyInto(out *PowerSchedule) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerSchedule.
+func (in *PowerSchedule) DeepCopy() *PowerSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PowerTransition) DeepCopyInto(out *PowerTransition) {
+	*out = *in
+	in.Time.DeepCopyInto(&out.Time)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PowerTransition.
+func (in *PowerTransition) DeepCopy() *PowerTransition {
+	if in == nil {
+		return nil
+	}
+	out := new(PowerTransition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *PreStopHook) DeepCopyInto(out *PreStopHook) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PreStopHook.
+func (in *PreStopHook) DeepCopy() *PreStopHook {
+	if in == nil {
+		return nil
+	}
+	out := new(PreStopHook)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Provision) DeepCopyInto(out *Provision) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Provision.
+func (in *Provision) DeepCopy() *Provision {
+	if in == nil {
+		return nil
+	}
+	out := new(Provision)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *Provision) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisionCost) DeepCopyInto(out *ProvisionCost) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionCost.
+func (in *ProvisionCost) DeepCopy() *ProvisionCost {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisionCost)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisionList) DeepCopyInto(out *ProvisionList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]Provision, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionList.
+func (in *ProvisionList) DeepCopy() *ProvisionList {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisionList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProvisionList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisionPolicy) DeepCopyInto(out *ProvisionPolicy) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionPolicy.
+func (in *ProvisionPolicy) DeepCopy() *ProvisionPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisionPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProvisionPolicy) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisionPolicyList) DeepCopyInto(out *ProvisionPolicyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]ProvisionPolicy, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionPolicyList.
+func (in *ProvisionPolicyList) DeepCopy() *ProvisionPolicyList {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisionPolicyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *ProvisionPolicyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisionPolicyRule) DeepCopyInto(out *ProvisionPolicyRule) {
+	*out = *in
+	if in.Allow != nil {
+		in, out := &in.Allow, &out.Allow
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Deny != nil {
+		in, out := &in.Deny, &out.Deny
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionPolicyRule.
+func (in *ProvisionPolicyRule) DeepCopy() *ProvisionPolicyRule {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisionPolicyRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisionPolicySpec) DeepCopyInto(out *ProvisionPolicySpec) {
+	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]ProvisionPolicyRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionPolicySpec.
+func (in *ProvisionPolicySpec) DeepCopy() *ProvisionPolicySpec {
 	if in == nil {
 		return nil
 	}
-	out := new(BlockStorageMapping)
+	out := new(ProvisionPolicySpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Data) DeepCopyInto(out *Data) {
+func (in *ProvisionSet) DeepCopyInto(out *ProvisionSet) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	out.Status = in.Status
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Data.
-func (in *Data) DeepCopy() *Data {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionSet.
+func (in *ProvisionSet) DeepCopy() *ProvisionSet {
 	if in == nil {
 		return nil
 	}
-	out := new(Data)
+	out := new(ProvisionSet)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Data) DeepCopyObject() runtime.Object {
+func (in *ProvisionSet) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -67,31 +1367,31 @@ func (in *Data) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DataList) DeepCopyInto(out *DataList) {
+func (in *ProvisionSetList) DeepCopyInto(out *ProvisionSetList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]Data, len(*in))
+		*out = make([]ProvisionSet, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataList.
-func (in *DataList) DeepCopy() *DataList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionSetList.
+func (in *ProvisionSetList) DeepCopy() *ProvisionSetList {
 	if in == nil {
 		return nil
 	}
-	out := new(DataList)
+	out := new(ProvisionSetList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *DataList) DeepCopyObject() runtime.Object {
+func (in *ProvisionSetList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -99,71 +1399,257 @@ func (in *DataList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DataSpec) DeepCopyInto(out *DataSpec) {
+func (in *ProvisionSetSpec) DeepCopyInto(out *ProvisionSetSpec) {
 	*out = *in
+	in.Template.DeepCopyInto(&out.Template)
+	if in.SpreadReplicas != nil {
+		in, out := &in.SpreadReplicas, &out.SpreadReplicas
+		*out = new(bool)
+		**out = **in
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataSpec.
-func (in *DataSpec) DeepCopy() *DataSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionSetSpec.
+func (in *ProvisionSetSpec) DeepCopy() *ProvisionSetSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(DataSpec)
+	out := new(ProvisionSetSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *DataStatus) DeepCopyInto(out *DataStatus) {
+func (in *ProvisionSetStatus) DeepCopyInto(out *ProvisionSetStatus) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new DataStatus.
-func (in *DataStatus) DeepCopy() *DataStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionSetStatus.
+func (in *ProvisionSetStatus) DeepCopy() *ProvisionSetStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(DataStatus)
+	out := new(ProvisionSetStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *NetworkInterface) DeepCopyInto(out *NetworkInterface) {
+func (in *ProvisionSpec) DeepCopyInto(out *ProvisionSpec) {
 	*out = *in
+	out.Server = in.Server
+	if in.BlockStorageMappings != nil {
+		in, out := &in.BlockStorageMappings, &out.BlockStorageMappings
+		*out = make([]BlockStorageMapping, len(*in))
+		copy(*out, *in)
+	}
+	out.NetworkInterface = in.NetworkInterface
+	if in.Adopt != nil {
+		in, out := &in.Adopt, &out.Adopt
+		*out = new(AdoptSource)
+		**out = **in
+	}
+	if in.PowerSchedule != nil {
+		in, out := &in.PowerSchedule, &out.PowerSchedule
+		*out = new(PowerSchedule)
+		**out = **in
+	}
+	if in.Expiry != nil {
+		in, out := &in.Expiry, &out.Expiry
+		*out = new(Expiry)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Resize != nil {
+		in, out := &in.Resize, &out.Resize
+		*out = new(ResizePolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Placement != nil {
+		in, out := &in.Placement, &out.Placement
+		*out = new(ZonePlacement)
+		(*in).DeepCopyInto(*out)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new NetworkInterface.
-func (in *NetworkInterface) DeepCopy() *NetworkInterface {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionSpec.
+func (in *ProvisionSpec) DeepCopy() *ProvisionSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(NetworkInterface)
+	out := new(ProvisionSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Operatingsystems) DeepCopyInto(out *Operatingsystems) {
+func (in *ProvisionStatus) DeepCopyInto(out *ProvisionStatus) {
+	*out = *in
+	if in.BlockStorages != nil {
+		in, out := &in.BlockStorages, &out.BlockStorages
+		*out = make([]BlockStorageStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.LastSyncTime != nil {
+		in, out := &in.LastSyncTime, &out.LastSyncTime
+		*out = (*in).DeepCopy()
+	}
+	if in.NextPowerTransition != nil {
+		in, out := &in.NextPowerTransition, &out.NextPowerTransition
+		*out = new(PowerTransition)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.LastBackupTime != nil {
+		in, out := &in.LastBackupTime, &out.LastBackupTime
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpiryWarningTime != nil {
+		in, out := &in.ExpiryWarningTime, &out.ExpiryWarningTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Resize != nil {
+		in, out := &in.Resize, &out.Resize
+		*out = new(ResizeStatus)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Cost != nil {
+		in, out := &in.Cost, &out.Cost
+		*out = new(CostEstimate)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionStatus.
+func (in *ProvisionStatus) DeepCopy() *ProvisionStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisionStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ProvisionTemplate) DeepCopyInto(out *ProvisionTemplate) {
+	*out = *in
+	if in.Labels != nil {
+		in, out := &in.Labels, &out.Labels
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	in.Spec.DeepCopyInto(&out.Spec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionTemplate.
+func (in *ProvisionTemplate) DeepCopy() *ProvisionTemplate {
+	if in == nil {
+		return nil
+	}
+	out := new(ProvisionTemplate)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResizePolicy) DeepCopyInto(out *ResizePolicy) {
+	*out = *in
+	if in.PreStopHook != nil {
+		in, out := &in.PreStopHook, &out.PreStopHook
+		*out = new(PreStopHook)
+		**out = **in
+	}
+	if in.MaintenanceWindow != nil {
+		in, out := &in.MaintenanceWindow, &out.MaintenanceWindow
+		*out = new(MaintenanceWindow)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResizePolicy.
+func (in *ResizePolicy) DeepCopy() *ResizePolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(ResizePolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ResizeStatus) DeepCopyInto(out *ResizeStatus) {
+	*out = *in
+	in.StartTime.DeepCopyInto(&out.StartTime)
+	if in.NextWindowTime != nil {
+		in, out := &in.NextWindowTime, &out.NextWindowTime
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ResizeStatus.
+func (in *ResizeStatus) DeepCopy() *ResizeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(ResizeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *Server) DeepCopyInto(out *Server) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Server.
+func (in *Server) DeepCopy() *Server {
+	if in == nil {
+		return nil
+	}
+	out := new(Server)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ServerImage) DeepCopyInto(out *ServerImage) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
 	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Operatingsystems.
-func (in *Operatingsystems) DeepCopy() *Operatingsystems {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerImage.
+func (in *ServerImage) DeepCopy() *ServerImage {
 	if in == nil {
 		return nil
 	}
-	out := new(Operatingsystems)
+	out := new(ServerImage)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Operatingsystems) DeepCopyObject() runtime.Object {
+func (in *ServerImage) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -171,31 +1657,31 @@ func (in *Operatingsystems) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OperatingsystemsList) DeepCopyInto(out *OperatingsystemsList) {
+func (in *ServerImageList) DeepCopyInto(out *ServerImageList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]Operatingsystems, len(*in))
+		*out = make([]ServerImage, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatingsystemsList.
-func (in *OperatingsystemsList) DeepCopy() *OperatingsystemsList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerImageList.
+func (in *ServerImageList) DeepCopy() *ServerImageList {
 	if in == nil {
 		return nil
 	}
-	out := new(OperatingsystemsList)
+	out := new(ServerImageList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *OperatingsystemsList) DeepCopyObject() runtime.Object {
+func (in *ServerImageList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -203,56 +1689,78 @@ func (in *OperatingsystemsList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OperatingsystemsSpec) DeepCopyInto(out *OperatingsystemsSpec) {
+func (in *ServerImageSpec) DeepCopyInto(out *ServerImageSpec) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatingsystemsSpec.
-func (in *OperatingsystemsSpec) DeepCopy() *OperatingsystemsSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerImageSpec.
+func (in *ServerImageSpec) DeepCopy() *ServerImageSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(OperatingsystemsSpec)
+	out := new(ServerImageSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *OperatingsystemsStatus) DeepCopyInto(out *OperatingsystemsStatus) {
+func (in *ServerImageStatus) DeepCopyInto(out *ServerImageStatus) {
 	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new OperatingsystemsStatus.
-func (in *OperatingsystemsStatus) DeepCopy() *OperatingsystemsStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ServerImageStatus.
+func (in *ServerImageStatus) DeepCopy() *ServerImageStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(OperatingsystemsStatus)
+	out := new(ServerImageStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Plan) DeepCopyInto(out *Plan) {
+func (in *Target) DeepCopyInto(out *Target) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Target.
+func (in *Target) DeepCopy() *Target {
+	if in == nil {
+		return nil
+	}
+	out := new(Target)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *TargetGroup) DeepCopyInto(out *TargetGroup) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
-	out.Status = in.Status
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Plan.
-func (in *Plan) DeepCopy() *Plan {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetGroup.
+func (in *TargetGroup) DeepCopy() *TargetGroup {
 	if in == nil {
 		return nil
 	}
-	out := new(Plan)
+	out := new(TargetGroup)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Plan) DeepCopyObject() runtime.Object {
+func (in *TargetGroup) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -260,31 +1768,31 @@ func (in *Plan) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PlanList) DeepCopyInto(out *PlanList) {
+func (in *TargetGroupList) DeepCopyInto(out *TargetGroupList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]Plan, len(*in))
+		*out = make([]TargetGroup, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlanList.
-func (in *PlanList) DeepCopy() *PlanList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetGroupList.
+func (in *TargetGroupList) DeepCopy() *TargetGroupList {
 	if in == nil {
 		return nil
 	}
-	out := new(PlanList)
+	out := new(TargetGroupList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *PlanList) DeepCopyObject() runtime.Object {
+func (in *TargetGroupList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -292,56 +1800,70 @@ func (in *PlanList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PlanSpec) DeepCopyInto(out *PlanSpec) {
+func (in *TargetGroupSpec) DeepCopyInto(out *TargetGroupSpec) {
 	*out = *in
+	out.HealthCheck = in.HealthCheck
+	in.Selector.DeepCopyInto(&out.Selector)
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlanSpec.
-func (in *PlanSpec) DeepCopy() *PlanSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetGroupSpec.
+func (in *TargetGroupSpec) DeepCopy() *TargetGroupSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(PlanSpec)
+	out := new(TargetGroupSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *PlanStatus) DeepCopyInto(out *PlanStatus) {
+func (in *TargetGroupStatus) DeepCopyInto(out *TargetGroupStatus) {
 	*out = *in
+	if in.Targets != nil {
+		in, out := &in.Targets, &out.Targets
+		*out = make([]Target, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new PlanStatus.
-func (in *PlanStatus) DeepCopy() *PlanStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new TargetGroupStatus.
+func (in *TargetGroupStatus) DeepCopy() *TargetGroupStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(PlanStatus)
+	out := new(TargetGroupStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Provision) DeepCopyInto(out *Provision) {
+func (in *VMQuota) DeepCopyInto(out *VMQuota) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
-	out.Spec = in.Spec
+	in.Spec.DeepCopyInto(&out.Spec)
 	out.Status = in.Status
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Provision.
-func (in *Provision) DeepCopy() *Provision {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMQuota.
+func (in *VMQuota) DeepCopy() *VMQuota {
 	if in == nil {
 		return nil
 	}
-	out := new(Provision)
+	out := new(VMQuota)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *Provision) DeepCopyObject() runtime.Object {
+func (in *VMQuota) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -349,31 +1871,66 @@ func (in *Provision) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProvisionList) DeepCopyInto(out *ProvisionList) {
+func (in *VMQuotaLimits) DeepCopyInto(out *VMQuotaLimits) {
+	*out = *in
+	if in.Servers != nil {
+		in, out := &in.Servers, &out.Servers
+		*out = new(int64)
+		**out = **in
+	}
+	if in.VCPU != nil {
+		in, out := &in.VCPU, &out.VCPU
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MemoryGiB != nil {
+		in, out := &in.MemoryGiB, &out.MemoryGiB
+		*out = new(int64)
+		**out = **in
+	}
+	if in.StorageGiB != nil {
+		in, out := &in.StorageGiB, &out.StorageGiB
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMQuotaLimits.
+func (in *VMQuotaLimits) DeepCopy() *VMQuotaLimits {
+	if in == nil {
+		return nil
+	}
+	out := new(VMQuotaLimits)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *VMQuotaList) DeepCopyInto(out *VMQuotaList) {
 	*out = *in
 	out.TypeMeta = in.TypeMeta
 	in.ListMeta.DeepCopyInto(&out.ListMeta)
 	if in.Items != nil {
 		in, out := &in.Items, &out.Items
-		*out = make([]Provision, len(*in))
+		*out = make([]VMQuota, len(*in))
 		for i := range *in {
 			(*in)[i].DeepCopyInto(&(*out)[i])
 		}
 	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionList.
-func (in *ProvisionList) DeepCopy() *ProvisionList {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMQuotaList.
+func (in *VMQuotaList) DeepCopy() *VMQuotaList {
 	if in == nil {
 		return nil
 	}
-	out := new(ProvisionList)
+	out := new(VMQuotaList)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
-func (in *ProvisionList) DeepCopyObject() runtime.Object {
+func (in *VMQuotaList) DeepCopyObject() runtime.Object {
 	if c := in.DeepCopy(); c != nil {
 		return c
 	}
@@ -381,49 +1938,88 @@ func (in *ProvisionList) DeepCopyObject() runtime.Object {
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProvisionSpec) DeepCopyInto(out *ProvisionSpec) {
+func (in *VMQuotaSpec) DeepCopyInto(out *VMQuotaSpec) {
 	*out = *in
-	out.Server = in.Server
-	out.BlockStorageMapping = in.BlockStorageMapping
-	out.NetworkInterface = in.NetworkInterface
+	in.Hard.DeepCopyInto(&out.Hard)
+	if in.AllowedServerProductCodes != nil {
+		in, out := &in.AllowedServerProductCodes, &out.AllowedServerProductCodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedServerImageProductCodes != nil {
+		in, out := &in.AllowedServerImageProductCodes, &out.AllowedServerImageProductCodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionSpec.
-func (in *ProvisionSpec) DeepCopy() *ProvisionSpec {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMQuotaSpec.
+func (in *VMQuotaSpec) DeepCopy() *VMQuotaSpec {
 	if in == nil {
 		return nil
 	}
-	out := new(ProvisionSpec)
+	out := new(VMQuotaSpec)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *ProvisionStatus) DeepCopyInto(out *ProvisionStatus) {
+func (in *VMQuotaStatus) DeepCopyInto(out *VMQuotaStatus) {
 	*out = *in
+	out.Used = in.Used
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ProvisionStatus.
-func (in *ProvisionStatus) DeepCopy() *ProvisionStatus {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMQuotaStatus.
+func (in *VMQuotaStatus) DeepCopy() *VMQuotaStatus {
 	if in == nil {
 		return nil
 	}
-	out := new(ProvisionStatus)
+	out := new(VMQuotaStatus)
 	in.DeepCopyInto(out)
 	return out
 }
 
 // DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
-func (in *Server) DeepCopyInto(out *Server) {
+func (in *VMQuotaUsage) DeepCopyInto(out *VMQuotaUsage) {
 	*out = *in
 }
 
-// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new Server.
-func (in *Server) DeepCopy() *Server {
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new VMQuotaUsage.
+func (in *VMQuotaUsage) DeepCopy() *VMQuotaUsage {
 	if in == nil {
 		return nil
 	}
-	out := new(Server)
+	out := new(VMQuotaUsage)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *ZonePlacement) DeepCopyInto(out *ZonePlacement) {
+	*out = *in
+	if in.Zones != nil {
+		in, out := &in.Zones, &out.Zones
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SubnetNos != nil {
+		in, out := &in.SubnetNos, &out.SubnetNos
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.SpreadSelector != nil {
+		in, out := &in.SpreadSelector, &out.SpreadSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new ZonePlacement.
+func (in *ZonePlacement) DeepCopy() *ZonePlacement {
+	if in == nil {
+		return nil
+	}
+	out := new(ZonePlacement)
 	in.DeepCopyInto(out)
 	return out
 }
@@ -17,11 +17,15 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"flag"
 	"github.com/cloud-club/Aviator-service/pkg"
 	"github.com/cloud-club/Aviator-service/types/auth"
 
 	"os"
+	"time"
+
+	"golang.org/x/time/rate"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
@@ -33,10 +37,15 @@ import (
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
 	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
 
 	vmv1 "vm.cloudclub.io/api/v1"
 	"vm.cloudclub.io/internal/controller"
+	"vm.cloudclub.io/internal/ncp"
+	"vm.cloudclub.io/internal/pricing"
+	"vm.cloudclub.io/internal/tracing"
+	"vm.cloudclub.io/internal/webhook"
 	//+kubebuilder:scaffold:imports
 )
 
@@ -55,11 +64,52 @@ func main() {
 	var metricsAddr string
 	var enableLeaderElection bool
 	var probeAddr string
+	var provisionResyncPeriod time.Duration
+	var orphanCollectInterval time.Duration
+	var orphanGracePeriod time.Duration
+	var orphanTerminate bool
+	var clusterID string
+	var ncpQPS float64
+	var ncpBurst int
+	var serverCacheTTL time.Duration
+	var otlpEndpoint string
+	var traceSampleRatio float64
+	var estimateCosts bool
+	var priceFile string
+	var priceCacheTTL time.Duration
 	flag.StringVar(&metricsAddr, "metrics-bind-address", ":8080", "The address the metric endpoint binds to.")
 	flag.StringVar(&probeAddr, "health-probe-bind-address", ":8081", "The address the probe endpoint binds to.")
 	flag.BoolVar(&enableLeaderElection, "leader-elect", false,
 		"Enable leader election for controller manager. "+
 			"Enabling this will ensure there is only one active controller manager.")
+	flag.DurationVar(&provisionResyncPeriod, "provision-resync-period", 5*time.Minute,
+		"How often provisioned servers are compared with their Provision spec to detect drift. 0 disables the periodic resync.")
+	flag.DurationVar(&orphanCollectInterval, "orphan-collect-interval", 10*time.Minute,
+		"How often operator owned servers without a Provision are looked for. 0 disables the orphan collector.")
+	flag.DurationVar(&orphanGracePeriod, "orphan-grace-period", time.Hour,
+		"How long a server has to stay orphaned before it is terminated.")
+	flag.BoolVar(&orphanTerminate, "orphan-terminate", false,
+		"Terminate orphan servers after the grace period instead of only reporting them. Requires --cluster-id.")
+	flag.StringVar(&clusterID, "cluster-id", "",
+		"Identifies this cluster in the tags of the servers it creates, so that other clusters sharing the NCP account leave them alone.")
+	flag.Float64Var(&ncpQPS, "ncp-qps", 10,
+		"Maximum sustained rate of NCP API calls per second, shared by all controllers.")
+	flag.IntVar(&ncpBurst, "ncp-burst", 20,
+		"Maximum burst of NCP API calls above ncp-qps.")
+	flag.DurationVar(&serverCacheTTL, "ncp-server-cache-ttl", 30*time.Second,
+		"How long the servers listed for a region are reused before they are listed again.")
+	flag.BoolVar(&controller.RedactAPIPayloads, "redact-api-payloads", true,
+		"Mask secrets and addresses in the NCP payloads logged at trace verbosity (--zap-log-level=2).")
+	flag.StringVar(&otlpEndpoint, "otlp-traces-endpoint", "",
+		"OTLP/HTTP endpoint traces are exported to, e.g. http://otel-collector:4318/v1/traces. Tracing is disabled when empty.")
+	flag.Float64Var(&traceSampleRatio, "trace-sample-ratio", 1,
+		"Fraction of reconciles traced when tracing is enabled.")
+	flag.BoolVar(&estimateCosts, "estimate-costs", true,
+		"Estimate the cost of each Provision into its status and sum them in CostReports.")
+	flag.StringVar(&priceFile, "price-file", "",
+		"YAML file with the prices costs are estimated from. The list prices of the NCP billing API are used when empty.")
+	flag.DurationVar(&priceCacheTTL, "price-cache-ttl", 24*time.Hour,
+		"How long the prices read from the NCP billing API for a region are reused.")
 	opts := zap.Options{
 		Development: true,
 	}
@@ -68,6 +118,17 @@ func main() {
 
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	shutdownTracing, err := tracing.Setup(otlpEndpoint, traceSampleRatio)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+	defer func() {
+		if err := shutdownTracing(context.Background()); err != nil {
+			setupLog.Error(err, "unable to flush traces")
+		}
+	}()
+
 	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
 		Scheme:                 scheme,
 		Metrics:                metricsserver.Options{BindAddress: metricsAddr},
@@ -91,14 +152,35 @@ func main() {
 		os.Exit(1)
 	}
 
-	err = (controller.NewProvisionReconciler(
+	keyService := auth.NewKeyService("6CmrDJ4KaswJ10g25GEP", "OvZ7QHH0Bi3AwGn5rlsD7xoC986bEOiIjdbwMFCo")
+	ncpLimiter := rate.NewLimiter(rate.Limit(ncpQPS), ncpBurst)
+	ncpService := &pkg.NcpService{
+		Server: ncp.NewRateLimitedServerService(pkg.NewServerService(keyService), ncpLimiter)}
+	ncpClient := ncp.NewClient(keyService, ncpLimiter)
+	serverCache := ncp.NewServerCache(ncpClient, serverCacheTTL)
+	provisionReconciler := controller.NewProvisionReconciler(
 		mgr.GetClient(),
 		mgr.GetScheme(),
-		&pkg.NcpService{
-			Server: pkg.NewServerService(
-				auth.NewKeyService("6CmrDJ4KaswJ10g25GEP", "OvZ7QHH0Bi3AwGn5rlsD7xoC986bEOiIjdbwMFCo"))},
-	)).SetupWithManager(mgr)
-	if err != nil {
+		mgr.GetEventRecorderFor("provision-controller"),
+		ncpService,
+		ncpClient,
+		serverCache,
+	)
+	provisionReconciler.ResyncPeriod = provisionResyncPeriod
+	provisionReconciler.ClusterID = clusterID
+	if estimateCosts {
+		if priceFile != "" {
+			catalog, err := pricing.LoadFile(priceFile)
+			if err != nil {
+				setupLog.Error(err, "unable to load price file")
+				os.Exit(1)
+			}
+			provisionReconciler.Pricing = catalog
+		} else {
+			provisionReconciler.Pricing = pricing.NewAPICatalog(ncpClient, priceCacheTTL)
+		}
+	}
+	if err = provisionReconciler.SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Provision")
 		os.Exit(1)
 	}
@@ -109,10 +191,12 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "Operatingsystems")
 		os.Exit(1)
 	}
-	if err = (&controller.DataReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
-	}).SetupWithManager(mgr); err != nil {
+	if err = controller.NewDataReconciler(
+		mgr.GetClient(),
+		mgr.GetScheme(),
+		mgr.GetEventRecorderFor("data-controller"),
+		ncpClient,
+	).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "Data")
 		os.Exit(1)
 	}
@@ -123,8 +207,119 @@ func main() {
 		setupLog.Error(err, "unable to create controller", "controller", "Plan")
 		os.Exit(1)
 	}
+	if err = (&controller.CostReportReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "CostReport")
+		os.Exit(1)
+	}
+	if err = (&controller.VMQuotaReconciler{
+		Client: mgr.GetClient(),
+		Scheme: mgr.GetScheme(),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "VMQuota")
+		os.Exit(1)
+	}
+	if err = controller.NewServerImageReconciler(
+		mgr.GetClient(),
+		mgr.GetScheme(),
+		mgr.GetEventRecorderFor("serverimage-controller"),
+		ncpClient,
+	).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ServerImage")
+		os.Exit(1)
+	}
+	if err = controller.NewBlockStorageSnapshotReconciler(
+		mgr.GetClient(),
+		mgr.GetScheme(),
+		mgr.GetEventRecorderFor("blockstoragesnapshot-controller"),
+		ncpClient,
+	).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BlockStorageSnapshot")
+		os.Exit(1)
+	}
+	if err = (&controller.BlockStorageSnapshotScheduleReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("blockstoragesnapshotschedule-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BlockStorageSnapshotSchedule")
+		os.Exit(1)
+	}
+	if err = (&controller.BackupPolicyReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("backuppolicy-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "BackupPolicy")
+		os.Exit(1)
+	}
+	if err = controller.NewPlacementGroupReconciler(
+		mgr.GetClient(),
+		mgr.GetScheme(),
+		mgr.GetEventRecorderFor("placementgroup-controller"),
+		ncpClient,
+	).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "PlacementGroup")
+		os.Exit(1)
+	}
+	if err = (&controller.ProvisionSetReconciler{
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("provisionset-controller"),
+	}).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "ProvisionSet")
+		os.Exit(1)
+	}
+	if err = controller.NewTargetGroupReconciler(
+		mgr.GetClient(),
+		mgr.GetScheme(),
+		mgr.GetEventRecorderFor("targetgroup-controller"),
+		ncpClient,
+	).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "TargetGroup")
+		os.Exit(1)
+	}
+	if err = controller.NewLoadBalancerReconciler(
+		mgr.GetClient(),
+		mgr.GetScheme(),
+		mgr.GetEventRecorderFor("loadbalancer-controller"),
+		ncpClient,
+	).SetupWithManager(mgr); err != nil {
+		setupLog.Error(err, "unable to create controller", "controller", "LoadBalancer")
+		os.Exit(1)
+	}
+	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+		if err = (&webhook.ProvisionValidator{Client: mgr.GetClient()}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Provision")
+			os.Exit(1)
+		}
+		if err = (&webhook.DataValidator{}).SetupWebhookWithManager(mgr); err != nil {
+			setupLog.Error(err, "unable to create webhook", "webhook", "Data")
+			os.Exit(1)
+		}
+	}
 	//+kubebuilder:scaffold:builder
 
+	metrics.Registry.MustRegister(controller.NewServerCollector(mgr.GetClient()))
+
+	if orphanCollectInterval > 0 {
+		if orphanTerminate && clusterID == "" {
+			setupLog.Error(nil, "--orphan-terminate requires --cluster-id")
+			os.Exit(1)
+		}
+		orphanCollector := controller.NewOrphanCollector(mgr.GetClient(), ncpService, ncpClient, serverCache)
+		orphanCollector.Interval = orphanCollectInterval
+		orphanCollector.GracePeriod = orphanGracePeriod
+		orphanCollector.Terminate = orphanTerminate
+		orphanCollector.ClusterID = clusterID
+		if err := mgr.Add(orphanCollector); err != nil {
+			setupLog.Error(err, "unable to set up orphan collector")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -137,6 +332,7 @@ func main() {
 	setupLog.Info("starting manager")
 	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
 		setupLog.Error(err, "problem running manager")
+		_ = shutdownTracing(context.Background())
 		os.Exit(1)
 	}
 }
@@ -0,0 +1,47 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Retain returns the backup times to keep: the newest backup of each of the
+// newest daily days and of the newest weekly ISO weeks, with days and weeks
+// taken in location.
+func Retain(times []time.Time, daily, weekly int, location *time.Location) map[time.Time]bool {
+	sorted := append([]time.Time(nil), times...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].After(sorted[j]) })
+
+	keep := map[time.Time]bool{}
+	days, weeks := map[string]bool{}, map[string]bool{}
+	for _, t := range sorted {
+		local := t.In(location)
+		if day := local.Format(time.DateOnly); !days[day] && len(days) < daily {
+			days[day] = true
+			keep[t] = true
+		}
+		year, week := local.ISOWeek()
+		if key := fmt.Sprintf("%d-W%02d", year, week); !weeks[key] && len(weeks) < weekly {
+			weeks[key] = true
+			keep[t] = true
+		}
+	}
+	return keep
+}
@@ -0,0 +1,56 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Retain", func() {
+	seoul, _ := time.LoadLocation("Asia/Seoul")
+	at := func(day, hour int) time.Time { return time.Date(2024, 1, day, hour, 0, 0, 0, seoul) }
+
+	It("keeps the newest backup of each day and week", func() {
+		// 2024-01-01 is a Monday; the 15th starts the third ISO week
+		var times []time.Time
+		for day := 1; day <= 16; day++ {
+			times = append(times, at(day, 3))
+		}
+		times = append(times, at(16, 15))
+
+		keep := Retain(times, 2, 3, seoul)
+		Expect(keep).To(HaveLen(4))
+		Expect(keep).To(HaveKey(at(16, 15)))
+		Expect(keep).To(HaveKey(at(15, 3)))
+		// the newest of the two weeks before
+		Expect(keep).To(HaveKey(at(14, 3)))
+		Expect(keep).To(HaveKey(at(7, 3)))
+	})
+
+	It("takes days in the location", func() {
+		// the same UTC day, but 23:00 and 01:00 the next day in Seoul
+		times := []time.Time{
+			time.Date(2024, 1, 1, 14, 0, 0, 0, time.UTC),
+			time.Date(2024, 1, 1, 16, 0, 0, 0, time.UTC),
+		}
+		Expect(Retain(times, 2, 0, seoul)).To(HaveLen(2))
+		Expect(Retain(times, 2, 0, time.UTC)).To(HaveLen(1))
+	})
+})
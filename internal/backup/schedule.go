@@ -0,0 +1,95 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package backup evaluates the schedules and retention of
+// BlockStorageSnapshotSchedules and BackupPolicies.
+package backup
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+// maxMissed bounds how many missed runs Due skips over to find the latest.
+const maxMissed = 10000
+
+// Schedule is the parsed cron expression and time zone of a schedule.
+type Schedule struct {
+	schedule cron.Schedule
+	location *time.Location
+}
+
+// Parse parses a cron expression and the time zone it is evaluated in.
+func Parse(expression, timeZone string) (*Schedule, error) {
+	location := time.UTC
+	if timeZone != "" {
+		var err error
+		if location, err = time.LoadLocation(timeZone); err != nil {
+			return nil, fmt.Errorf("invalid schedule time zone %q: %v", timeZone, err)
+		}
+	}
+	schedule, err := cron.ParseStandard(expression)
+	if err != nil {
+		return nil, fmt.Errorf("invalid schedule %q: %v", expression, err)
+	}
+	return &Schedule{schedule: schedule, location: location}, nil
+}
+
+// Due returns whether a run fell between last and now and when the next run
+// after now is. Missed runs are coalesced: scheduled is the latest of them.
+func (s *Schedule) Due(last, now time.Time) (due bool, scheduled, next time.Time) {
+	t := s.schedule.Next(last.In(s.location))
+	if t.After(now) {
+		return false, time.Time{}, t
+	}
+	for i := 0; i < maxMissed; i++ {
+		n := s.schedule.Next(t)
+		if n.After(now) {
+			return true, t, n
+		}
+		t = n
+	}
+	return true, now, s.schedule.Next(now.In(s.location))
+}
+
+// Location is the time zone the schedule is evaluated in.
+func (s *Schedule) Location() *time.Location {
+	return s.location
+}
+
+// Expired returns the snapshots beyond the newest retention available ones,
+// oldest first. Snapshots still being created are neither counted nor
+// returned.
+func Expired(snapshots []vmv1.BlockStorageSnapshot, retention int) []vmv1.BlockStorageSnapshot {
+	var available []vmv1.BlockStorageSnapshot
+	for _, s := range snapshots {
+		if s.Status.Phase == vmv1.BlockStorageSnapshotPhaseAvailable && s.DeletionTimestamp.IsZero() {
+			available = append(available, s)
+		}
+	}
+	if len(available) <= retention {
+		return nil
+	}
+	sort.SliceStable(available, func(i, j int) bool {
+		return available[i].CreationTimestamp.Before(&available[j].CreationTimestamp)
+	})
+	return available[:len(available)-retention]
+}
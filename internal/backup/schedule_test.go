@@ -0,0 +1,87 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package backup
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+var _ = Describe("Schedule", func() {
+	var schedule *Schedule
+	seoul, _ := time.LoadLocation("Asia/Seoul")
+
+	BeforeEach(func() {
+		var err error
+		schedule, err = Parse("0 3 * * *", "Asia/Seoul")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("is not due before the next run", func() {
+		due, _, next := schedule.Due(time.Date(2024, 1, 3, 3, 0, 0, 0, seoul), time.Date(2024, 1, 3, 12, 0, 0, 0, seoul))
+		Expect(due).To(BeFalse())
+		Expect(next).To(BeTemporally("==", time.Date(2024, 1, 4, 3, 0, 0, 0, seoul)))
+	})
+
+	It("coalesces missed runs into the latest", func() {
+		due, scheduled, next := schedule.Due(time.Date(2024, 1, 1, 3, 0, 0, 0, seoul), time.Date(2024, 1, 3, 12, 0, 0, 0, seoul))
+		Expect(due).To(BeTrue())
+		Expect(scheduled).To(BeTemporally("==", time.Date(2024, 1, 3, 3, 0, 0, 0, seoul)))
+		Expect(next).To(BeTemporally("==", time.Date(2024, 1, 4, 3, 0, 0, 0, seoul)))
+	})
+
+	It("rejects invalid schedules", func() {
+		_, err := Parse("0 3 * *", "")
+		Expect(err).To(HaveOccurred())
+		_, err = Parse("0 3 * * *", "Mars/Olympus")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Expired", func() {
+	snapshot := func(name string, day int, phase vmv1.BlockStorageSnapshotPhase) vmv1.BlockStorageSnapshot {
+		s := vmv1.BlockStorageSnapshot{}
+		s.Name = name
+		s.CreationTimestamp = metav1.NewTime(time.Date(2024, 1, day, 3, 0, 0, 0, time.UTC))
+		s.Status.Phase = phase
+		return s
+	}
+
+	It("returns the oldest available snapshots beyond the retention", func() {
+		snapshots := []vmv1.BlockStorageSnapshot{
+			snapshot("d3", 3, vmv1.BlockStorageSnapshotPhaseAvailable),
+			snapshot("d1", 1, vmv1.BlockStorageSnapshotPhaseAvailable),
+			snapshot("d4", 4, vmv1.BlockStorageSnapshotPhaseCreating),
+			snapshot("d2", 2, vmv1.BlockStorageSnapshotPhaseAvailable),
+		}
+		var names []string
+		for _, s := range Expired(snapshots, 2) {
+			names = append(names, s.Name)
+		}
+		Expect(names).To(Equal([]string{"d1"}))
+	})
+
+	It("keeps everything within the retention", func() {
+		snapshots := []vmv1.BlockStorageSnapshot{snapshot("d1", 1, vmv1.BlockStorageSnapshotPhaseAvailable)}
+		Expect(Expired(snapshots, 1)).To(BeEmpty())
+	})
+})
@@ -0,0 +1,318 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/backup"
+)
+
+// BackupPolicyReconciler backs up the servers of the Provisions a
+// BackupPolicy selects, reports completed backups on the Provisions and
+// prunes backups the retention no longer keeps.
+type BackupPolicyReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+// backupRun is the backup one run of a policy took of one Provision: a
+// ServerImage or a BlockStorageSnapshot per disk.
+type backupRun struct {
+	time     time.Time
+	objects  []client.Object
+	complete bool
+}
+
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=backuppolicies,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=backuppolicies/status,verbs=get;update;patch
+
+// Reconcile reports and prunes the backups of a BackupPolicy, backs up the
+// selected Provisions when a run is due and requeues for the next run.
+func (r *BackupPolicyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	policy := &vmv1.BackupPolicy{}
+	if err := r.Get(ctx, req.NamespacedName, policy); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get BackupPolicy resource")
+		return ctrl.Result{}, err
+	}
+	schedule, err := backup.Parse(policy.Spec.Schedule, policy.Spec.TimeZone)
+	if err != nil {
+		log.Error(err, "Invalid backup schedule")
+		recordEvent(ctx, r.Recorder, policy, corev1.EventTypeWarning, eventReasonInvalidBackupPolicy, "%s", err.Error())
+		return ctrl.Result{}, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(&policy.Spec.Selector)
+	if err != nil {
+		log.Error(err, "Invalid BackupPolicy selector")
+		recordEvent(ctx, r.Recorder, policy, corev1.EventTypeWarning, eventReasonInvalidBackupPolicy, "%s", err.Error())
+		return ctrl.Result{}, nil
+	}
+
+	runs, err := r.backupRuns(ctx, policy)
+	if err != nil {
+		log.Error(err, "Failed to list backups")
+		return ctrl.Result{}, err
+	}
+	if err := r.reportBackups(ctx, policy.Namespace, runs); err != nil {
+		log.Error(err, "Failed to report backups on Provisions")
+		return ctrl.Result{}, err
+	}
+	if err := r.prune(ctx, policy, schedule.Location(), runs); err != nil {
+		log.Error(err, "Failed to prune backups")
+		return ctrl.Result{}, err
+	}
+	if policy.Spec.Suspend {
+		return ctrl.Result{}, nil
+	}
+
+	last := policy.CreationTimestamp.Time
+	if policy.Status.LastScheduleTime != nil {
+		last = policy.Status.LastScheduleTime.Time
+	}
+	now := time.Now()
+	due, scheduled, next := schedule.Due(last, now)
+	if due {
+		provisions := &vmv1.ProvisionList{}
+		if err := r.List(ctx, provisions, client.InNamespace(policy.Namespace),
+			client.MatchingLabelsSelector{Selector: selector}); err != nil {
+			log.Error(err, "Failed to list Provisions")
+			return ctrl.Result{}, err
+		}
+		backedUp := 0
+		for i := range provisions.Items {
+			provision := &provisions.Items[i]
+			if !provision.DeletionTimestamp.IsZero() || managedServerInstanceNo(provision) == "" {
+				continue
+			}
+			if err := r.backup(ctx, policy, provision, scheduled); err != nil {
+				log.Error(err, "Failed to back up Provision", "provision", provision.Name)
+				return ctrl.Result{}, err
+			}
+			backedUp++
+		}
+		log.V(LogLevelInfo).Info("Scheduled backups", "provisions", backedUp, "scheduled", scheduled)
+		recordEvent(ctx, r.Recorder, policy, corev1.EventTypeNormal, eventReasonBackupScheduled,
+			"Backing up %d Provisions by %s", backedUp, policy.Spec.Method)
+		policy.Status.LastScheduleTime = &metav1.Time{Time: scheduled}
+		policy.Status.Provisions = backedUp
+	}
+	policy.Status.NextScheduleTime = &metav1.Time{Time: next}
+	if err := r.Status().Update(ctx, policy); err != nil {
+		log.Error(err, "Failed to update BackupPolicy status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: next.Sub(now)}, nil
+}
+
+// backup creates the backup objects of one run of a Provision. They are
+// named after the run, so a retried run does not create them twice.
+func (r *BackupPolicyReconciler) backup(ctx context.Context, policy *vmv1.BackupPolicy, provision *vmv1.Provision, scheduled time.Time) error {
+	name := fmt.Sprintf("%s-%s-%d", policy.Name, provision.Name, scheduled.Unix()/60)
+	meta := metav1.ObjectMeta{
+		Namespace: policy.Namespace,
+		Labels: map[string]string{
+			vmv1.BackupPolicyLabel:    policy.Name,
+			vmv1.BackupProvisionLabel: provision.Name,
+			vmv1.BackupRunLabel:       strconv.FormatInt(scheduled.Unix(), 10),
+		},
+	}
+	description := fmt.Sprintf("backup by %s/%s", policy.Namespace, policy.Name)
+
+	var objects []client.Object
+	switch policy.Spec.Method {
+	case vmv1.BackupMethodServerImage:
+		image := &vmv1.ServerImage{ObjectMeta: meta, Spec: vmv1.ServerImageSpec{ProvisionName: provision.Name, Description: description}}
+		image.Name = name
+		objects = append(objects, image)
+	default:
+		for _, order := range diskOrders(provision) {
+			snapshot := &vmv1.BlockStorageSnapshot{
+				ObjectMeta: *meta.DeepCopy(),
+				Spec: vmv1.BlockStorageSnapshotSpec{
+					Source:      vmv1.BlockStorageSource{ProvisionName: provision.Name, Order: order},
+					Description: description,
+				},
+			}
+			snapshot.Name = fmt.Sprintf("%s-%d", name, order)
+			objects = append(objects, snapshot)
+		}
+	}
+	for _, object := range objects {
+		if err := r.Create(ctx, object); err != nil && !errors.IsAlreadyExists(err) {
+			return err
+		}
+	}
+	return nil
+}
+
+// diskOrders returns the boot volume and the disks reported for a Provision's
+// block storage mappings.
+func diskOrders(provision *vmv1.Provision) []int {
+	orders := []int{0}
+	for _, b := range provision.Status.BlockStorages {
+		if b.Order != 0 {
+			orders = append(orders, b.Order)
+		}
+	}
+	sort.Ints(orders)
+	return orders
+}
+
+// backupRuns groups the backups of a policy by Provision and run.
+func (r *BackupPolicyReconciler) backupRuns(ctx context.Context, policy *vmv1.BackupPolicy) (map[string][]*backupRun, error) {
+	matching := []client.ListOption{client.InNamespace(policy.Namespace), client.MatchingLabels{vmv1.BackupPolicyLabel: policy.Name}}
+	images := &vmv1.ServerImageList{}
+	if err := r.List(ctx, images, matching...); err != nil {
+		return nil, err
+	}
+	snapshots := &vmv1.BlockStorageSnapshotList{}
+	if err := r.List(ctx, snapshots, matching...); err != nil {
+		return nil, err
+	}
+
+	runs := map[string][]*backupRun{}
+	byKey := map[string]*backupRun{}
+	add := func(object client.Object, available bool) {
+		if !object.GetDeletionTimestamp().IsZero() {
+			return
+		}
+		labels := object.GetLabels()
+		unix, err := strconv.ParseInt(labels[vmv1.BackupRunLabel], 10, 64)
+		if err != nil {
+			return
+		}
+		provision := labels[vmv1.BackupProvisionLabel]
+		key := provision + "/" + labels[vmv1.BackupRunLabel]
+		run, ok := byKey[key]
+		if !ok {
+			run = &backupRun{time: time.Unix(unix, 0), complete: true}
+			byKey[key] = run
+			runs[provision] = append(runs[provision], run)
+		}
+		run.objects = append(run.objects, object)
+		run.complete = run.complete && available
+	}
+	for i := range images.Items {
+		add(&images.Items[i], images.Items[i].Status.Phase == vmv1.ServerImagePhaseAvailable)
+	}
+	for i := range snapshots.Items {
+		add(&snapshots.Items[i], snapshots.Items[i].Status.Phase == vmv1.BlockStorageSnapshotPhaseAvailable)
+	}
+	return runs, nil
+}
+
+// reportBackups records the newest complete run of each Provision in its
+// LastBackupTime.
+func (r *BackupPolicyReconciler) reportBackups(ctx context.Context, namespace string, runs map[string][]*backupRun) error {
+	for name, provisionRuns := range runs {
+		var newest time.Time
+		for _, run := range provisionRuns {
+			if run.complete && run.time.After(newest) {
+				newest = run.time
+			}
+		}
+		if newest.IsZero() {
+			continue
+		}
+		provision := &vmv1.Provision{}
+		if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, provision); err != nil {
+			if errors.IsNotFound(err) {
+				continue
+			}
+			return err
+		}
+		if last := provision.Status.LastBackupTime; last != nil && !last.Time.Before(newest) {
+			continue
+		}
+		patch := client.MergeFrom(provision.DeepCopy())
+		provision.Status.LastBackupTime = &metav1.Time{Time: newest}
+		if err := r.Status().Patch(ctx, provision, patch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// prune deletes the complete runs the retention does not keep. Runs still
+// being created are kept.
+func (r *BackupPolicyReconciler) prune(ctx context.Context, policy *vmv1.BackupPolicy, location *time.Location, runs map[string][]*backupRun) error {
+	retention := policy.Spec.Retention
+	for provision, provisionRuns := range runs {
+		var times []time.Time
+		for _, run := range provisionRuns {
+			if run.complete {
+				times = append(times, run.time)
+			}
+		}
+		keep := backup.Retain(times, retention.Daily, retention.Weekly, location)
+		for _, run := range provisionRuns {
+			if !run.complete || keep[run.time] {
+				continue
+			}
+			for _, object := range run.objects {
+				if err := r.Delete(ctx, object); client.IgnoreNotFound(err) != nil {
+					return err
+				}
+			}
+			recordEvent(ctx, r.Recorder, policy, corev1.EventTypeNormal, eventReasonBackupPruned,
+				"Deleted the backup of %s taken at %s", provision, run.time.In(location).Format(time.RFC3339))
+		}
+	}
+	return nil
+}
+
+// policyForBackup enqueues the BackupPolicy that created a backup.
+func (r *BackupPolicyReconciler) policyForBackup(ctx context.Context, obj client.Object) []reconcile.Request {
+	name, ok := obj.GetLabels()[vmv1.BackupPolicyLabel]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: name}}}
+}
+
+// SetupWithManager sets up the controller with the Manager. Backups are
+// watched by label rather than owned, so that they outlive their policy.
+func (r *BackupPolicyReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&vmv1.BackupPolicy{}).
+		Watches(&vmv1.ServerImage{}, handler.EnqueueRequestsFromMapFunc(r.policyForBackup)).
+		Watches(&vmv1.BlockStorageSnapshot{}, handler.EnqueueRequestsFromMapFunc(r.policyForBackup)).
+		Complete(traced("BackupPolicy", r))
+}
@@ -0,0 +1,218 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+// snapshotFinalizer keeps a BlockStorageSnapshot until its NCP snapshot is deleted.
+const snapshotFinalizer = "vm.cloudclub.io/block-storage-snapshot"
+
+// snapshotNamePrefix starts the NCP names derived for BlockStorageSnapshots.
+const snapshotNamePrefix = "aviator-snap-"
+
+// BlockStorageSnapshotReconciler creates NCP snapshots of the volumes of
+// Provisions and Data and deletes them with their BlockStorageSnapshot.
+type BlockStorageSnapshotReconciler struct {
+	client.Client
+	Scheme    *runtime.Scheme
+	Recorder  record.EventRecorder
+	ncpClient *ncp.Client
+}
+
+func NewBlockStorageSnapshotReconciler(client client.Client, scheme *runtime.Scheme, recorder record.EventRecorder, ncpClient *ncp.Client) *BlockStorageSnapshotReconciler {
+	return &BlockStorageSnapshotReconciler{
+		Client:    client,
+		Scheme:    scheme,
+		Recorder:  recorder,
+		ncpClient: ncpClient,
+	}
+}
+
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=blockstoragesnapshots,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=blockstoragesnapshots/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=blockstoragesnapshots/finalizers,verbs=update
+
+// Reconcile creates the snapshot of a BlockStorageSnapshot once its source
+// volume exists and follows it until it is available.
+func (r *BlockStorageSnapshotReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return reconcileNCPResource(ctx, r.Client, r.Recorder, req, &vmv1.BlockStorageSnapshot{}, ncpResource[*vmv1.BlockStorageSnapshot]{
+		kind:       "BlockStorageSnapshot",
+		finalizer:  snapshotFinalizer,
+		status:     func(snapshot *vmv1.BlockStorageSnapshot) interface{} { return snapshot.Status.DeepCopy() },
+		conditions: func(snapshot *vmv1.BlockStorageSnapshot) *[]metav1.Condition { return &snapshot.Status.Conditions },
+		reconcile:  r.reconcileSnapshot,
+		remove:     r.remove,
+	})
+}
+
+func (r *BlockStorageSnapshotReconciler) reconcileSnapshot(ctx context.Context, log logr.Logger, snapshot *vmv1.BlockStorageSnapshot) (ctrl.Result, error) {
+	if snapshot.Status.SnapshotInstanceNo == "" {
+		return r.create(ctx, log, snapshot)
+	}
+	if snapshot.Status.Phase == vmv1.BlockStorageSnapshotPhaseAvailable {
+		return ctrl.Result{}, nil
+	}
+
+	instance, err := r.ncpClient.GetBlockStorageSnapshotInstanceDetail(ctx, snapshot.Status.RegionCode, snapshot.Status.SnapshotInstanceNo)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !instance.Available() {
+		log.V(LogLevelDebug).Info("Waiting for block storage snapshot", "snapshotInstanceNo", instance.BlockStorageSnapshotInstanceNo,
+			"status", instance.BlockStorageSnapshotInstanceStatus.Code)
+		return ctrl.Result{RequeueAfter: creationPollInterval}, nil
+	}
+	snapshot.Status.Phase = vmv1.BlockStorageSnapshotPhaseAvailable
+	snapshot.Status.BlockStorageSize = instance.SizeGiB()
+	recordEvent(ctx, r.Recorder, snapshot, corev1.EventTypeNormal, eventReasonSnapshotAvailable,
+		"Snapshot %s is available", instance.BlockStorageSnapshotInstanceNo)
+	return ctrl.Result{}, nil
+}
+
+// create requests the snapshot once the source volume exists.
+func (r *BlockStorageSnapshotReconciler) create(ctx context.Context, log logr.Logger, snapshot *vmv1.BlockStorageSnapshot) (ctrl.Result, error) {
+	regionCode, blockStorageInstanceNo, err := sourceVolume(ctx, r.Client, r.ncpClient, snapshot.Namespace, &snapshot.Spec.Source)
+	if err != nil {
+		snapshot.Status.Phase = vmv1.BlockStorageSnapshotPhasePending
+		return ctrl.Result{}, err
+	}
+
+	name := ncpName(snapshotNamePrefix, snapshot.Namespace, snapshot.Name)
+	instance, err := r.ncpClient.FindBlockStorageSnapshotInstance(ctx, regionCode, name)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if instance != nil && instance.OriginalBlockStorageInstanceNo != blockStorageInstanceNo {
+		return ctrl.Result{}, &ncp.Error{Kind: ncp.ErrorKindInvalidArgument,
+			Message: fmt.Sprintf("snapshot name %s is used by snapshot %s of volume %s", name,
+				instance.BlockStorageSnapshotInstanceNo, instance.OriginalBlockStorageInstanceNo)}
+	}
+	if instance == nil {
+		instance, err = r.ncpClient.CreateBlockStorageSnapshotInstance(ctx, regionCode, blockStorageInstanceNo, name, snapshot.Spec.Description)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	snapshot.Status.Phase = vmv1.BlockStorageSnapshotPhaseCreating
+	snapshot.Status.SnapshotInstanceNo = instance.BlockStorageSnapshotInstanceNo
+	snapshot.Status.RegionCode = regionCode
+	snapshot.Status.BlockStorageInstanceNo = blockStorageInstanceNo
+	snapshot.Status.SnapshotName = name
+	log.V(LogLevelInfo).Info("Block storage snapshot requested", "snapshotInstanceNo", instance.BlockStorageSnapshotInstanceNo,
+		"blockStorageInstanceNo", blockStorageInstanceNo)
+	recordEvent(ctx, r.Recorder, snapshot, corev1.EventTypeNormal, eventReasonSnapshotRequested,
+		"Requested snapshot %s (%s) of volume %s", name, instance.BlockStorageSnapshotInstanceNo, blockStorageInstanceNo)
+	return ctrl.Result{RequeueAfter: creationPollInterval}, nil
+}
+
+// remove deletes the NCP snapshot, if any.
+func (r *BlockStorageSnapshotReconciler) remove(ctx context.Context, log logr.Logger, snapshot *vmv1.BlockStorageSnapshot) error {
+	no := snapshot.Status.SnapshotInstanceNo
+	if no == "" {
+		return nil
+	}
+	if err := r.ncpClient.DeleteBlockStorageSnapshotInstances(ctx, snapshot.Status.RegionCode, no); err != nil {
+		return err
+	}
+	log.V(LogLevelInfo).Info("Block storage snapshot deleted", "snapshotInstanceNo", no)
+	recordEvent(ctx, r.Recorder, snapshot, corev1.EventTypeNormal, eventReasonSnapshotDeleted, "Deleted snapshot %s", no)
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *BlockStorageSnapshotReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&vmv1.BlockStorageSnapshot{}).
+		Complete(traced("BlockStorageSnapshot", r))
+}
+
+// sourceVolume returns the region and number of the volume a snapshot is
+// taken of. Order 0 of a Provision without a boot volume mapping is looked
+// up among the volumes of its server.
+func sourceVolume(ctx context.Context, c client.Reader, ncpClient *ncp.Client, namespace string, source *vmv1.BlockStorageSource) (string, string, error) {
+	if source.DataName != "" {
+		data := &vmv1.Data{}
+		if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: source.DataName}, data); err != nil {
+			if errors.IsNotFound(err) {
+				return "", "", &dependencyNotReadyError{kind: "Data", name: source.DataName, reason: "not found"}
+			}
+			return "", "", err
+		}
+		if data.Status.Phase != vmv1.DataPhaseAvailable {
+			return "", "", &dependencyNotReadyError{kind: "Data", name: source.DataName, reason: "not available yet"}
+		}
+		return data.Status.RegionCode, data.Status.BlockStorageInstanceNo, nil
+	}
+
+	provision := &vmv1.Provision{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: source.ProvisionName}, provision); err != nil {
+		if errors.IsNotFound(err) {
+			return "", "", &dependencyNotReadyError{kind: "Provision", name: source.ProvisionName, reason: "not found"}
+		}
+		return "", "", err
+	}
+	for _, b := range provision.Status.BlockStorages {
+		if b.Order == source.Order && b.BlockStorageInstanceNo != "" {
+			return provision.Spec.RegionCode, b.BlockStorageInstanceNo, nil
+		}
+	}
+	if serverInstanceNo := managedServerInstanceNo(provision); source.Order == 0 && serverInstanceNo != "" {
+		resp, err := ncpClient.GetBlockStorageInstanceList(ctx, provision.Spec.RegionCode, serverInstanceNo)
+		if err != nil {
+			return "", "", err
+		}
+		for _, b := range resp.BlockStorageInstanceList {
+			if b.BlockStorageType.Code == ncp.BlockStorageTypeBasic {
+				return provision.Spec.RegionCode, b.BlockStorageInstanceNo, nil
+			}
+		}
+	}
+	return "", "", &dependencyNotReadyError{kind: "Provision", name: source.ProvisionName,
+		reason: fmt.Sprintf("without a volume of order %d", source.Order)}
+}
+
+// availableSnapshot returns the BlockStorageSnapshot a snapshotRef names once
+// its snapshot can be restored.
+func availableSnapshot(ctx context.Context, c client.Reader, namespace, name string) (*vmv1.BlockStorageSnapshot, error) {
+	snapshot := &vmv1.BlockStorageSnapshot{}
+	if err := c.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, snapshot); err != nil {
+		if errors.IsNotFound(err) {
+			return nil, &dependencyNotReadyError{kind: "BlockStorageSnapshot", name: name, reason: "not found"}
+		}
+		return nil, err
+	}
+	if snapshot.Status.Phase != vmv1.BlockStorageSnapshotPhaseAvailable {
+		return nil, &dependencyNotReadyError{kind: "BlockStorageSnapshot", name: name, reason: "not available yet"}
+	}
+	return snapshot, nil
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/backup"
+)
+
+// BlockStorageSnapshotScheduleReconciler creates the BlockStorageSnapshots of
+// a schedule when they are due and deletes those beyond its retention.
+type BlockStorageSnapshotScheduleReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=blockstoragesnapshotschedules,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=blockstoragesnapshotschedules/status,verbs=get;update;patch
+
+// Reconcile creates a BlockStorageSnapshot for the latest run that fell due,
+// prunes old snapshots and requeues for the next run.
+func (r *BlockStorageSnapshotScheduleReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	schedule := &vmv1.BlockStorageSnapshotSchedule{}
+	if err := r.Get(ctx, req.NamespacedName, schedule); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get BlockStorageSnapshotSchedule resource")
+		return ctrl.Result{}, err
+	}
+
+	if err := r.prune(ctx, schedule); err != nil {
+		log.Error(err, "Failed to prune snapshots")
+		return ctrl.Result{}, err
+	}
+	if schedule.Spec.Suspend {
+		return ctrl.Result{}, nil
+	}
+
+	parsed, err := backup.Parse(schedule.Spec.Schedule, schedule.Spec.TimeZone)
+	if err != nil {
+		log.Error(err, "Invalid snapshot schedule")
+		recordEvent(ctx, r.Recorder, schedule, corev1.EventTypeWarning, eventReasonInvalidSchedule, "%s", err.Error())
+		return ctrl.Result{}, nil
+	}
+	last := schedule.CreationTimestamp.Time
+	if schedule.Status.LastScheduleTime != nil {
+		last = schedule.Status.LastScheduleTime.Time
+	}
+	now := time.Now()
+	due, scheduled, next := parsed.Due(last, now)
+	if due {
+		name, err := r.createSnapshot(ctx, schedule, scheduled)
+		if err != nil {
+			log.Error(err, "Failed to create snapshot")
+			return ctrl.Result{}, err
+		}
+		log.V(LogLevelInfo).Info("Scheduled block storage snapshot", "snapshot", name, "scheduled", scheduled)
+		recordEvent(ctx, r.Recorder, schedule, corev1.EventTypeNormal, eventReasonSnapshotScheduled, "Created BlockStorageSnapshot %s", name)
+		schedule.Status.LastScheduleTime = &metav1.Time{Time: scheduled}
+		schedule.Status.LastSnapshotName = name
+	}
+	schedule.Status.NextScheduleTime = &metav1.Time{Time: next}
+	if err := r.Status().Update(ctx, schedule); err != nil {
+		log.Error(err, "Failed to update BlockStorageSnapshotSchedule status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: next.Sub(now)}, nil
+}
+
+// createSnapshot creates the BlockStorageSnapshot of one run, named after the
+// schedule and the run's time so a retried run does not create a second one.
+func (r *BlockStorageSnapshotScheduleReconciler) createSnapshot(ctx context.Context, schedule *vmv1.BlockStorageSnapshotSchedule, scheduled time.Time) (string, error) {
+	snapshot := &vmv1.BlockStorageSnapshot{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      fmt.Sprintf("%s-%d", schedule.Name, scheduled.Unix()/60),
+			Namespace: schedule.Namespace,
+			Labels:    map[string]string{vmv1.SnapshotScheduleLabel: schedule.Name},
+		},
+		Spec: vmv1.BlockStorageSnapshotSpec{
+			Source:      schedule.Spec.Source,
+			Description: fmt.Sprintf("scheduled by %s/%s", schedule.Namespace, schedule.Name),
+		},
+	}
+	if err := controllerutil.SetControllerReference(schedule, snapshot, r.Scheme); err != nil {
+		return "", err
+	}
+	if err := r.Create(ctx, snapshot); err != nil && !errors.IsAlreadyExists(err) {
+		return "", err
+	}
+	return snapshot.Name, nil
+}
+
+// prune deletes the available snapshots of the schedule beyond its retention.
+func (r *BlockStorageSnapshotScheduleReconciler) prune(ctx context.Context, schedule *vmv1.BlockStorageSnapshotSchedule) error {
+	snapshots := &vmv1.BlockStorageSnapshotList{}
+	if err := r.List(ctx, snapshots, client.InNamespace(schedule.Namespace),
+		client.MatchingLabels{vmv1.SnapshotScheduleLabel: schedule.Name}); err != nil {
+		return err
+	}
+	expired := backup.Expired(snapshots.Items, schedule.Spec.Retention)
+	for i := range expired {
+		if err := r.Delete(ctx, &expired[i]); client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		recordEvent(ctx, r.Recorder, schedule, corev1.EventTypeNormal, eventReasonSnapshotPruned, "Deleted BlockStorageSnapshot %s", expired[i].Name)
+	}
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager. Owning the
+// snapshots requeues the schedule when one becomes available, so that older
+// ones are pruned.
+func (r *BlockStorageSnapshotScheduleReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&vmv1.BlockStorageSnapshotSchedule{}).
+		Owns(&vmv1.BlockStorageSnapshot{}).
+		Complete(traced("BlockStorageSnapshotSchedule", r))
+}
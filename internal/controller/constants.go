@@ -1,16 +1,104 @@
 package controller
 
+import "time"
+
 const (
 	apiUrlCreate = "https://ncloud.apigw.ntruss.com/vserver/v2/createServerInstances"
 	apiUrlDelete = "https://ncloud.apigw.ntruss.com/vserver/v2/terminateServerInstances"
 	apiUrlGet    = "https://ncloud.apigw.ntruss.com/vserver/v2/getMemberServerImageInstanceList"
 	apiUrlStop   = "https://ncloud.apigw.ntruss.com/vserver/v2/stopServerInstances"
 	apiUrlUpdate = "https://ncloud.apigw.ntruss.com/vserver/v2/changeServerInstanceSpec"
-	// error level
-	ErrorLevelIsInfo    = 0
-	ErrorLevelIsFatal   = 1
-	ErrorLevelIsAnError = 2
-	ErrorLevelIsWarn    = 3
-	ErrorLevelIsDebug   = 5
-	ErrorLevelIsTrace   = 6
+	apiUrlAdopt  = "https://ncloud.apigw.ntruss.com/vserver/v2/getServerInstanceList"
+	// logr verbosity, passed to V. Errors are logged with Error, which has no
+	// verbosity, and are always shown.
+	LogLevelInfo  = 0
+	LogLevelDebug = 1
+	// NCP request and response payloads, redacted, are only logged at this level
+	LogLevelTrace = 2
+
+	// how often to poll NCP until created block storages are reported
+	blockStorageRequeueInterval = 15 * time.Second
+	// how long to back off after the NCP API gateway throttled a call
+	throttledRequeueInterval = 30 * time.Second
+	// how long to wait for quota to be freed before creating a server again
+	quotaRequeueInterval = time.Minute
+	// how often a server being stopped or terminated on expiry is checked
+	expiryRequeueInterval = 15 * time.Second
+	// how often the server of a deleted Provision is checked until it is terminated
+	terminationRequeueInterval = 15 * time.Second
+	// how often a server being stopped, changed or started for a resize is checked
+	resizeRequeueInterval = 15 * time.Second
+	// how often to check whether an object a Provision refers to became ready
+	dependencyRequeueInterval = 15 * time.Second
+	// how often an image or snapshot being created is checked
+	creationPollInterval = 30 * time.Second
+	// how often provisioned servers are checked for drift unless overridden
+	defaultResyncPeriod = 5 * time.Minute
+	// the longest a pre-stop hook is waited for, whatever its own timeout
+	preStopHookMaxTimeout = 5 * time.Minute
+
+	// servers whose name starts with this prefix are owned by the operator
+	ownedServerNamePrefix = "aviator-"
+	// how often and after how long orphan servers are collected unless overridden
+	defaultOrphanCollectInterval = 10 * time.Minute
+	defaultOrphanGracePeriod     = time.Hour
+)
+
+// Reasons of the events recorded on Provisions and the objects backed by NCP resources.
+const (
+	eventReasonCreationRequested     = "CreationRequested"
+	eventReasonServerFound           = "ServerFound"
+	eventReasonAdopted               = "Adopted"
+	eventReasonServerRunning         = "ServerRunning"
+	eventReasonServerStopped         = "ServerStopped"
+	eventReasonSpecChangeRequested   = "SpecChangeRequested"
+	eventReasonStopRequested         = "StopRequested"
+	eventReasonTerminationRequested  = "TerminationRequested"
+	eventReasonDriftDetected         = "DriftDetected"
+	eventReasonDriftCorrected        = "DriftCorrected"
+	eventReasonQuotaExceeded         = "VMQuotaExceeded"
+	eventReasonPolicyViolation       = "PolicyViolation"
+	eventReasonScheduledStart        = "ScheduledStart"
+	eventReasonScheduledStop         = "ScheduledStop"
+	eventReasonInvalidPowerSchedule  = "InvalidPowerSchedule"
+	eventReasonExpiringSoon          = "ExpiringSoon"
+	eventReasonExpired               = "Expired"
+	eventReasonWaiting               = "Waiting"
+	eventReasonImageCreationStarted  = "ImageCreationRequested"
+	eventReasonImageAvailable        = "ImageAvailable"
+	eventReasonImageFailed           = "ImageFailed"
+	eventReasonImageDeleted          = "ImageDeleted"
+	eventReasonSnapshotRequested     = "SnapshotRequested"
+	eventReasonSnapshotAvailable     = "SnapshotAvailable"
+	eventReasonSnapshotDeleted       = "SnapshotDeleted"
+	eventReasonSnapshotScheduled     = "SnapshotScheduled"
+	eventReasonSnapshotPruned        = "SnapshotPruned"
+	eventReasonInvalidSchedule       = "InvalidSnapshotSchedule"
+	eventReasonVolumeRequested       = "VolumeRequested"
+	eventReasonVolumeAvailable       = "VolumeAvailable"
+	eventReasonVolumeDeleted         = "VolumeDeleted"
+	eventReasonVolumeLost            = "VolumeLost"
+	eventReasonExpansionRequested    = "VolumeExpansionRequested"
+	eventReasonVolumeExpanded        = "VolumeExpanded"
+	eventReasonBackupScheduled       = "BackupScheduled"
+	eventReasonBackupPruned          = "BackupPruned"
+	eventReasonInvalidBackupPolicy   = "InvalidBackupPolicy"
+	eventReasonResizeWaiting         = "ResizeWaitingForWindow"
+	eventReasonResizeStarted         = "ResizeStarted"
+	eventReasonResizeCompleted       = "ResizeCompleted"
+	eventReasonPreStopHookFailed     = "PreStopHookFailed"
+	eventReasonStartRequested        = "StartRequested"
+	eventReasonInvalidWindow         = "InvalidMaintenanceWindow"
+	eventReasonPlacementGroupCreated = "PlacementGroupCreated"
+	eventReasonPlacementGroupDeleted = "PlacementGroupDeleted"
+	eventReasonReplicaCreated        = "ReplicaCreated"
+	eventReasonReplicaDeleted        = "ReplicaDeleted"
+	eventReasonNoZoneSubnet          = "NoZoneSubnet"
+	eventReasonTargetGroupCreated    = "TargetGroupCreated"
+	eventReasonTargetGroupDeleted    = "TargetGroupDeleted"
+	eventReasonTargetAdded           = "TargetAdded"
+	eventReasonTargetRemoved         = "TargetRemoved"
+	eventReasonLoadBalancerRequested = "LoadBalancerRequested"
+	eventReasonLoadBalancerAvailable = "LoadBalancerAvailable"
+	eventReasonLoadBalancerDeleted   = "LoadBalancerDeleted"
 )
@@ -0,0 +1,147 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+// CostReportReconciler sums the cost estimates of Provisions into the
+// CostReports of their namespace.
+type CostReportReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=costreports,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=costreports/status,verbs=get;update;patch
+
+// Reconcile recomputes the totals of a CostReport from the Provisions it selects.
+func (r *CostReportReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	report := &vmv1.CostReport{}
+	if err := r.Get(ctx, req.NamespacedName, report); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get CostReport resource")
+		return ctrl.Result{}, err
+	}
+
+	selector := labels.Everything()
+	if report.Spec.Selector != nil {
+		var err error
+		if selector, err = metav1.LabelSelectorAsSelector(report.Spec.Selector); err != nil {
+			log.Error(err, "Invalid CostReport selector")
+			return ctrl.Result{}, nil
+		}
+	}
+	provisions := &vmv1.ProvisionList{}
+	if err := r.List(ctx, provisions, client.InNamespace(report.Namespace),
+		client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		log.Error(err, "Failed to list Provisions")
+		return ctrl.Result{}, err
+	}
+
+	status := costReportStatus(provisions.Items)
+	status.LastUpdateTime = report.Status.LastUpdateTime
+	if equality.Semantic.DeepEqual(status, &report.Status) {
+		return ctrl.Result{}, nil
+	}
+	now := metav1.Now()
+	status.LastUpdateTime = &now
+	report.Status = *status
+	if err := r.Status().Update(ctx, report); err != nil {
+		log.Error(err, "Failed to update CostReport status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// costReportStatus sums the estimates of provisions by currency.
+func costReportStatus(provisions []vmv1.Provision) *vmv1.CostReportStatus {
+	status := &vmv1.CostReportStatus{}
+	hourly, monthly := map[string]float64{}, map[string]float64{}
+	for _, p := range provisions {
+		cost := p.Status.Cost
+		if cost == nil {
+			if p.Spec.Phase != vmv1.ProvisionPhaseDelete {
+				status.Unpriced = append(status.Unpriced, p.Name)
+			}
+			continue
+		}
+		status.Provisions = append(status.Provisions, vmv1.ProvisionCost{
+			Name:     p.Name,
+			Currency: cost.Currency,
+			Hourly:   cost.Hourly,
+			Monthly:  cost.Monthly,
+		})
+		hourly[cost.Currency] += parseAmount(cost.Hourly)
+		monthly[cost.Currency] += parseAmount(cost.Monthly)
+	}
+	for currency := range hourly {
+		status.Totals = append(status.Totals, vmv1.CostTotal{
+			Currency: currency,
+			Hourly:   formatAmount(hourly[currency]),
+			Monthly:  formatAmount(monthly[currency]),
+		})
+	}
+	sort.Slice(status.Totals, func(i, j int) bool { return status.Totals[i].Currency < status.Totals[j].Currency })
+	sort.Slice(status.Provisions, func(i, j int) bool { return status.Provisions[i].Name < status.Provisions[j].Name })
+	sort.Strings(status.Unpriced)
+	return status
+}
+
+// reportsForProvision enqueues the CostReports of a Provision's namespace.
+func (r *CostReportReconciler) reportsForProvision(ctx context.Context, obj client.Object) []reconcile.Request {
+	reports := &vmv1.CostReportList{}
+	if err := r.List(ctx, reports, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list CostReports")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(reports.Items))
+	for _, report := range reports.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: report.Namespace, Name: report.Name},
+		})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *CostReportReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&vmv1.CostReport{}).
+		Watches(&vmv1.Provision{}, handler.EnqueueRequestsFromMapFunc(r.reportsForProvision)).
+		Complete(traced("CostReport", r))
+}
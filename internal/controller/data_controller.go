@@ -19,44 +19,190 @@ package controller
 import (
 	"context"
 
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
-	"sigs.k8s.io/controller-runtime/pkg/log"
 
 	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
 )
 
-// DataReconciler reconciles a Data object
+// dataFinalizer keeps a Data until its NCP volume is deleted.
+const dataFinalizer = "vm.cloudclub.io/data"
+
+// dataNamePrefix starts the NCP names derived for Data volumes.
+const dataNamePrefix = "aviator-data-"
+
+// DataReconciler creates NCP block storage volumes, empty or restored from a
+// snapshot, on the servers of Provisions and deletes them with their Data.
 type DataReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme    *runtime.Scheme
+	Recorder  record.EventRecorder
+	ncpClient *ncp.Client
+}
+
+func NewDataReconciler(client client.Client, scheme *runtime.Scheme, recorder record.EventRecorder, ncpClient *ncp.Client) *DataReconciler {
+	return &DataReconciler{
+		Client:    client,
+		Scheme:    scheme,
+		Recorder:  recorder,
+		ncpClient: ncpClient,
+	}
 }
 
 //+kubebuilder:rbac:groups=vm.cloudclub.io,resources=data,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=vm.cloudclub.io,resources=data/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=vm.cloudclub.io,resources=data/finalizers,verbs=update
 
-// Reconcile is part of the main kubernetes reconciliation loop which aims to
-// move the current state of the cluster closer to the desired state.
-// TODO(user): Modify the Reconcile function to compare the state specified by
-// the Data object against the actual cluster state, and then
-// perform operations to make the cluster state reflect the state specified by
-// the user.
-//
-// For more details, check Reconcile and its Result here:
-// - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.16.3/pkg/reconcile
+// Reconcile creates the volume of a Data once its Provision has a server and
+// its snapshot, if any, is available, and follows it until it is attached.
 func (r *DataReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	_ = log.FromContext(ctx)
+	return reconcileNCPResource(ctx, r.Client, r.Recorder, req, &vmv1.Data{}, ncpResource[*vmv1.Data]{
+		kind:       "Data",
+		finalizer:  dataFinalizer,
+		status:     func(data *vmv1.Data) interface{} { return data.Status.DeepCopy() },
+		conditions: func(data *vmv1.Data) *[]metav1.Condition { return &data.Status.Conditions },
+		reconcile:  r.reconcileVolume,
+		remove:     r.remove,
+	})
+}
+
+// reconcileVolume creates the volume, checks it until it is attached and
+// expands it when the spec asks for more than its size. Available volumes
+// are checked every defaultResyncPeriod, since NCP deletes them with their
+// server.
+func (r *DataReconciler) reconcileVolume(ctx context.Context, log logr.Logger, data *vmv1.Data) (ctrl.Result, error) {
+	if data.Status.BlockStorageInstanceNo == "" {
+		return r.create(ctx, log, data)
+	}
+	if data.Status.Phase == vmv1.DataPhaseLost {
+		return ctrl.Result{}, nil
+	}
 
-	// TODO(user): your logic here
+	instance, err := r.ncpClient.GetBlockStorageInstanceDetail(ctx, data.Status.RegionCode, data.Status.BlockStorageInstanceNo)
+	if err != nil {
+		if ncp.AsError(err).Kind != ncp.ErrorKindNotFound {
+			return ctrl.Result{}, err
+		}
+		data.Status.Phase = vmv1.DataPhaseLost
+		recordEvent(ctx, r.Recorder, data, corev1.EventTypeWarning, eventReasonVolumeLost,
+			"Volume %s no longer exists", data.Status.BlockStorageInstanceNo)
+		return ctrl.Result{}, nil
+	}
+	if !instance.Attached() {
+		log.V(LogLevelDebug).Info("Waiting for block storage", "blockStorageInstanceNo", instance.BlockStorageInstanceNo,
+			"status", instance.BlockStorageInstanceStatus.Code)
+		return ctrl.Result{RequeueAfter: creationPollInterval}, nil
+	}
+	previousSize := data.Status.BlockStorageSize
+	data.Status.DeviceName = instance.DeviceName
+	data.Status.BlockStorageSize = instance.SizeGiB()
+	if want := data.Spec.BlockStorageSize; want > instance.SizeGiB() {
+		return r.expand(ctx, log, data, want)
+	}
+	switch data.Status.Phase {
+	case vmv1.DataPhaseExpanding:
+		data.Status.Phase = vmv1.DataPhaseAvailable
+		recordEvent(ctx, r.Recorder, data, corev1.EventTypeNormal, eventReasonVolumeExpanded,
+			"Volume %s expanded from %d to %d GiB", instance.BlockStorageInstanceNo, previousSize, instance.SizeGiB())
+	case vmv1.DataPhaseAvailable:
+	default:
+		data.Status.Phase = vmv1.DataPhaseAvailable
+		recordEvent(ctx, r.Recorder, data, corev1.EventTypeNormal, eventReasonVolumeAvailable,
+			"Volume %s is attached as %s", instance.BlockStorageInstanceNo, instance.DeviceName)
+	}
+	return ctrl.Result{RequeueAfter: defaultResyncPeriod}, nil
+}
+
+// expand grows the attached volume to sizeGiB and polls until it has grown.
+func (r *DataReconciler) expand(ctx context.Context, log logr.Logger, data *vmv1.Data, sizeGiB int) (ctrl.Result, error) {
+	no := data.Status.BlockStorageInstanceNo
+	if err := r.ncpClient.ChangeBlockStorageVolumeSize(ctx, data.Status.RegionCode, no, sizeGiB); err != nil {
+		return ctrl.Result{}, err
+	}
+	data.Status.Phase = vmv1.DataPhaseExpanding
+	log.V(LogLevelInfo).Info("Volume expansion requested", "blockStorageInstanceNo", no,
+		"from", data.Status.BlockStorageSize, "to", sizeGiB)
+	recordEvent(ctx, r.Recorder, data, corev1.EventTypeNormal, eventReasonExpansionRequested,
+		"Requested expansion of volume %s from %d to %d GiB", no, data.Status.BlockStorageSize, sizeGiB)
+	return ctrl.Result{RequeueAfter: creationPollInterval}, nil
+}
+
+// create requests the volume once the Provision has a server and the
+// snapshot to restore, if any, is available.
+func (r *DataReconciler) create(ctx context.Context, log logr.Logger, data *vmv1.Data) (ctrl.Result, error) {
+	data.Status.Phase = vmv1.DataPhasePending
+	provision := &vmv1.Provision{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: data.Namespace, Name: data.Spec.ProvisionName}, provision); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, &dependencyNotReadyError{kind: "Provision", name: data.Spec.ProvisionName, reason: "not found"}
+		}
+		return ctrl.Result{}, err
+	}
+	serverInstanceNo := managedServerInstanceNo(provision)
+	if serverInstanceNo == "" {
+		return ctrl.Result{}, &dependencyNotReadyError{kind: "Provision", name: data.Spec.ProvisionName, reason: "without a server"}
+	}
+	snapshotInstanceNo := data.Spec.SnapshotInstanceNo
+	if data.Spec.SnapshotRef != "" {
+		snapshot, err := availableSnapshot(ctx, r.Client, data.Namespace, data.Spec.SnapshotRef)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		snapshotInstanceNo = snapshot.Status.SnapshotInstanceNo
+	}
+
+	name := data.Spec.BlockStorageName
+	if name == "" {
+		name = ncpName(dataNamePrefix, data.Namespace, data.Name)
+	}
+	instance, err := r.ncpClient.CreateBlockStorageInstance(ctx, &ncp.CreateBlockStorageInstanceRequest{
+		RegionCode:                 provision.Spec.RegionCode,
+		ServerInstanceNo:           serverInstanceNo,
+		SnapshotInstanceNo:         snapshotInstanceNo,
+		BlockStorageName:           name,
+		BlockStorageDescription:    data.Spec.Description,
+		BlockStorageSize:           data.Spec.BlockStorageSize,
+		BlockStorageVolumeTypeCode: string(data.Spec.BlockStorageVolumeTypeCode),
+	})
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	data.Status.Phase = vmv1.DataPhaseCreating
+	data.Status.BlockStorageInstanceNo = instance.BlockStorageInstanceNo
+	data.Status.RegionCode = provision.Spec.RegionCode
+	data.Status.ServerInstanceNo = serverInstanceNo
+	log.V(LogLevelInfo).Info("Block storage creation requested", "blockStorageInstanceNo", instance.BlockStorageInstanceNo,
+		"serverInstanceNo", serverInstanceNo, "snapshotInstanceNo", snapshotInstanceNo)
+	recordEvent(ctx, r.Recorder, data, corev1.EventTypeNormal, eventReasonVolumeRequested,
+		"Requested volume %s (%s) on server %s", name, instance.BlockStorageInstanceNo, serverInstanceNo)
+	return ctrl.Result{RequeueAfter: creationPollInterval}, nil
+}
 
-	return ctrl.Result{}, nil
+// remove deletes the NCP volume, if any and not already lost.
+func (r *DataReconciler) remove(ctx context.Context, log logr.Logger, data *vmv1.Data) error {
+	no := data.Status.BlockStorageInstanceNo
+	if no == "" || data.Status.Phase == vmv1.DataPhaseLost {
+		return nil
+	}
+	if err := r.ncpClient.DeleteBlockStorageInstances(ctx, data.Status.RegionCode, no); err != nil {
+		return err
+	}
+	log.V(LogLevelInfo).Info("Block storage deleted", "blockStorageInstanceNo", no)
+	recordEvent(ctx, r.Recorder, data, corev1.EventTypeNormal, eventReasonVolumeDeleted, "Deleted volume %s", no)
+	return nil
 }
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *DataReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&vmv1.Data{}).
-		Complete(r)
+		Complete(traced("Data", r))
 }
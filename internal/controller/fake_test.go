@@ -0,0 +1,154 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path"
+	"sync"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	ncputil "github.com/cloud-club/Aviator-service/pkg"
+	"github.com/cloud-club/Aviator-service/types/auth"
+	server "github.com/cloud-club/Aviator-service/types/server"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+// emptyNCPList is how NCP answers a list or detail action with no results.
+const emptyNCPList = "<response><returnCode>0</returnCode><totalRows>0</totalRows></response>"
+
+// newFakeClient returns a client of an in-memory API server holding objects,
+// with the status subresource of every kind of the operator.
+func newFakeClient(objects ...client.Object) client.Client {
+	scheme := runtime.NewScheme()
+	Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+	Expect(vmv1.AddToScheme(scheme)).To(Succeed())
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objects...).
+		WithStatusSubresource(&vmv1.Provision{}, &vmv1.ProvisionSet{}, &vmv1.PlacementGroup{},
+			&vmv1.TargetGroup{}, &vmv1.LoadBalancer{}, &vmv1.ServerImage{}, &vmv1.Data{},
+			&vmv1.BlockStorageSnapshot{}).
+		Build()
+}
+
+// fakeNCP is an NCP API gateway answering each action with the response set
+// for it, or an empty list, and recording the actions called.
+type fakeNCP struct {
+	srv     *httptest.Server
+	client  *ncp.Client
+	mu      sync.Mutex
+	answers map[string]fakeAnswer
+	calls   []string
+}
+
+type fakeAnswer struct {
+	statusCode int
+	body       string
+}
+
+func newFakeNCP() *fakeNCP {
+	f := &fakeNCP{answers: map[string]fakeAnswer{}}
+	f.srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		action := path.Base(req.URL.Path)
+		f.mu.Lock()
+		f.calls = append(f.calls, action)
+		answer, ok := f.answers[action]
+		f.mu.Unlock()
+		if !ok {
+			answer = fakeAnswer{statusCode: http.StatusOK, body: emptyNCPList}
+		}
+		w.WriteHeader(answer.statusCode)
+		fmt.Fprint(w, answer.body)
+	}))
+	DeferCleanup(f.srv.Close)
+	f.client = ncp.NewClient(auth.NewKeyService("ak", "sk"), nil).WithAPIGateway(f.srv.URL)
+	return f
+}
+
+// answer sets the body action is answered with.
+func (f *fakeNCP) answer(action, body string) {
+	f.set(action, fakeAnswer{statusCode: http.StatusOK, body: body})
+}
+
+// reject makes action fail with statusCode and an NCP error returnMessage.
+func (f *fakeNCP) reject(action string, statusCode int, returnMessage string) {
+	f.set(action, fakeAnswer{statusCode: statusCode,
+		body: "<responseError><returnCode>1</returnCode><returnMessage>" + returnMessage + "</returnMessage></responseError>"})
+}
+
+func (f *fakeNCP) set(action string, answer fakeAnswer) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.answers[action] = answer
+}
+
+// called returns the actions called so far.
+func (f *fakeNCP) called() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.calls...)
+}
+
+// serverList answers getServerInstanceList with one server.
+func serverList(serverInstanceNo, status string) string {
+	return fmt.Sprintf("<getServerInstanceListResponse><returnCode>0</returnCode><totalRows>1</totalRows><serverInstanceList>"+
+		"<serverInstance><serverInstanceNo>%s</serverInstanceNo><serverInstanceStatus><code>%s</code></serverInstanceStatus>"+
+		"</serverInstance></serverInstanceList></getServerInstanceListResponse>", serverInstanceNo, status)
+}
+
+// fakeServerService records the power calls made through the Aviator-service.
+type fakeServerService struct {
+	ncputil.ServerInterface
+	mu      sync.Mutex
+	stopped []string
+	deleted []string
+}
+
+func (s *fakeServerService) Stop(url string, request *server.StopServerRequest) (*server.StopServerResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.stopped = append(s.stopped, request.ServerNo)
+	return &server.StopServerResponse{}, nil
+}
+
+func (s *fakeServerService) Delete(url string, request *server.DeleteServerRequest) (*server.DeleteServerResponse, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.deleted = append(s.deleted, request.ServerNo)
+	return &server.DeleteServerResponse{}, nil
+}
+
+// newTestProvisionReconciler returns a ProvisionReconciler on c calling
+// fakeNCP and, for power calls, servers.
+func newTestProvisionReconciler(c client.Client, n *fakeNCP, servers *fakeServerService) *ProvisionReconciler {
+	return NewProvisionReconciler(c, c.Scheme(), record.NewFakeRecorder(100),
+		&ncputil.NcpService{Server: servers}, n.client, ncp.NewServerCache(n.client, time.Minute))
+}
@@ -0,0 +1,197 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+// loadBalancerFinalizer keeps a LoadBalancer until its NCP load balancer is deleted.
+const loadBalancerFinalizer = "vm.cloudclub.io/load-balancer"
+
+// loadBalancerNamePrefix starts the NCP names derived for LoadBalancers.
+const loadBalancerNamePrefix = "aviator-lb-"
+
+// LoadBalancerReconciler creates NCP load balancers once the TargetGroups of
+// their listeners exist and deletes them with their LoadBalancer.
+type LoadBalancerReconciler struct {
+	client.Client
+	Scheme    *runtime.Scheme
+	Recorder  record.EventRecorder
+	ncpClient *ncp.Client
+}
+
+func NewLoadBalancerReconciler(client client.Client, scheme *runtime.Scheme, recorder record.EventRecorder, ncpClient *ncp.Client) *LoadBalancerReconciler {
+	return &LoadBalancerReconciler{
+		Client:    client,
+		Scheme:    scheme,
+		Recorder:  recorder,
+		ncpClient: ncpClient,
+	}
+}
+
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=loadbalancers,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=loadbalancers/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=loadbalancers/finalizers,verbs=update
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=targetgroups,verbs=get;list;watch
+
+// Reconcile creates the load balancer of a LoadBalancer and follows it until
+// it is available.
+func (r *LoadBalancerReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return reconcileNCPResource(ctx, r.Client, r.Recorder, req, &vmv1.LoadBalancer{}, ncpResource[*vmv1.LoadBalancer]{
+		kind:       "LoadBalancer",
+		finalizer:  loadBalancerFinalizer,
+		status:     func(lb *vmv1.LoadBalancer) interface{} { return lb.Status.DeepCopy() },
+		conditions: func(lb *vmv1.LoadBalancer) *[]metav1.Condition { return &lb.Status.Conditions },
+		reconcile:  r.reconcileLoadBalancer,
+		remove:     r.remove,
+	})
+}
+
+func (r *LoadBalancerReconciler) reconcileLoadBalancer(ctx context.Context, log logr.Logger, lb *vmv1.LoadBalancer) (ctrl.Result, error) {
+	if lb.Status.LoadBalancerInstanceNo == "" {
+		return r.create(ctx, log, lb)
+	}
+	if lb.Status.Phase == vmv1.LoadBalancerPhaseAvailable {
+		return ctrl.Result{}, nil
+	}
+
+	instance, err := r.ncpClient.GetLoadBalancerInstanceDetail(ctx, lb.Status.RegionCode, lb.Status.LoadBalancerInstanceNo)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if !instance.Ready() {
+		log.V(LogLevelDebug).Info("Waiting for load balancer", "loadBalancerInstanceNo", instance.LoadBalancerInstanceNo,
+			"status", instance.LoadBalancerInstanceStatus.Code, "operation", instance.LoadBalancerInstanceOperation.Code)
+		return ctrl.Result{RequeueAfter: creationPollInterval}, nil
+	}
+	lb.Status.Phase = vmv1.LoadBalancerPhaseAvailable
+	lb.Status.Domain = instance.LoadBalancerDomain
+	lb.Status.IPs = instance.LoadBalancerIpList
+	recordEvent(ctx, r.Recorder, lb, corev1.EventTypeNormal, eventReasonLoadBalancerAvailable,
+		"Load balancer %s is available at %s", instance.LoadBalancerInstanceNo, instance.LoadBalancerDomain)
+	return ctrl.Result{}, nil
+}
+
+// create requests the load balancer once the TargetGroup of every listener
+// has its NCP target group.
+func (r *LoadBalancerReconciler) create(ctx context.Context, log logr.Logger, lb *vmv1.LoadBalancer) (ctrl.Result, error) {
+	listeners := make([]ncp.LoadBalancerListener, 0, len(lb.Spec.Listeners))
+	for _, l := range lb.Spec.Listeners {
+		targetGroupNo, err := r.targetGroup(ctx, lb.Namespace, l.TargetGroupRef)
+		if err != nil {
+			lb.Status.Phase = vmv1.LoadBalancerPhasePending
+			return ctrl.Result{}, err
+		}
+		listeners = append(listeners, ncp.LoadBalancerListener{
+			ProtocolTypeCode: l.Protocol,
+			Port:             int(l.Port),
+			TargetGroupNo:    targetGroupNo,
+			SSLCertificateNo: l.SSLCertificateNo,
+		})
+	}
+
+	name := lb.Spec.LoadBalancerName
+	if name == "" {
+		name = ncpName(loadBalancerNamePrefix, lb.Namespace, lb.Name)
+	}
+	// A load balancer under the derived name was requested by an earlier
+	// attempt whose status update was lost and is followed like a new one
+	// until available. One named in the spec is not ours to delete.
+	instance, err := r.ncpClient.FindLoadBalancerInstance(ctx, lb.Spec.RegionCode, lb.Spec.VpcNo, name)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if instance != nil && lb.Spec.LoadBalancerName != "" {
+		return ctrl.Result{}, &ncp.Error{Kind: ncp.ErrorKindInvalidArgument,
+			Message: fmt.Sprintf("load balancer %s already exists as %s", name, instance.LoadBalancerInstanceNo)}
+	}
+	if instance == nil {
+		instance, err = r.ncpClient.CreateLoadBalancerInstance(ctx, &ncp.CreateLoadBalancerInstanceRequest{
+			RegionCode:                  lb.Spec.RegionCode,
+			VpcNo:                       lb.Spec.VpcNo,
+			LoadBalancerName:            name,
+			LoadBalancerDescription:     lb.Spec.Description,
+			LoadBalancerTypeCode:        string(lb.Spec.Type),
+			LoadBalancerNetworkTypeCode: string(lb.Spec.NetworkType),
+			IdleTimeout:                 int(lb.Spec.IdleTimeoutSeconds),
+			SubnetNoList:                lb.Spec.SubnetNos,
+			LoadBalancerListenerList:    listeners,
+		})
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		recordEvent(ctx, r.Recorder, lb, corev1.EventTypeNormal, eventReasonLoadBalancerRequested,
+			"Requested load balancer %s (%s)", name, instance.LoadBalancerInstanceNo)
+	}
+	lb.Status.Phase = vmv1.LoadBalancerPhaseCreating
+	lb.Status.LoadBalancerInstanceNo = instance.LoadBalancerInstanceNo
+	lb.Status.RegionCode = lb.Spec.RegionCode
+	lb.Status.LoadBalancerName = name
+	log.V(LogLevelInfo).Info("Load balancer creation requested", "loadBalancerInstanceNo", instance.LoadBalancerInstanceNo)
+	return ctrl.Result{RequeueAfter: creationPollInterval}, nil
+}
+
+// targetGroup returns the NCP target group of a TargetGroup once created.
+func (r *LoadBalancerReconciler) targetGroup(ctx context.Context, namespace, name string) (string, error) {
+	group := &vmv1.TargetGroup{}
+	if err := r.Get(ctx, types.NamespacedName{Namespace: namespace, Name: name}, group); err != nil {
+		if errors.IsNotFound(err) {
+			return "", &dependencyNotReadyError{kind: "TargetGroup", name: name, reason: "not found"}
+		}
+		return "", err
+	}
+	if group.Status.TargetGroupNo == "" {
+		return "", &dependencyNotReadyError{kind: "TargetGroup", name: name, reason: "not created yet"}
+	}
+	return group.Status.TargetGroupNo, nil
+}
+
+// remove deletes the NCP load balancer, if any.
+func (r *LoadBalancerReconciler) remove(ctx context.Context, log logr.Logger, lb *vmv1.LoadBalancer) error {
+	no := lb.Status.LoadBalancerInstanceNo
+	if no == "" {
+		return nil
+	}
+	if err := r.ncpClient.DeleteLoadBalancerInstances(ctx, lb.Status.RegionCode, no); err != nil {
+		return err
+	}
+	log.V(LogLevelInfo).Info("Load balancer deleted", "loadBalancerInstanceNo", no)
+	recordEvent(ctx, r.Recorder, lb, corev1.EventTypeNormal, eventReasonLoadBalancerDeleted, "Deleted load balancer %s", no)
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *LoadBalancerReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&vmv1.LoadBalancer{}).
+		Complete(traced("LoadBalancer", r))
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/go-logr/logr"
+)
+
+// RedactAPIPayloads masks secrets and addresses in the NCP payloads logged at
+// LogLevelTrace. It is only meant to be turned off to debug the API itself.
+var RedactAPIPayloads = true
+
+const redacted = "REDACTED"
+
+// sensitiveFieldSuffixes match, case insensitively, the ends of payload field
+// names whose values are redacted.
+var sensitiveFieldSuffixes = []string{"password", "secret", "secretkey", "accesskey", "privatekey", "loginkey", "ip", "ipaddress", "ipaddr", "userdata", "initscript"}
+
+// logAPIPayload logs an NCP request or response at trace level.
+func logAPIPayload(log logr.Logger, msg string, payload interface{}) {
+	trace := log.V(LogLevelTrace)
+	if !trace.Enabled() {
+		return
+	}
+	if RedactAPIPayloads {
+		payload = redact(payload)
+	}
+	trace.Info(msg, "payload", payload)
+}
+
+// redact returns payload as generic JSON values with sensitive fields masked.
+func redact(payload interface{}) interface{} {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return redacted
+	}
+	var v interface{}
+	if err := json.Unmarshal(data, &v); err != nil {
+		return redacted
+	}
+	return redactValue(v)
+}
+
+func redactValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, field := range v {
+			if sensitiveField(k) {
+				if field != nil && field != "" {
+					v[k] = redacted
+				}
+				continue
+			}
+			v[k] = redactValue(field)
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redactValue(v[i])
+		}
+	}
+	return v
+}
+
+func sensitiveField(name string) bool {
+	name = strings.ToLower(name)
+	for _, suffix := range sensitiveFieldSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return false
+}
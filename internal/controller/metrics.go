@@ -0,0 +1,91 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+var (
+	timeToRunning = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "aviator_provision_time_to_running_seconds",
+		Help:    "Time from the creation of a Provision until its new server was first seen running.",
+		Buckets: prometheus.ExponentialBuckets(30, 1.5, 12),
+	})
+
+	serversDesc = prometheus.NewDesc(
+		"aviator_servers",
+		"Servers managed by Provisions by last observed status and server product code.",
+		[]string{"status", "product_code"}, nil,
+	)
+)
+
+func init() {
+	metrics.Registry.MustRegister(timeToRunning)
+}
+
+// observeTimeToRunning records how long a created server took to run, when
+// the status change is the end of its creation.
+func observeTimeToRunning(original *vmv1.Provision, previous string) {
+	if original.Status.ServerStatus != ncp.ServerStatusRunning || original.Spec.Adopt != nil {
+		return
+	}
+	if previous == ncp.ServerStatusInit || previous == ncp.ServerStatusCreating {
+		timeToRunning.Observe(time.Since(original.CreationTimestamp.Time).Seconds())
+	}
+}
+
+// ServerCollector reports the aviator_servers gauge from the Provisions in
+// the manager's cache when scraped, so it never disagrees with their status.
+type ServerCollector struct {
+	reader client.Reader
+}
+
+func NewServerCollector(reader client.Reader) *ServerCollector {
+	return &ServerCollector{reader: reader}
+}
+
+func (c *ServerCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- serversDesc
+}
+
+func (c *ServerCollector) Collect(ch chan<- prometheus.Metric) {
+	provisions := &vmv1.ProvisionList{}
+	if err := c.reader.List(context.Background(), provisions); err != nil {
+		return
+	}
+	type key struct{ status, productCode string }
+	counts := map[key]int{}
+	for i := range provisions.Items {
+		p := &provisions.Items[i]
+		if p.Status.ServerStatus == "" {
+			continue
+		}
+		counts[key{p.Status.ServerStatus, p.Spec.Server.ProductCode}]++
+	}
+	for k, n := range counts {
+		ch <- prometheus.MustNewConstMetric(serversDesc, prometheus.GaugeValue, float64(n), k.status, k.productCode)
+	}
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/api/equality"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	"vm.cloudclub.io/internal/ncp"
+)
+
+// ncpResource describes how an object backed by an NCP resource, such as a
+// ServerImage, is reconciled by reconcileNCPResource.
+type ncpResource[T client.Object] struct {
+	kind      string
+	finalizer string
+	// status returns a copy of the object's status, compared before and
+	// after reconcile to decide whether to update it.
+	status func(T) interface{}
+	// conditions returns the conditions of the object's status, which
+	// record a terminal failure of its spec.
+	conditions func(T) *[]metav1.Condition
+	// reconcile creates the NCP resource, which it looks up by name first in
+	// case an earlier reconcile created it but failed to record it, and
+	// follows it until it is ready.
+	reconcile func(context.Context, logr.Logger, T) (ctrl.Result, error)
+	// remove deletes the NCP resource, if any. A NotFound error means it is
+	// already gone.
+	remove func(context.Context, logr.Logger, T) error
+}
+
+// reconcileNCPResource reconciles the object named by req: it keeps the
+// finalizer until the NCP resource is removed, reconciles the resource and
+// updates the status when it changed. A spec NCP rejected terminally is not
+// reconciled again until it changes, while deleting is always retried, as
+// what blocks it, such as servers left in a placement group, may go away.
+func reconcileNCPResource[T client.Object](ctx context.Context, c client.Client, recorder record.EventRecorder,
+	req ctrl.Request, object T, resource ncpResource[T]) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	if err := c.Get(ctx, req.NamespacedName, object); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get "+resource.kind+" resource")
+		return ctrl.Result{}, err
+	}
+	if !object.GetDeletionTimestamp().IsZero() {
+		if !controllerutil.ContainsFinalizer(object, resource.finalizer) {
+			return ctrl.Result{}, nil
+		}
+		if err := resource.remove(ctx, log, object); err != nil && ncp.AsError(err).Kind != ncp.ErrorKindNotFound {
+			log.Error(err, "Failed to delete the NCP resource of "+resource.kind)
+			return resourceError(ctx, recorder, object, nil, err)
+		}
+		controllerutil.RemoveFinalizer(object, resource.finalizer)
+		if err := c.Update(ctx, object); err != nil {
+			log.Error(err, "Failed to remove finalizer")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+	if controllerutil.AddFinalizer(object, resource.finalizer) {
+		if err := c.Update(ctx, object); err != nil {
+			log.Error(err, "Failed to add finalizer")
+			return ctrl.Result{}, err
+		}
+	}
+
+	if failedFor(*resource.conditions(object), object.GetGeneration()) {
+		log.V(LogLevelDebug).Info("Not retrying a spec NCP rejected, waiting for it to change",
+			"generation", object.GetGeneration())
+		return ctrl.Result{}, nil
+	}
+
+	observed := resource.status(object)
+	result, err := resource.reconcile(ctx, log, object)
+	if err != nil {
+		log.Error(err, "Failed to reconcile "+resource.kind)
+		result, err = resourceError(ctx, recorder, object, resource.conditions(object), err)
+	} else {
+		clearFailedCondition(resource.conditions(object), object.GetGeneration())
+	}
+	if !equality.Semantic.DeepEqual(observed, resource.status(object)) {
+		if updateErr := c.Status().Update(ctx, object); updateErr != nil {
+			log.Error(updateErr, "Failed to update "+resource.kind+" status")
+			return ctrl.Result{}, updateErr
+		}
+	}
+	return result, err
+}
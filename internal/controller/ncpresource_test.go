@@ -0,0 +1,148 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net/http"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+var _ = Describe("Reconciling objects backed by NCP resources", func() {
+	var (
+		ctx     context.Context
+		key     types.NamespacedName
+		fakeAPI *fakeNCP
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		key = types.NamespacedName{Namespace: "default", Name: "spread"}
+		fakeAPI = newFakeNCP()
+	})
+
+	reconcileGroup := func(c client.Client) (ctrl.Result, error) {
+		r := NewPlacementGroupReconciler(c, c.Scheme(), record.NewFakeRecorder(100), fakeAPI.client)
+		return r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+	}
+
+	It("marks a ServerImage whose image is gone Failed", func() {
+		c := newFakeClient(&vmv1.ServerImage{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name, Finalizers: []string{serverImageFinalizer}},
+			Status: vmv1.ServerImageStatus{Phase: vmv1.ServerImagePhaseCreating, MemberServerImageInstanceNo: "31",
+				RegionCode: "KR"},
+		})
+		r := NewServerImageReconciler(c, c.Scheme(), record.NewFakeRecorder(100), fakeAPI.client)
+
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		image := &vmv1.ServerImage{}
+		Expect(c.Get(ctx, key, image)).To(Succeed())
+		Expect(image.Status.Phase).To(Equal(vmv1.ServerImagePhaseFailed))
+	})
+
+	It("marks a Data whose volume is gone Lost", func() {
+		c := newFakeClient(&vmv1.Data{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name, Finalizers: []string{dataFinalizer}},
+			Status: vmv1.DataStatus{Phase: vmv1.DataPhaseAvailable, BlockStorageInstanceNo: "41",
+				RegionCode: "KR"},
+		})
+		r := NewDataReconciler(c, c.Scheme(), record.NewFakeRecorder(100), fakeAPI.client)
+
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		data := &vmv1.Data{}
+		Expect(c.Get(ctx, key, data)).To(Succeed())
+		Expect(data.Status.Phase).To(Equal(vmv1.DataPhaseLost))
+	})
+
+	It("releases a deleted object whose NCP resource is already gone", func() {
+		now := metav1.Now()
+		c := newFakeClient(&vmv1.PlacementGroup{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name,
+				Finalizers: []string{placementGroupFinalizer}, DeletionTimestamp: &now},
+			Status: vmv1.PlacementGroupStatus{PlacementGroupNo: "51", RegionCode: "KR"},
+		})
+		fakeAPI.reject("deletePlacementGroup", http.StatusBadRequest, "The placement group does not exist.")
+
+		_, err := reconcileGroup(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apierrors.IsNotFound(c.Get(ctx, key, &vmv1.PlacementGroup{}))).To(BeTrue())
+	})
+
+	It("keeps retrying a delete NCP refuses", func() {
+		now := metav1.Now()
+		c := newFakeClient(&vmv1.PlacementGroup{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name,
+				Finalizers: []string{placementGroupFinalizer}, DeletionTimestamp: &now},
+			Status: vmv1.PlacementGroupStatus{PlacementGroupNo: "51", RegionCode: "KR"},
+		})
+		fakeAPI.reject("deletePlacementGroup", http.StatusBadRequest, "Invalid request, servers remain in the placement group.")
+
+		_, err := reconcileGroup(c)
+		Expect(err).To(HaveOccurred())
+		group := &vmv1.PlacementGroup{}
+		Expect(c.Get(ctx, key, group)).To(Succeed())
+		Expect(controllerutil.ContainsFinalizer(group, placementGroupFinalizer)).To(BeTrue())
+	})
+
+	It("records a terminal rejection in Failed and retries only a changed spec", func() {
+		c := newFakeClient(&vmv1.PlacementGroup{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name, Generation: 1},
+			Spec:       vmv1.PlacementGroupSpec{RegionCode: "KR"},
+		})
+		fakeAPI.reject("createPlacementGroup", http.StatusBadRequest, "Invalid placementGroupTypeCode.")
+
+		result, err := reconcileGroup(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(ctrl.Result{}))
+		group := &vmv1.PlacementGroup{}
+		Expect(c.Get(ctx, key, group)).To(Succeed())
+		failed := meta.FindStatusCondition(group.Status.Conditions, vmv1.ConditionTypeFailed)
+		Expect(failed).NotTo(BeNil())
+		Expect(failed.Status).To(Equal(metav1.ConditionTrue))
+		Expect(failed.Reason).To(Equal(string(ncp.ErrorKindInvalidArgument)))
+
+		_, err = reconcileGroup(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fakeAPI.called()).To(HaveLen(2), "getPlacementGroupList and createPlacementGroup, once")
+
+		group.Generation = 2
+		Expect(c.Update(ctx, group)).To(Succeed())
+		fakeAPI.answer("createPlacementGroup", "<createPlacementGroupResponse><placementGroupList><placementGroup>"+
+			"<placementGroupNo>52</placementGroupNo></placementGroup></placementGroupList></createPlacementGroupResponse>")
+		_, err = reconcileGroup(c)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.Get(ctx, key, group)).To(Succeed())
+		Expect(group.Status.PlacementGroupNo).To(Equal("52"))
+		Expect(meta.IsStatusConditionTrue(group.Status.Conditions, vmv1.ConditionTypeFailed)).To(BeFalse())
+	})
+})
@@ -58,5 +58,5 @@ func (r *OperatingsystemsReconciler) Reconcile(ctx context.Context, req ctrl.Req
 func (r *OperatingsystemsReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&vmv1.Operatingsystems{}).
-		Complete(r)
+		Complete(traced("Operatingsystems", r))
 }
@@ -0,0 +1,215 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/go-logr/logr"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	ncputil "github.com/cloud-club/Aviator-service/pkg"
+	server "github.com/cloud-club/Aviator-service/types/server"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+// OrphanCollector periodically looks for servers owned by the operator that no
+// Provision manages any more, e.g. after a force-deleted Provision or a crash
+// between creating a server and recording it. Ownership is read from the
+// server's tags, never from its name. Orphans are reported and, when Terminate
+// and ClusterID are set, stopped and returned once they stayed orphaned for
+// GracePeriod. Servers are tagged only once recorded by their Provision, so
+// untagged servers carrying the ownership name prefix are reported too, but
+// never terminated, as they cannot be told apart from those of other clusters.
+type OrphanCollector struct {
+	client.Client
+	ncpService *ncputil.NcpService
+	ncpClient  *ncp.Client
+	// serverCache is shared with the Provision reconciler
+	serverCache *ncp.ServerCache
+
+	Interval    time.Duration
+	GracePeriod time.Duration
+	Terminate   bool
+	// ClusterID, when set, restricts collection to servers tagged with it.
+	// Orphans are only terminated with a ClusterID, as without one servers
+	// of other clusters sharing the account cannot be told apart.
+	ClusterID string
+
+	// first time each orphan was seen, by server instance number
+	firstSeen map[string]time.Time
+}
+
+func NewOrphanCollector(
+	client client.Client,
+	ncpService *ncputil.NcpService,
+	ncpClient *ncp.Client,
+	serverCache *ncp.ServerCache,
+) *OrphanCollector {
+	return &OrphanCollector{
+		Client:      client,
+		ncpService:  ncpService,
+		ncpClient:   ncpClient,
+		serverCache: serverCache,
+		Interval:    defaultOrphanCollectInterval,
+		GracePeriod: defaultOrphanGracePeriod,
+		firstSeen:   map[string]time.Time{},
+	}
+}
+
+// Start runs the sweeper until ctx is cancelled. It implements manager.Runnable.
+func (c *OrphanCollector) Start(ctx context.Context) error {
+	log := ctrl.Log.WithName("orphan-collector")
+	wait.UntilWithContext(ctx, func(ctx context.Context) {
+		if err := c.collect(ctx, log); err != nil {
+			log.Error(err, "Failed to collect orphan servers")
+		}
+	}, c.Interval)
+	return nil
+}
+
+// NeedLeaderElection makes only the leader sweep, so replicas do not race on termination.
+func (c *OrphanCollector) NeedLeaderElection() bool {
+	return true
+}
+
+func (c *OrphanCollector) collect(ctx context.Context, log logr.Logger) error {
+	provisions := &vmv1.ProvisionList{}
+	if err := c.List(ctx, provisions); err != nil {
+		return err
+	}
+	managed := map[string]bool{}
+	regions := map[string]bool{"": true}
+	for i := range provisions.Items {
+		p := &provisions.Items[i]
+		regions[p.Spec.RegionCode] = true
+		if no := managedServerInstanceNo(p); no != "" {
+			managed[no] = true
+		}
+	}
+
+	now := time.Now()
+	orphans := map[string]bool{}
+	for regionCode := range regions {
+		servers, err := c.serverCache.List(ctx, regionCode)
+		if err != nil {
+			return err
+		}
+		var candidates []*ncp.ServerInstance
+		for i := range servers {
+			s := &servers[i]
+			if managed[s.ServerInstanceNo] || orphans[s.ServerInstanceNo] {
+				continue
+			}
+			candidates = append(candidates, s)
+		}
+		owned, untagged, err := c.ownedServers(ctx, regionCode, candidates)
+		if err != nil {
+			return err
+		}
+		for _, s := range untagged {
+			log.V(LogLevelInfo).Info("Found untagged server named like an operator owned one, not collecting it",
+				"serverInstanceNo", s.ServerInstanceNo, "serverName", s.ServerName, "regionCode", s.RegionCode)
+		}
+		for _, s := range owned {
+			orphans[s.ServerInstanceNo] = true
+			if _, ok := c.firstSeen[s.ServerInstanceNo]; !ok {
+				c.firstSeen[s.ServerInstanceNo] = now
+			}
+			c.handleOrphan(ctx, log, s, now.Sub(c.firstSeen[s.ServerInstanceNo]))
+		}
+	}
+
+	for no := range c.firstSeen {
+		if !orphans[no] {
+			delete(c.firstSeen, no)
+		}
+	}
+	return nil
+}
+
+func (c *OrphanCollector) handleOrphan(ctx context.Context, log logr.Logger, s *ncp.ServerInstance, orphanedFor time.Duration) {
+	log = log.WithValues("serverInstanceNo", s.ServerInstanceNo, "serverName", s.ServerName,
+		"regionCode", s.RegionCode, "status", s.ServerInstanceStatus.Code, "orphanedFor", orphanedFor.Round(time.Second))
+	if !c.Terminate || c.ClusterID == "" || orphanedFor < c.GracePeriod {
+		log.V(LogLevelInfo).Info("Found orphan server")
+		return
+	}
+	if s.IsProtectServerTermination {
+		log.V(LogLevelInfo).Info("Not terminating orphan server protected from termination")
+		return
+	}
+
+	var err error
+	switch s.ServerInstanceStatus.Code {
+	case ncp.ServerStatusRunning:
+		log.V(LogLevelInfo).Info("Stopping orphan server before termination")
+		_, err = ncp.WithContext(ctx, c.ncpService.Server).Stop(ncputil.API_URL+ncputil.STOP_SERVER_INSTANCE_PATH,
+			&server.StopServerRequest{ServerNo: s.ServerInstanceNo})
+	case ncp.ServerStatusStopped:
+		log.V(LogLevelInfo).Info("Terminating orphan server")
+		err = c.ncpClient.TerminateServerInstances(ctx, s.RegionCode, s.ServerInstanceNo)
+	}
+	if err != nil {
+		log.Error(err, "Failed to collect orphan server")
+	}
+	c.serverCache.Invalidate(s.RegionCode)
+}
+
+// ownedServers returns the servers whose tags mark them as created for a
+// Provision, by their UID tag, and, with a ClusterID, as owned by this
+// cluster, and the servers without tags whose name has the ownership prefix.
+func (c *OrphanCollector) ownedServers(ctx context.Context, regionCode string, servers []*ncp.ServerInstance) (owned, untagged []*ncp.ServerInstance, err error) {
+	if len(servers) == 0 {
+		return nil, nil, nil
+	}
+	instanceNos := make([]string, 0, len(servers))
+	for _, s := range servers {
+		instanceNos = append(instanceNos, s.ServerInstanceNo)
+	}
+	resp, err := c.ncpClient.GetInstanceTagList(ctx, regionCode, instanceNos...)
+	if err != nil {
+		return nil, nil, err
+	}
+	tags := map[string]map[string]string{}
+	for _, t := range resp.InstanceTagList {
+		if tags[t.InstanceNo] == nil {
+			tags[t.InstanceNo] = map[string]string{}
+		}
+		tags[t.InstanceNo][t.TagKey] = t.TagValue
+	}
+	for _, s := range servers {
+		t := tags[s.ServerInstanceNo]
+		if t[tagKeyUID] == "" {
+			if len(t) == 0 && strings.HasPrefix(s.ServerName, ownedServerNamePrefix) {
+				untagged = append(untagged, s)
+			}
+			continue
+		}
+		if c.ClusterID != "" && t[tagKeyCluster] != c.ClusterID {
+			continue
+		}
+		owned = append(owned, s)
+	}
+	return owned, untagged, nil
+}
@@ -0,0 +1,102 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ncputil "github.com/cloud-club/Aviator-service/pkg"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+var _ = Describe("OrphanCollector", func() {
+	var (
+		fakeAPI   *fakeNCP
+		servers   *fakeServerService
+		collector *OrphanCollector
+	)
+
+	BeforeEach(func() {
+		provision := &vmv1.Provision{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-1"},
+			Spec:       vmv1.ProvisionSpec{RegionCode: "KR"},
+			Status:     vmv1.ProvisionStatus{ServerInstanceNo: "111"},
+		}
+		c := newFakeClient(provision)
+		fakeAPI = newFakeNCP()
+		servers = &fakeServerService{}
+		collector = NewOrphanCollector(c, &ncputil.NcpService{Server: servers}, fakeAPI.client,
+			ncp.NewServerCache(fakeAPI.client, time.Minute))
+		collector.Terminate = true
+		collector.ClusterID = "cluster-1"
+		collector.GracePeriod = 0
+
+		var list string
+		for _, s := range [][2]string{
+			{"111", "aviator-default-web-1"},
+			{"222", "aviator-default-web-2"},
+			{"333", "aviator-default-web-3"},
+			{"444", "aviator-default-web-4"},
+		} {
+			list += fmt.Sprintf("<serverInstance><serverInstanceNo>%s</serverInstanceNo><serverName>%s</serverName>"+
+				"<serverInstanceStatus><code>%s</code></serverInstanceStatus></serverInstance>", s[0], s[1], ncp.ServerStatusRunning)
+		}
+		fakeAPI.answer("getServerInstanceList", "<getServerInstanceListResponse><returnCode>0</returnCode>"+
+			"<totalRows>4</totalRows><serverInstanceList>"+list+"</serverInstanceList></getServerInstanceListResponse>")
+		fakeAPI.answer("getInstanceTagList", "<getInstanceTagListResponse><returnCode>0</returnCode><instanceTagList>"+
+			instanceTag("111", tagKeyUID, "uid-1")+instanceTag("111", tagKeyCluster, "cluster-1")+
+			instanceTag("222", tagKeyUID, "uid-2")+instanceTag("222", tagKeyCluster, "cluster-1")+
+			instanceTag("444", tagKeyUID, "uid-4")+instanceTag("444", tagKeyCluster, "cluster-2")+
+			"</instanceTagList></getInstanceTagListResponse>")
+	})
+
+	It("collects only tagged servers of its cluster that no Provision manages", func() {
+		Expect(collector.collect(context.Background(), logr.Discard())).To(Succeed())
+		Expect(servers.stopped).To(Equal([]string{"222"}))
+		Expect(collector.firstSeen).To(HaveKey("222"))
+	})
+
+	It("only reports untagged servers named like operator owned ones", func() {
+		candidates := []*ncp.ServerInstance{
+			{ServerInstanceNo: "333", ServerName: "aviator-default-web-3"},
+			{ServerInstanceNo: "555", ServerName: "other"},
+		}
+		owned, untagged, err := collector.ownedServers(context.Background(), "KR", candidates)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(owned).To(BeEmpty())
+		Expect(untagged).To(ConsistOf(candidates[0]))
+
+		Expect(collector.collect(context.Background(), logr.Discard())).To(Succeed())
+		Expect(servers.stopped).NotTo(ContainElement("333"))
+		Expect(collector.firstSeen).NotTo(HaveKey("333"))
+	})
+})
+
+func instanceTag(instanceNo, key, value string) string {
+	return fmt.Sprintf("<instanceTag><instanceNo>%s</instanceNo><tagKey>%s</tagKey><tagValue>%s</tagValue></instanceTag>",
+		instanceNo, key, value)
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+// placementGroupFinalizer keeps a PlacementGroup until its NCP group is deleted.
+const placementGroupFinalizer = "vm.cloudclub.io/placement-group"
+
+// placementGroupNamePrefix starts the NCP names derived for PlacementGroups.
+const placementGroupNamePrefix = "aviator-pg-"
+
+// PlacementGroupReconciler creates NCP placement groups and deletes them with
+// their PlacementGroup.
+type PlacementGroupReconciler struct {
+	client.Client
+	Scheme    *runtime.Scheme
+	Recorder  record.EventRecorder
+	ncpClient *ncp.Client
+}
+
+func NewPlacementGroupReconciler(client client.Client, scheme *runtime.Scheme, recorder record.EventRecorder, ncpClient *ncp.Client) *PlacementGroupReconciler {
+	return &PlacementGroupReconciler{
+		Client:    client,
+		Scheme:    scheme,
+		Recorder:  recorder,
+		ncpClient: ncpClient,
+	}
+}
+
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=placementgroups,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=placementgroups/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=placementgroups/finalizers,verbs=update
+
+// Reconcile creates the NCP placement group of a PlacementGroup.
+func (r *PlacementGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return reconcileNCPResource(ctx, r.Client, r.Recorder, req, &vmv1.PlacementGroup{}, ncpResource[*vmv1.PlacementGroup]{
+		kind:       "PlacementGroup",
+		finalizer:  placementGroupFinalizer,
+		status:     func(group *vmv1.PlacementGroup) interface{} { return group.Status.DeepCopy() },
+		conditions: func(group *vmv1.PlacementGroup) *[]metav1.Condition { return &group.Status.Conditions },
+		reconcile:  r.reconcileGroup,
+		remove:     r.remove,
+	})
+}
+
+func (r *PlacementGroupReconciler) reconcileGroup(ctx context.Context, log logr.Logger, group *vmv1.PlacementGroup) (ctrl.Result, error) {
+	if group.Status.PlacementGroupNo != "" {
+		return ctrl.Result{}, nil
+	}
+	return r.create(ctx, log, group)
+}
+
+func (r *PlacementGroupReconciler) create(ctx context.Context, log logr.Logger, group *vmv1.PlacementGroup) (ctrl.Result, error) {
+	name := group.Spec.PlacementGroupName
+	if name == "" {
+		name = ncpName(placementGroupNamePrefix, group.Namespace, group.Name)
+	}
+	typeCode := group.Spec.PlacementGroupType
+	if typeCode == "" {
+		typeCode = vmv1.PlacementGroupTypeAntiAffinity
+	}
+	// A group under the derived name was created by an earlier attempt whose
+	// status update was lost and is reused. A group named in the spec may be
+	// shared or made by hand, and would be deleted with the PlacementGroup.
+	pg, err := r.ncpClient.FindPlacementGroup(ctx, group.Spec.RegionCode, name)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if pg != nil && group.Spec.PlacementGroupName != "" {
+		return ctrl.Result{}, &ncp.Error{Kind: ncp.ErrorKindInvalidArgument,
+			Message: fmt.Sprintf("placement group %s already exists as %s", name, pg.PlacementGroupNo)}
+	}
+	if pg != nil && pg.PlacementGroupType.Code != string(typeCode) {
+		return ctrl.Result{}, &ncp.Error{Kind: ncp.ErrorKindInvalidArgument,
+			Message: fmt.Sprintf("placement group name %s is used by group %s of type %s", name,
+				pg.PlacementGroupNo, pg.PlacementGroupType.Code)}
+	}
+	if pg == nil {
+		pg, err = r.ncpClient.CreatePlacementGroup(ctx, group.Spec.RegionCode, name, string(typeCode))
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+		recordEvent(ctx, r.Recorder, group, corev1.EventTypeNormal, eventReasonPlacementGroupCreated,
+			"Created placement group %s (%s)", name, pg.PlacementGroupNo)
+	}
+	group.Status.PlacementGroupNo = pg.PlacementGroupNo
+	group.Status.PlacementGroupName = name
+	group.Status.RegionCode = group.Spec.RegionCode
+	log.V(LogLevelInfo).Info("Placement group ready", "placementGroupNo", pg.PlacementGroupNo, "name", name)
+	return ctrl.Result{}, nil
+}
+
+// remove deletes the NCP placement group, if any. NCP refuses while servers
+// remain in the group, which is retried.
+func (r *PlacementGroupReconciler) remove(ctx context.Context, log logr.Logger, group *vmv1.PlacementGroup) error {
+	no := group.Status.PlacementGroupNo
+	if no == "" {
+		return nil
+	}
+	if err := r.ncpClient.DeletePlacementGroup(ctx, group.Status.RegionCode, no); err != nil {
+		return err
+	}
+	log.V(LogLevelInfo).Info("Placement group deleted", "placementGroupNo", no)
+	recordEvent(ctx, r.Recorder, group, corev1.EventTypeNormal, eventReasonPlacementGroupDeleted, "Deleted placement group %s", no)
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *PlacementGroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&vmv1.PlacementGroup{}).
+		Complete(traced("PlacementGroup", r))
+}
+
+// placementGroup returns the placement group a Provision's server is created
+// in: that of its PlacementGroupRef once created, or placementGroupNo.
+func placementGroup(ctx context.Context, r *ProvisionReconciler, original *vmv1.Provision) (string, error) {
+	if original.Spec.PlacementGroupRef == "" {
+		return original.Spec.PlacementGroupNo, nil
+	}
+	group := &vmv1.PlacementGroup{}
+	key := types.NamespacedName{Namespace: original.Namespace, Name: original.Spec.PlacementGroupRef}
+	if err := r.Get(ctx, key, group); err != nil {
+		if errors.IsNotFound(err) {
+			return "", &dependencyNotReadyError{kind: "PlacementGroup", name: key.Name, reason: "not found"}
+		}
+		return "", err
+	}
+	if group.Status.PlacementGroupNo == "" {
+		return "", &dependencyNotReadyError{kind: "PlacementGroup", name: key.Name, reason: "not created yet"}
+	}
+	return group.Status.PlacementGroupNo, nil
+}
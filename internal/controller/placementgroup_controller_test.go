@@ -0,0 +1,85 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+var _ = Describe("Creating a placement group", func() {
+	var (
+		ctx     context.Context
+		key     types.NamespacedName
+		fakeAPI *fakeNCP
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		key = types.NamespacedName{Namespace: "default", Name: "spread"}
+		fakeAPI = newFakeNCP()
+	})
+
+	existing := func(name string) {
+		fakeAPI.answer("getPlacementGroupList", fmt.Sprintf("<getPlacementGroupListResponse><totalRows>1</totalRows>"+
+			"<placementGroupList><placementGroup><placementGroupNo>51</placementGroupNo><placementGroupName>%s</placementGroupName>"+
+			"<placementGroupType><code>%s</code></placementGroupType></placementGroup></placementGroupList>"+
+			"</getPlacementGroupListResponse>", name, vmv1.PlacementGroupTypeAntiAffinity))
+	}
+
+	reconcile := func(spec vmv1.PlacementGroupSpec) (client.Client, *vmv1.PlacementGroup) {
+		c := newFakeClient(&vmv1.PlacementGroup{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+			Spec:       spec,
+		})
+		r := NewPlacementGroupReconciler(c, c.Scheme(), record.NewFakeRecorder(100), fakeAPI.client)
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		group := &vmv1.PlacementGroup{}
+		Expect(c.Get(ctx, key, group)).To(Succeed())
+		return c, group
+	}
+
+	It("reuses the group an earlier attempt created under the derived name", func() {
+		existing(ncpName(placementGroupNamePrefix, key.Namespace, key.Name))
+
+		_, group := reconcile(vmv1.PlacementGroupSpec{RegionCode: "KR"})
+		Expect(group.Status.PlacementGroupNo).To(Equal("51"))
+		Expect(fakeAPI.called()).NotTo(ContainElement("createPlacementGroup"))
+	})
+
+	It("does not take over a group named in the spec", func() {
+		existing("shared")
+
+		_, group := reconcile(vmv1.PlacementGroupSpec{RegionCode: "KR", PlacementGroupName: "shared"})
+		Expect(group.Status.PlacementGroupNo).To(BeEmpty())
+		Expect(meta.IsStatusConditionTrue(group.Status.Conditions, vmv1.ConditionTypeFailed)).To(BeTrue())
+		Expect(fakeAPI.called()).NotTo(ContainElement("createPlacementGroup"))
+	})
+})
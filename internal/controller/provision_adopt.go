@@ -0,0 +1,157 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+// adopt takes ownership of the server referenced by spec.adopt. Unset spec
+// fields are filled from the live server and its instance number is recorded
+// in status, after which the Provision is reconciled like one it created.
+func adopt(ctx context.Context, r *ProvisionReconciler, log logr.Logger, url string, original *vmv1.Provision, payload interface{}) error {
+	log.V(LogLevelInfo).Info("Adopting an existing VM",
+		"serverInstanceNo", original.Spec.Adopt.ServerInstanceNo, "serverName", original.Spec.Adopt.ServerName)
+
+	instances, err := adoptionCandidates(ctx, r, original)
+	if err != nil {
+		return err
+	}
+	instance, err := findAdoptedServer(instances, original.Spec.Adopt)
+	if err != nil {
+		return err
+	}
+
+	owner, err := r.serverOwner(ctx, instance.ServerInstanceNo)
+	if err != nil {
+		return err
+	}
+	if owner != nil && owner.UID != original.UID {
+		return fmt.Errorf("server %s is already owned by Provision %s/%s",
+			instance.ServerInstanceNo, owner.Namespace, owner.Name)
+	}
+
+	tagList, err := r.ncpClient.GetInstanceTagList(ctx, original.Spec.RegionCode, instance.ServerInstanceNo)
+	if err != nil {
+		return err
+	}
+	tags := make(map[string]string, len(tagList.InstanceTagList))
+	for _, t := range tagList.InstanceTagList {
+		tags[t.TagKey] = t.TagValue
+	}
+	if foreignServer(r.ClusterID, tags) {
+		return fmt.Errorf("server %s is owned by cluster %q", instance.ServerInstanceNo, tags[tagKeyCluster])
+	}
+
+	fillSpecFromServer(&original.Spec, instance)
+	if err := updateKeepingStatus(ctx, r, original); err != nil {
+		return err
+	}
+	original.Status.ServerInstanceNo = instance.ServerInstanceNo
+
+	log.V(LogLevelInfo).Info("Adopted VM", "serverInstanceNo", instance.ServerInstanceNo, "serverName", instance.ServerName)
+	r.event(ctx, original, corev1.EventTypeNormal, eventReasonAdopted,
+		"Adopted server %s (%s)", instance.ServerName, instance.ServerInstanceNo)
+	return nil
+}
+
+// adoptionCandidates looks the server of spec.adopt up by number or, without
+// one, by name, so that NCP does the filtering rather than a listing of
+// every server of the region.
+func adoptionCandidates(ctx context.Context, r *ProvisionReconciler, original *vmv1.Provision) ([]ncp.ServerInstance, error) {
+	source := original.Spec.Adopt
+	if source.ServerInstanceNo == "" {
+		return r.ncpClient.GetServerInstancesByName(ctx, original.Spec.RegionCode, source.ServerName)
+	}
+	instance, err := r.ncpClient.GetServerInstanceDetail(ctx, original.Spec.RegionCode, source.ServerInstanceNo)
+	if err != nil {
+		if ncp.AsError(err).Kind == ncp.ErrorKindNotFound {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return []ncp.ServerInstance{*instance}, nil
+}
+
+func findAdoptedServer(instances []ncp.ServerInstance, source *vmv1.AdoptSource) (*ncp.ServerInstance, error) {
+	var found *ncp.ServerInstance
+	for i := range instances {
+		s := &instances[i]
+		if source.ServerInstanceNo != "" && s.ServerInstanceNo != source.ServerInstanceNo {
+			continue
+		}
+		if source.ServerName != "" && s.ServerName != source.ServerName {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("more than one server matches serverName %q", source.ServerName)
+		}
+		found = s
+	}
+	if found == nil {
+		return nil, fmt.Errorf("no server matches serverInstanceNo %q serverName %q",
+			source.ServerInstanceNo, source.ServerName)
+	}
+	return found, nil
+}
+
+// serverOwner returns the Provision, in any namespace, that already manages serverInstanceNo.
+func (r *ProvisionReconciler) serverOwner(ctx context.Context, serverInstanceNo string) (*vmv1.Provision, error) {
+	provisions := &vmv1.ProvisionList{}
+	if err := r.List(ctx, provisions); err != nil {
+		return nil, err
+	}
+	for i := range provisions.Items {
+		if provisions.Items[i].Status.ServerInstanceNo == serverInstanceNo {
+			return &provisions.Items[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// fillSpecFromServer copies the live server's attributes into fields the user
+// left empty. The subnet is left to the placement, if any.
+func fillSpecFromServer(spec *vmv1.ProvisionSpec, instance *ncp.ServerInstance) {
+	setIfEmpty := func(field *string, value string) {
+		if *field == "" {
+			*field = value
+		}
+	}
+	setIfEmpty(&spec.ServerInstanceNo, instance.ServerInstanceNo)
+	setIfEmpty(&spec.ServerNo, instance.ServerInstanceNo)
+	setIfEmpty(&spec.RegionCode, instance.RegionCode)
+	setIfEmpty(&spec.VpcNo, instance.VpcNo)
+	if spec.Placement == nil {
+		setIfEmpty(&spec.SubnetNo, instance.SubnetNo)
+	}
+	setIfEmpty(&spec.LoginKeyName, instance.LoginKeyName)
+	setIfEmpty(&spec.Server.Name, instance.ServerName)
+	setIfEmpty(&spec.Server.ProductCode, instance.ServerProductCode)
+	setIfEmpty(&spec.Server.ImageProductCode, instance.ServerImageProductCode)
+	setIfEmpty(&spec.Server.ImageNo, instance.ServerImageNo)
+	setIfEmpty(&spec.Server.SpecCode, instance.ServerSpecCode)
+	if instance.IsProtectServerTermination {
+		spec.IsProtectServerTermination = true
+	}
+}
@@ -0,0 +1,90 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+var _ = Describe("Adopting a server", func() {
+	const legacyServer = "<getServerInstanceListResponse><returnCode>0</returnCode><totalRows>1</totalRows>" +
+		"<serverInstanceList><serverInstance><serverInstanceNo>111</serverInstanceNo><serverName>legacy</serverName>" +
+		"<vpcNo>2</vpcNo><subnetNo>31</subnetNo><loginKeyName>ops</loginKeyName>" +
+		"</serverInstance></serverInstanceList></getServerInstanceListResponse>"
+
+	var (
+		ctx       context.Context
+		fakeAPI   *fakeNCP
+		provision *vmv1.Provision
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeAPI = newFakeNCP()
+		provision = &vmv1.Provision{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "legacy"},
+			Spec:       vmv1.ProvisionSpec{RegionCode: "KR", Adopt: &vmv1.AdoptSource{ServerName: "legacy"}},
+		}
+	})
+
+	adopted := func() error {
+		c := newFakeClient(provision)
+		r := newTestProvisionReconciler(c, fakeAPI, &fakeServerService{})
+		return adopt(ctx, r, logr.Discard(), "", provision, nil)
+	}
+
+	It("looks a server up by name and fills the spec from it", func() {
+		fakeAPI.answer("getServerInstanceList", legacyServer)
+
+		Expect(adopted()).To(Succeed())
+		Expect(provision.Status.ServerInstanceNo).To(Equal("111"))
+		Expect(provision.Spec.VpcNo).To(Equal("2"))
+		Expect(provision.Spec.SubnetNo).To(Equal("31"))
+		Expect(provision.Spec.LoginKeyName).To(Equal("ops"))
+	})
+
+	It("looks a server up by number", func() {
+		provision.Spec.Adopt = &vmv1.AdoptSource{ServerInstanceNo: "111"}
+		fakeAPI.answer("getServerInstanceDetail", legacyServer)
+
+		Expect(adopted()).To(Succeed())
+		Expect(provision.Status.ServerInstanceNo).To(Equal("111"))
+		Expect(fakeAPI.called()).NotTo(ContainElement("getServerInstanceList"))
+	})
+
+	It("fails when no server has the number", func() {
+		provision.Spec.Adopt = &vmv1.AdoptSource{ServerInstanceNo: "111"}
+
+		Expect(adopted()).To(MatchError(ContainSubstring("no server matches")))
+	})
+
+	It("leaves the subnet to the placement", func() {
+		provision.Spec.Placement = &vmv1.ZonePlacement{SubnetNos: []string{"31", "32"}}
+		fakeAPI.answer("getServerInstanceList", legacyServer)
+
+		Expect(adopted()).To(Succeed())
+		Expect(provision.Spec.SubnetNo).To(BeEmpty())
+	})
+})
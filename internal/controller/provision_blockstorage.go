@@ -0,0 +1,253 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+// blockStorageMappingList converts the spec mappings into createServerInstances
+// parameters, ordered by their disk order.
+func blockStorageMappingList(mappings []vmv1.BlockStorageMapping) []ncp.BlockStorageMapping {
+	if len(mappings) == 0 {
+		return nil
+	}
+	list := make([]ncp.BlockStorageMapping, 0, len(mappings))
+	for _, m := range sortedMappings(mappings) {
+		list = append(list, ncp.BlockStorageMapping{
+			Order:                      m.Order,
+			SnapshotInstanceNo:         m.SnapshotInstanceNo,
+			BlockStorageSize:           m.BlockStorageSize,
+			BlockStorageName:           m.BlockStorageName,
+			BlockStorageVolumeTypeCode: string(m.BlockStorageVolumeTypeCode),
+			Encrypted:                  m.Encrypted,
+		})
+	}
+	return list
+}
+
+// restoredMappings returns the spec mappings with the snapshot of each
+// snapshotRef filled in.
+func restoredMappings(ctx context.Context, c client.Reader, original *vmv1.Provision) ([]vmv1.BlockStorageMapping, error) {
+	mappings := append([]vmv1.BlockStorageMapping(nil), original.Spec.BlockStorageMappings...)
+	for i, m := range mappings {
+		if m.SnapshotRef == "" {
+			continue
+		}
+		snapshot, err := availableSnapshot(ctx, c, original.Namespace, m.SnapshotRef)
+		if err != nil {
+			return nil, err
+		}
+		mappings[i].SnapshotInstanceNo = snapshot.Status.SnapshotInstanceNo
+	}
+	return mappings, nil
+}
+
+func sortedMappings(mappings []vmv1.BlockStorageMapping) []vmv1.BlockStorageMapping {
+	sorted := append([]vmv1.BlockStorageMapping(nil), mappings...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Order < sorted[j].Order })
+	return sorted
+}
+
+// blockStoragesPending reports whether a created server still has mapped
+// volumes that have not shown up in its status yet.
+func blockStoragesPending(original *vmv1.Provision) bool {
+	return original.Status.ServerInstanceNo != "" &&
+		len(original.Status.BlockStorages) < len(original.Spec.BlockStorageMappings)
+}
+
+// refreshBlockStorages records the instance number and device name of each
+// volume NCP attached to the server for the spec's block storage mappings.
+func refreshBlockStorages(ctx context.Context, r *ProvisionReconciler, log logr.Logger, original *vmv1.Provision) (err error) {
+	if len(original.Spec.BlockStorageMappings) == 0 {
+		return nil
+	}
+	// each call is one iteration of polling until the volumes are reported
+	ctx, span := tracer.Start(ctx, "Provision.refreshBlockStorages")
+	defer func() { endSpan(span, err) }()
+	resp, err := r.ncpClient.GetBlockStorageInstanceList(ctx, original.Spec.RegionCode, original.Status.ServerInstanceNo)
+	if err != nil {
+		return err
+	}
+	dataVolumes, err := dataBlockStorages(ctx, r.Client, original)
+	if err != nil {
+		return err
+	}
+	previous := make(map[string]int, len(original.Status.BlockStorages))
+	for _, b := range original.Status.BlockStorages {
+		previous[b.BlockStorageInstanceNo] = b.BlockStorageSize
+	}
+	original.Status.BlockStorages = matchBlockStorages(original.Spec.BlockStorageMappings, resp.BlockStorageInstanceList, dataVolumes)
+	log.V(LogLevelDebug).Info("Refreshed block storage status",
+		"serverInstanceNo", original.Status.ServerInstanceNo,
+		"mapped", len(original.Status.BlockStorages), "requested", len(original.Spec.BlockStorageMappings))
+	for _, b := range original.Status.BlockStorages {
+		if size, ok := previous[b.BlockStorageInstanceNo]; ok && b.BlockStorageSize > size {
+			r.event(ctx, original, corev1.EventTypeNormal, eventReasonVolumeExpanded,
+				"Volume %s expanded from %d to %d GiB", b.BlockStorageInstanceNo, size, b.BlockStorageSize)
+		}
+	}
+	return expandBlockStorages(ctx, r, log, original, resp.BlockStorageInstanceList)
+}
+
+// dataBlockStorages returns the numbers of the volumes Data objects attached
+// to the server of a Provision, which are not among its mappings.
+func dataBlockStorages(ctx context.Context, c client.Reader, original *vmv1.Provision) (map[string]bool, error) {
+	list := &vmv1.DataList{}
+	if err := c.List(ctx, list, client.InNamespace(original.Namespace)); err != nil {
+		return nil, err
+	}
+	volumes := map[string]bool{}
+	for _, data := range list.Items {
+		if data.Status.BlockStorageInstanceNo != "" && data.Status.ServerInstanceNo == original.Status.ServerInstanceNo {
+			volumes[data.Status.BlockStorageInstanceNo] = true
+		}
+	}
+	return volumes, nil
+}
+
+// expandBlockStorages grows the additional volumes whose mapping asks for
+// more than their size. Volumes still changing are left until they settle.
+func expandBlockStorages(ctx context.Context, r *ProvisionReconciler, log logr.Logger, original *vmv1.Provision, instances []ncp.BlockStorageInstance) error {
+	byNo := make(map[string]*ncp.BlockStorageInstance, len(instances))
+	for i := range instances {
+		byNo[instances[i].BlockStorageInstanceNo] = &instances[i]
+	}
+	for _, b := range original.Status.BlockStorages {
+		want := expansionSize(original, b)
+		instance := byNo[b.BlockStorageInstanceNo]
+		if want == 0 || instance == nil {
+			continue
+		}
+		if !instance.Attached() {
+			log.V(LogLevelDebug).Info("Waiting for volume to settle before expanding it",
+				"blockStorageInstanceNo", b.BlockStorageInstanceNo, "status", instance.BlockStorageInstanceStatus.Code)
+			continue
+		}
+		if err := r.ncpClient.ChangeBlockStorageVolumeSize(ctx, original.Spec.RegionCode, b.BlockStorageInstanceNo, want); err != nil {
+			return err
+		}
+		log.V(LogLevelInfo).Info("Volume expansion requested", "blockStorageInstanceNo", b.BlockStorageInstanceNo,
+			"from", b.BlockStorageSize, "to", want)
+		r.event(ctx, original, corev1.EventTypeNormal, eventReasonExpansionRequested,
+			"Requested expansion of volume %s from %d to %d GiB", b.BlockStorageInstanceNo, b.BlockStorageSize, want)
+	}
+	return nil
+}
+
+// expansionSize returns the size an additional volume should grow to, or 0
+// when its mapping asks for no more than it has. Boot volumes are not grown.
+func expansionSize(original *vmv1.Provision, b vmv1.BlockStorageStatus) int {
+	if b.Order == 0 {
+		return 0
+	}
+	for _, m := range original.Spec.BlockStorageMappings {
+		if m.Order == b.Order && m.BlockStorageSize > b.BlockStorageSize {
+			return m.BlockStorageSize
+		}
+	}
+	return 0
+}
+
+// blockStoragesExpanding reports whether a volume is smaller than its mapping asks.
+func blockStoragesExpanding(original *vmv1.Provision) bool {
+	for _, b := range original.Status.BlockStorages {
+		if expansionSize(original, b) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// matchBlockStorages pairs mappings with attached volumes. Order 0 is the boot
+// volume; additional volumes are matched by name when one was requested and
+// otherwise in device name order. Volumes of Data objects, given by number in
+// dataVolumes or, before their number is recorded, by name, are never matched.
+func matchBlockStorages(mappings []vmv1.BlockStorageMapping, instances []ncp.BlockStorageInstance, dataVolumes map[string]bool) []vmv1.BlockStorageStatus {
+	var basic *ncp.BlockStorageInstance
+	additional := make([]*ncp.BlockStorageInstance, 0, len(instances))
+	for i := range instances {
+		switch instances[i].BlockStorageType.Code {
+		case ncp.BlockStorageTypeBasic:
+			basic = &instances[i]
+		case ncp.BlockStorageTypeServer:
+			if dataVolumes[instances[i].BlockStorageInstanceNo] ||
+				strings.HasPrefix(instances[i].BlockStorageName, dataNamePrefix) {
+				continue
+			}
+			additional = append(additional, &instances[i])
+		}
+	}
+	// xvdz comes before xvdaa
+	sort.Slice(additional, func(i, j int) bool {
+		a, b := additional[i].DeviceName, additional[j].DeviceName
+		if len(a) != len(b) {
+			return len(a) < len(b)
+		}
+		return a < b
+	})
+
+	sorted := sortedMappings(mappings)
+	assigned := make([]*ncp.BlockStorageInstance, len(sorted))
+	used := make(map[string]bool, len(additional))
+	claim := func(i int, match func(*ncp.BlockStorageInstance) bool) {
+		for _, b := range additional {
+			if !used[b.BlockStorageInstanceNo] && match(b) {
+				assigned[i], used[b.BlockStorageInstanceNo] = b, true
+				return
+			}
+		}
+	}
+	// Named volumes first, so an unnamed mapping cannot take a named one.
+	for i, m := range sorted {
+		if m.Order > 0 && m.BlockStorageName != "" {
+			claim(i, func(b *ncp.BlockStorageInstance) bool { return b.BlockStorageName == m.BlockStorageName })
+		}
+	}
+	for i, m := range sorted {
+		switch {
+		case m.Order == 0:
+			assigned[i] = basic
+		case assigned[i] == nil:
+			claim(i, func(*ncp.BlockStorageInstance) bool { return true })
+		}
+	}
+
+	var statuses []vmv1.BlockStorageStatus
+	for i, b := range assigned {
+		if b == nil {
+			continue
+		}
+		statuses = append(statuses, vmv1.BlockStorageStatus{
+			Order:                  sorted[i].Order,
+			BlockStorageInstanceNo: b.BlockStorageInstanceNo,
+			BlockStorageName:       b.BlockStorageName,
+			DeviceName:             b.DeviceName,
+			BlockStorageSize:       b.SizeGiB(),
+		})
+	}
+	return statuses
+}
@@ -0,0 +1,61 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+var _ = Describe("Matching block storage mappings with attached volumes", func() {
+	volume := func(no, name, typeCode, deviceName string) ncp.BlockStorageInstance {
+		return ncp.BlockStorageInstance{BlockStorageInstanceNo: no, BlockStorageName: name,
+			BlockStorageType: ncp.CommonCode{Code: typeCode}, DeviceName: deviceName}
+	}
+	matched := func(statuses []vmv1.BlockStorageStatus) map[int]string {
+		byOrder := map[int]string{}
+		for _, s := range statuses {
+			byOrder[s.Order] = s.BlockStorageInstanceNo
+		}
+		return byOrder
+	}
+
+	It("matches named mappings by name and the others in device name order", func() {
+		mappings := []vmv1.BlockStorageMapping{{Order: 0}, {Order: 1}, {Order: 2, BlockStorageName: "logs"}, {Order: 3}}
+		instances := []ncp.BlockStorageInstance{
+			volume("10", "boot", ncp.BlockStorageTypeBasic, "/dev/xvda"),
+			volume("13", "logs", ncp.BlockStorageTypeServer, "/dev/xvdb"),
+			volume("12", "b", ncp.BlockStorageTypeServer, "/dev/xvdaa"),
+			volume("11", "a", ncp.BlockStorageTypeServer, "/dev/xvdz"),
+		}
+		Expect(matched(matchBlockStorages(mappings, instances, nil))).To(Equal(map[int]string{0: "10", 1: "11", 2: "13", 3: "12"}))
+	})
+
+	It("leaves the volumes of Data objects out", func() {
+		mappings := []vmv1.BlockStorageMapping{{Order: 1}, {Order: 2}}
+		instances := []ncp.BlockStorageInstance{
+			volume("11", "restored", ncp.BlockStorageTypeServer, "/dev/xvdb"),
+			volume("12", dataNamePrefix+"default-scratch", ncp.BlockStorageTypeServer, "/dev/xvdc"),
+			volume("13", "a", ncp.BlockStorageTypeServer, "/dev/xvdd"),
+		}
+		Expect(matched(matchBlockStorages(mappings, instances, map[string]bool{"11": true}))).
+			To(Equal(map[int]string{1: "13"}))
+	})
+})
@@ -19,10 +19,16 @@ package controller
 import (
 	"context"
 	"fmt"
+	"time"
 
 	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	"k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
@@ -31,42 +37,69 @@ import (
 	server "github.com/cloud-club/Aviator-service/types/server"
 
 	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+	"vm.cloudclub.io/internal/policy"
+	"vm.cloudclub.io/internal/pricing"
+	"vm.cloudclub.io/internal/quota"
 )
 
-var provisionReconcileMap map[string]func(*ProvisionReconciler, logr.Logger, string, *vmv1.Provision, interface{}) error
+var provisionReconcileMap map[string]func(context.Context, *ProvisionReconciler, logr.Logger, string, *vmv1.Provision, interface{}) error
 
 // ProvisionReconciler reconciles a Provision object
 type ProvisionReconciler struct {
 	client.Client
 	Scheme     *runtime.Scheme
 	ncpService *ncputil.NcpService
+	ncpClient  *ncp.Client
+	// serverCache batches the server reads of all Provisions in a region
+	serverCache *ncp.ServerCache
+	Recorder    record.EventRecorder
+
+	// ResyncPeriod is how often a provisioned server is compared with its spec.
+	ResyncPeriod time.Duration
+	// ClusterID identifies this cluster in the tags of the servers it owns.
+	ClusterID string
+	// Pricing prices servers for the cost estimate in status, which is not
+	// estimated when nil.
+	Pricing pricing.Catalog
 }
 
 func NewProvisionReconciler(
 	client client.Client,
 	scheme *runtime.Scheme,
+	recorder record.EventRecorder,
 	ncpService *ncputil.NcpService,
+	ncpClient *ncp.Client,
+	serverCache *ncp.ServerCache,
 ) *ProvisionReconciler {
 	initProvisionReconcileMap()
 	return &ProvisionReconciler{
-		Client:     client,
-		Scheme:     scheme,
-		ncpService: ncpService,
+		Client:       client,
+		Scheme:       scheme,
+		Recorder:     recorder,
+		ncpService:   ncpService,
+		ncpClient:    ncpClient,
+		serverCache:  serverCache,
+		ResyncPeriod: defaultResyncPeriod,
 	}
 }
 
 func initProvisionReconcileMap() {
-	provisionReconcileMap = make(map[string]func(*ProvisionReconciler, logr.Logger, string, *vmv1.Provision, interface{}) error)
+	provisionReconcileMap = make(map[string]func(context.Context, *ProvisionReconciler, logr.Logger, string, *vmv1.Provision, interface{}) error)
 	provisionReconcileMap["provision"] = provision
 	provisionReconcileMap["deProvision"] = deProvision
 	provisionReconcileMap["update"] = update
 	provisionReconcileMap["get"] = get
 	provisionReconcileMap["stop"] = stop
+	provisionReconcileMap["adopt"] = adopt
 }
 
 //+kubebuilder:rbac:groups=vm.cloudclub.io,resources=provisions,verbs=get;list;watch;create;update;patch;delete
 //+kubebuilder:rbac:groups=vm.cloudclub.io,resources=provisions/status,verbs=get;update;patch
 //+kubebuilder:rbac:groups=vm.cloudclub.io,resources=provisions/finalizers,verbs=update
+//+kubebuilder:rbac:groups="",resources=events,verbs=create;patch
+//+kubebuilder:rbac:groups="",resources=namespaces,verbs=get;list;watch
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=provisionpolicies,verbs=get;list;watch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -79,117 +112,289 @@ func initProvisionReconcileMap() {
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.16.3/pkg/reconcile
 func (r *ProvisionReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.FromContext(ctx)
-	log.V(ErrorLevelIsInfo).Info("Reconciling Provision request", "Request", req)
+	log.V(LogLevelDebug).Info("Reconciling Provision")
 
 	original := &vmv1.Provision{}
 	err := r.Get(ctx, req.NamespacedName, original)
 	if err != nil {
 		if errors.IsNotFound(err) {
-			log.V(ErrorLevelIsInfo).Info("Provision resource not found. Ignoring reconciliation.")
+			log.V(LogLevelInfo).Info("Provision resource not found. Ignoring reconciliation.")
 			return ctrl.Result{}, nil
 		}
 		log.Error(err, "Failed to get Provision resource")
 		return ctrl.Result{}, err
 	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.String("provision.phase", string(original.Spec.Phase)))
+	if !original.DeletionTimestamp.IsZero() {
+		return r.finalize(ctx, log, original)
+	}
+	observed := original.Status.DeepCopy()
+	if r.checkExpiry(ctx, log, original) {
+		return r.expire(ctx, log, original, observed)
+	}
+	if failedForGeneration(original) {
+		log.V(LogLevelDebug).Info("Not retrying a spec NCP rejected, waiting for it to change",
+			"generation", original.Generation)
+		if !equality.Semantic.DeepEqual(observed, &original.Status) {
+			if err = r.Status().Update(ctx, original); err != nil {
+				log.Error(err, "Failed to update Provision status")
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{RequeueAfter: untilExpiryCheck(original)}, nil
+	}
+
+	if original.Spec.Adopt != nil && original.Status.ServerInstanceNo == "" {
+		if v, ok := provisionReconcileMap["adopt"]; ok {
+			if err = v(ctx, r, log, apiUrlAdopt, original, nil); err != nil {
+				return r.reconcileError(ctx, log, original, "Failed to adopt VM", err)
+			}
+		}
+		if err = r.Status().Update(ctx, original); err != nil {
+			log.Error(err, "Failed to update Provision status")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{Requeue: true}, nil
+	}
 
 	switch original.Spec.Phase {
 	case "", vmv1.ProvisionPhaseCreate:
 		if v, ok := provisionReconcileMap["provision"]; ok {
-			if err = v(r, log, apiUrlCreate, original, nil); err != nil {
-				log.Error(err, "Failed to create VM")
-				return ctrl.Result{}, err
+			if err = v(ctx, r, log, apiUrlCreate, original, nil); err != nil {
+				return r.reconcileError(ctx, log, original, "Failed to create VM", err)
 			}
 		}
 	case vmv1.ProvisionPhaseUpdate:
 		if v, ok := provisionReconcileMap["update"]; ok {
-			if err = v(r, log, apiUrlUpdate, original, nil); err != nil {
-				log.Error(err, "Failed to update VM")
-				return ctrl.Result{}, err
+			if err = v(ctx, r, log, apiUrlUpdate, original, nil); err != nil {
+				return r.reconcileError(ctx, log, original, "Failed to update VM", err)
 			}
 		}
 	case vmv1.ProvisionPhaseStop:
 		if v, ok := provisionReconcileMap["stop"]; ok {
-			if err = v(r, log, apiUrlStop, original, nil); err != nil {
-				log.Error(err, "Failed to stop VM")
-				return ctrl.Result{}, err
+			if err = v(ctx, r, log, apiUrlStop, original, nil); err != nil {
+				return r.reconcileError(ctx, log, original, "Failed to stop VM", err)
 			}
 		}
 	case vmv1.ProvisionPhaseDelete:
 		if v, ok := provisionReconcileMap["deProvision"]; ok {
-			if err = v(r, log, apiUrlDelete, original, nil); err != nil {
-				log.Error(err, "Failed to delete VM")
-				return ctrl.Result{}, err
+			if err = v(ctx, r, log, apiUrlDelete, original, nil); err != nil {
+				return r.reconcileError(ctx, log, original, "Failed to delete VM", err)
 			}
 		}
 	case vmv1.ProvisionPhaseGet:
 		if v, ok := provisionReconcileMap["get"]; ok {
-			if err = v(r, log, apiUrlGet, original, nil); err != nil {
-				log.Error(err, "Failed to get VM information")
-				return ctrl.Result{}, err
+			if err = v(ctx, r, log, apiUrlGet, original, nil); err != nil {
+				return r.reconcileError(ctx, log, original, "Failed to get VM information", err)
 			}
 		}
 	default:
-		log.V(ErrorLevelIsAnError).Error(err, "No action defined for the current phase",
-			"reconcile phase", original.Spec.Phase, "namespace", req.NamespacedName)
-		return ctrl.Result{}, err
+		log.Error(fmt.Errorf("unknown phase %q", original.Spec.Phase), "No action defined for the current phase")
+		return ctrl.Result{}, nil
+	}
+
+	if resyncEnabled(original) {
+		if err = resync(ctx, r, log, original); err != nil {
+			return r.reconcileError(ctx, log, original, "Failed to resync VM", err)
+		}
+	}
+	if err = applyPowerSchedule(ctx, r, log, original); err != nil {
+		return r.reconcileError(ctx, log, original, "Failed to apply power schedule", err)
+	}
+	if r.Pricing != nil {
+		estimateCost(ctx, r, log, original)
+	}
+	clearFailed(original)
+	r.recordServerStatusChange(ctx, original, observed.ServerStatus)
+
+	if !equality.Semantic.DeepEqual(observed, &original.Status) {
+		if err = r.Status().Update(ctx, original); err != nil {
+			log.Error(err, "Failed to update Provision status")
+			return ctrl.Result{}, err
+		}
+	}
+	if blockStoragesPending(original) || blockStoragesExpanding(original) {
+		return ctrl.Result{RequeueAfter: blockStorageRequeueInterval}, nil
+	}
+	var requeueAfter time.Duration
+	if resyncEnabled(original) && r.ResyncPeriod > 0 {
+		requeueAfter = r.ResyncPeriod
+	}
+	for _, until := range []time.Duration{untilPowerTransition(original), untilExpiryCheck(original), untilResizeCheck(original)} {
+		if until > 0 && (requeueAfter == 0 || until < requeueAfter) {
+			requeueAfter = until
+		}
 	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
 
-	return ctrl.Result{}, nil
+// recordServerStatusChange records an event and metrics when the server reached a stable status.
+func (r *ProvisionReconciler) recordServerStatusChange(ctx context.Context, original *vmv1.Provision, previous string) {
+	current := original.Status.ServerStatus
+	if current == previous {
+		return
+	}
+	observeTimeToRunning(original, previous)
+	switch current {
+	case ncp.ServerStatusRunning:
+		r.event(ctx, original, corev1.EventTypeNormal, eventReasonServerRunning,
+			"Server %s is running", managedServerInstanceNo(original))
+	case ncp.ServerStatusStopped:
+		r.event(ctx, original, corev1.EventTypeNormal, eventReasonServerStopped,
+			"Server %s is stopped", managedServerInstanceNo(original))
+	}
 }
 
 // SetupWithManager sets up the controller  with the Manager.
 func (r *ProvisionReconciler) SetupWithManager(mgr ctrl.Manager) error {
 	return ctrl.NewControllerManagedBy(mgr).
 		For(&vmv1.Provision{}).
-		Complete(r)
+		Complete(traced("Provision", r))
 }
 
-func provision(r *ProvisionReconciler, log logr.Logger, url string, original *vmv1.Provision, payload interface{}) error {
-	log.V(ErrorLevelIsInfo).Info("Creating a new VM")
-	csr := &server.CreateServerRequest{
-		ServerImageProductCode:    original.Spec.Server.ImageProductCode,
-		VpcNo:                     original.Spec.VpcNo,
-		SubnetNo:                  original.Spec.SubnetNo,
-		NetworkInterfaceOrder:     original.Spec.NetworkInterface.Order,
-		AccessControlGroupNoListN: original.Spec.AccessControlGroupNoListN,
-		ServerProductCode:         original.Spec.Server.ProductCode,
-	}
-	createServerResponse, err := r.ncpService.Server.Create(ncputil.API_URL+ncputil.CREATE_SERVER_INSTANCE_PATH, csr, []int{1, 1})
-	fmt.Println(createServerResponse)
-	return err
+func provision(ctx context.Context, r *ProvisionReconciler, log logr.Logger, url string, original *vmv1.Provision, payload interface{}) error {
+	if original.Status.ServerInstanceNo != "" {
+		if err := clearCreationToken(ctx, r, original); err != nil {
+			return err
+		}
+		if err := ensureServerTags(ctx, r, log, original); err != nil {
+			return err
+		}
+		return refreshBlockStorages(ctx, r, log, original)
+	}
+
+	if err := policy.Check(ctx, r, original, nil); err != nil {
+		return err
+	}
+	if err := quota.Check(ctx, r, original, nil); err != nil {
+		return err
+	}
+	name, retried, err := setCreationToken(ctx, r, original)
+	if err != nil {
+		return err
+	}
+	if retried {
+		instance, err := findCreatedServer(ctx, r, original, name)
+		if err != nil {
+			return err
+		}
+		if instance != nil {
+			original.Status.ServerInstanceNo = instance.ServerInstanceNo
+			log.V(LogLevelInfo).Info("Found VM created by an earlier attempt",
+				"serverInstanceNo", instance.ServerInstanceNo, "serverName", name)
+			r.event(ctx, original, corev1.EventTypeNormal, eventReasonServerFound,
+				"Found server %s created by an earlier attempt", instance.ServerInstanceNo)
+			return nil
+		}
+	}
+
+	memberServerImageInstanceNo, err := memberServerImage(ctx, r, original)
+	if err != nil {
+		return err
+	}
+	placementGroupNo, err := placementGroup(ctx, r, original)
+	if err != nil {
+		return err
+	}
+	vpcNo, subnetNo, err := zoneSubnet(ctx, r, original)
+	if err != nil {
+		return err
+	}
+	mappings, err := restoredMappings(ctx, r.Client, original)
+	if err != nil {
+		return err
+	}
+	log.V(LogLevelInfo).Info("Creating a new VM")
+	csr := &ncp.CreateServerInstancesRequest{
+		CreateServerRequest: server.CreateServerRequest{
+			ServerImageProductCode:    original.Spec.Server.ImageProductCode,
+			VpcNo:                     vpcNo,
+			SubnetNo:                  subnetNo,
+			NetworkInterfaceOrder:     original.Spec.NetworkInterface.Order,
+			AccessControlGroupNoListN: original.Spec.AccessControlGroupNoListN,
+			ServerProductCode:         original.Spec.Server.ProductCode,
+		},
+		RegionCode:                        original.Spec.RegionCode,
+		ServerName:                        name,
+		ServerDescription:                 serverDescription(original),
+		AssociateWithPublicIp:             original.Spec.AssociateWithPublicIp,
+		IsProtectServerTermination:        original.Spec.IsProtectServerTermination,
+		IsEncryptedBaseBlockStorageVolume: original.Spec.IsEncryptedBaseBlockStorageVolume,
+		LoginKeyName:                      original.Spec.LoginKeyName,
+		FeeSystemTypeCode:                 original.Spec.FeeSystemTypeCode,
+		InitScriptNo:                      original.Spec.InitScriptNo,
+		MemberServerImageInstanceNo:       memberServerImageInstanceNo,
+		PlacementGroupNo:                  placementGroupNo,
+		BlockStorageMappingList:           blockStorageMappingList(mappings),
+	}
+	logAPIPayload(log, "createServerInstances request", csr)
+	createServerResponse, err := r.ncpClient.CreateServerInstances(ctx, csr)
+	logAPIPayload(log, "createServerInstances response", createServerResponse)
+	if err != nil {
+		return err
+	}
+	if len(createServerResponse.ServerInstanceList) == 0 {
+		return fmt.Errorf("createServerInstances returned no server instance")
+	}
+	original.Status.ServerInstanceNo = createServerResponse.ServerInstanceList[0].ServerInstanceNo
+	r.serverCache.Invalidate(original.Spec.RegionCode)
+	log.V(LogLevelInfo).Info("VM creation requested", "serverInstanceNo", original.Status.ServerInstanceNo)
+	r.event(ctx, original, corev1.EventTypeNormal, eventReasonCreationRequested,
+		"Requested server %s (%s)", name, original.Status.ServerInstanceNo)
+	return nil
 }
 
-func deProvision(r *ProvisionReconciler, log logr.Logger, url string, original *vmv1.Provision, payload interface{}) error {
-	log.V(ErrorLevelIsInfo).Info("Deleting an existing VM")
+func deProvision(ctx context.Context, r *ProvisionReconciler, log logr.Logger, url string, original *vmv1.Provision, payload interface{}) error {
+	log.V(LogLevelInfo).Info("Deleting an existing VM", "serverNo", original.Spec.ServerNo)
 	dsr := &server.DeleteServerRequest{ServerNo: original.Spec.ServerNo}
-	deleteServerResponse, err := r.ncpService.Server.Delete(ncputil.API_URL+ncputil.DELETE_SERVER_INSTANCE_PATH, dsr)
-	fmt.Println(deleteServerResponse)
-	return err
+	defer r.serverCache.Invalidate(original.Spec.RegionCode)
+	deleteServerResponse, err := ncp.WithContext(ctx, r.ncpService.Server).Delete(ncputil.API_URL+ncputil.DELETE_SERVER_INSTANCE_PATH, dsr)
+	logAPIPayload(log, "deleteServerInstances response", deleteServerResponse)
+	if err != nil {
+		return err
+	}
+	r.event(ctx, original, corev1.EventTypeNormal, eventReasonTerminationRequested,
+		"Requested termination of server %s", dsr.ServerNo)
+	return nil
 }
 
-func update(r *ProvisionReconciler, log logr.Logger, url string, original *vmv1.Provision, payload interface{}) error {
-	log.V(ErrorLevelIsInfo).Info("Updating an existing VM")
-	usr := &server.UpdateServerRequest{
-		ServerInstanceNo:  original.Spec.ServerInstanceNo,
-		ServerProductCode: original.Spec.Server.ProductCode,
-	}
-	updateServerResponse, err := r.ncpService.Server.Update(ncputil.API_URL+ncputil.UPDATE_SERVER_INSTANCE_PATH, usr)
-	fmt.Println(updateServerResponse)
-	return err
+func update(ctx context.Context, r *ProvisionReconciler, log logr.Logger, url string, original *vmv1.Provision, payload interface{}) error {
+	log.V(LogLevelInfo).Info("Updating an existing VM", "serverInstanceNo", managedServerInstanceNo(original),
+		"serverProductCode", original.Spec.Server.ProductCode)
+	return resize(ctx, r, log, original)
 }
 
-func stop(r *ProvisionReconciler, log logr.Logger, url string, original *vmv1.Provision, payload interface{}) error {
-	log.V(ErrorLevelIsInfo).Info("Stopping an existing VM")
+func stop(ctx context.Context, r *ProvisionReconciler, log logr.Logger, url string, original *vmv1.Provision, payload interface{}) error {
+	log.V(LogLevelInfo).Info("Stopping an existing VM", "serverNo", original.Spec.ServerNo)
 	ssr := &server.StopServerRequest{ServerNo: original.Spec.ServerNo}
-	stopServerResponse, err := r.ncpService.Server.Stop(ncputil.API_URL+ncputil.STOP_SERVER_INSTANCE_PATH, ssr)
-	fmt.Println(stopServerResponse)
-	return err
+	defer r.serverCache.Invalidate(original.Spec.RegionCode)
+	stopServerResponse, err := ncp.WithContext(ctx, r.ncpService.Server).Stop(ncputil.API_URL+ncputil.STOP_SERVER_INSTANCE_PATH, ssr)
+	logAPIPayload(log, "stopServerInstances response", stopServerResponse)
+	if err != nil {
+		return err
+	}
+	r.event(ctx, original, corev1.EventTypeNormal, eventReasonStopRequested,
+		"Requested stop of server %s", ssr.ServerNo)
+	return nil
 }
 
-func get(r *ProvisionReconciler, log logr.Logger, url string, original *vmv1.Provision, payload interface{}) error {
-	log.V(ErrorLevelIsInfo).Info("Getting information for an existing VM")
-	lsr := &server.ListServerRequest{RegionCode: original.Spec.RegionCode}
-	serverListResponse, err := r.ncpService.Server.List(ncputil.API_URL+ncputil.GET_SERVER_INSTANCE_PATH, lsr)
-	fmt.Println(serverListResponse)
-	return err
+func get(ctx context.Context, r *ProvisionReconciler, log logr.Logger, url string, original *vmv1.Provision, payload interface{}) error {
+	log.V(LogLevelInfo).Info("Getting information for an existing VM")
+	serverInstanceNo := managedServerInstanceNo(original)
+	if serverInstanceNo == "" {
+		servers, err := r.serverCache.List(ctx, original.Spec.RegionCode)
+		if err != nil {
+			return err
+		}
+		log.V(LogLevelInfo).Info("No VM recorded for this Provision", "serversInRegion", len(servers))
+		return nil
+	}
+	instance, err := r.serverCache.Get(ctx, original.Spec.RegionCode, serverInstanceNo)
+	if err != nil {
+		return err
+	}
+	original.Status.ServerStatus = instance.ServerInstanceStatus.Code
+	log.V(LogLevelInfo).Info("Got VM", "serverInstanceNo", serverInstanceNo,
+		"serverName", instance.ServerName, "status", instance.ServerInstanceStatus.Code)
+	return nil
 }
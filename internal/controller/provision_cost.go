@@ -0,0 +1,114 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/go-logr/logr"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/pricing"
+)
+
+// defaultBlockStorageVolumeType is the volume type NCP creates when none is given.
+const defaultBlockStorageVolumeType = vmv1.BlockStorageVolumeTypeSSD
+
+// estimateCost records the estimated cost of the server and its additional
+// block storages in status. Failing to price them is not worth failing the
+// reconcile for: the previous estimate is kept and the error only logged.
+func estimateCost(ctx context.Context, r *ProvisionReconciler, log logr.Logger, original *vmv1.Provision) {
+	if original.Spec.Phase == vmv1.ProvisionPhaseDelete || original.Spec.Server.ProductCode == "" {
+		original.Status.Cost = nil
+		return
+	}
+	currency, hourly, monthly, err := provisionCost(ctx, r.Pricing, original)
+	if err != nil {
+		log.V(LogLevelDebug).Info("Unable to estimate the cost of the VM", "error", err.Error())
+		return
+	}
+
+	cost := &vmv1.CostEstimate{
+		Currency: currency,
+		Hourly:   formatAmount(hourly),
+		Monthly:  formatAmount(monthly),
+	}
+	if previous := original.Status.Cost; previous != nil && previous.Currency == cost.Currency &&
+		previous.Hourly == cost.Hourly && previous.Monthly == cost.Monthly {
+		return
+	}
+	cost.EstimatedTime = metav1.Now()
+	original.Status.Cost = cost
+}
+
+// provisionCost prices the server product and the additional block storages,
+// at the size NCP reports once they exist.
+func provisionCost(ctx context.Context, catalog pricing.Catalog, original *vmv1.Provision) (string, float64, float64, error) {
+	regionCode, fee := original.Spec.RegionCode, original.Spec.FeeSystemTypeCode
+	rate, err := catalog.ServerRate(ctx, regionCode, original.Spec.Server.ProductCode)
+	if err != nil {
+		return "", 0, 0, err
+	}
+	hourly, monthly := pricing.Cost(rate, fee, 1)
+
+	sizes := map[int]int{}
+	for _, b := range original.Status.BlockStorages {
+		sizes[b.Order] = b.BlockStorageSize
+	}
+	for _, m := range original.Spec.BlockStorageMappings {
+		// the boot volume is part of the server product
+		if m.Order == 0 {
+			continue
+		}
+		size, ok := sizes[m.Order]
+		if !ok || size == 0 {
+			size = m.BlockStorageSize
+		}
+		if size == 0 {
+			continue
+		}
+		volumeType := m.BlockStorageVolumeTypeCode
+		if volumeType == "" {
+			volumeType = defaultBlockStorageVolumeType
+		}
+		storageRate, err := catalog.BlockStorageRate(ctx, regionCode, string(volumeType))
+		if err != nil {
+			return "", 0, 0, err
+		}
+		if storageRate.Currency != rate.Currency {
+			return "", 0, 0, fmt.Errorf("%s block storage is priced in %s, the server in %s",
+				volumeType, storageRate.Currency, rate.Currency)
+		}
+		storageHourly, storageMonthly := pricing.Cost(storageRate, fee, float64(size))
+		hourly, monthly = hourly+storageHourly, monthly+storageMonthly
+	}
+	return rate.Currency, hourly, monthly, nil
+}
+
+// formatAmount formats an amount of money for status.
+func formatAmount(amount float64) string {
+	return strconv.FormatFloat(amount, 'f', 2, 64)
+}
+
+// parseAmount parses an amount formatted by formatAmount.
+func parseAmount(amount string) float64 {
+	f, _ := strconv.ParseFloat(amount, 64)
+	return f
+}
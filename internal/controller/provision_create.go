@@ -0,0 +1,113 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+// setCreationToken records the name of the server about to be created in the
+// creation token annotation and persists it before NCP is called. It returns
+// the name to create the server with and whether an earlier attempt already
+// set the token, in which case that attempt may have created the server.
+func setCreationToken(ctx context.Context, r *ProvisionReconciler, original *vmv1.Provision) (string, bool, error) {
+	if name, ok := original.Annotations[vmv1.CreationTokenAnnotation]; ok {
+		return name, true, nil
+	}
+	name := serverName(original)
+	if original.Annotations == nil {
+		original.Annotations = map[string]string{}
+	}
+	original.Annotations[vmv1.CreationTokenAnnotation] = name
+	if err := updateKeepingStatus(ctx, r, original); err != nil {
+		return "", false, err
+	}
+	return name, false, nil
+}
+
+// clearCreationToken drops the creation token once the created server is recorded in status.
+func clearCreationToken(ctx context.Context, r *ProvisionReconciler, original *vmv1.Provision) error {
+	if _, ok := original.Annotations[vmv1.CreationTokenAnnotation]; !ok {
+		return nil
+	}
+	delete(original.Annotations, vmv1.CreationTokenAnnotation)
+	return updateKeepingStatus(ctx, r, original)
+}
+
+// updateKeepingStatus updates the object without losing status changes not
+// yet written, as Update replaces the in-memory object with the stored one.
+func updateKeepingStatus(ctx context.Context, r *ProvisionReconciler, original *vmv1.Provision) error {
+	status := original.Status.DeepCopy()
+	if err := r.Update(ctx, original); err != nil {
+		return err
+	}
+	original.Status = *status
+	return nil
+}
+
+// findCreatedServer looks for the server an earlier attempt created under
+// name. Servers managed by another Provision or tagged as someone else's are
+// not considered.
+func findCreatedServer(ctx context.Context, r *ProvisionReconciler, original *vmv1.Provision, name string) (*ncp.ServerInstance, error) {
+	instances, err := r.ncpClient.GetServerInstancesByName(ctx, original.Spec.RegionCode, name)
+	if err != nil || len(instances) == 0 {
+		return nil, err
+	}
+
+	instanceNos := make([]string, 0, len(instances))
+	for _, s := range instances {
+		instanceNos = append(instanceNos, s.ServerInstanceNo)
+	}
+	tagList, err := r.ncpClient.GetInstanceTagList(ctx, original.Spec.RegionCode, instanceNos...)
+	if err != nil {
+		return nil, err
+	}
+	tags := map[string]map[string]string{}
+	for _, t := range tagList.InstanceTagList {
+		if tags[t.InstanceNo] == nil {
+			tags[t.InstanceNo] = map[string]string{}
+		}
+		tags[t.InstanceNo][t.TagKey] = t.TagValue
+	}
+
+	var found *ncp.ServerInstance
+	for i := range instances {
+		s := &instances[i]
+		if foreignServer(r.ClusterID, tags[s.ServerInstanceNo]) {
+			continue
+		}
+		if uid, ok := tags[s.ServerInstanceNo][tagKeyUID]; ok && uid != string(original.UID) {
+			continue
+		}
+		owner, err := r.serverOwner(ctx, s.ServerInstanceNo)
+		if err != nil {
+			return nil, err
+		}
+		if owner != nil && owner.UID != original.UID {
+			continue
+		}
+		if found != nil {
+			return nil, fmt.Errorf("more than one server named %q could have been created by this Provision", name)
+		}
+		found = s
+	}
+	return found, nil
+}
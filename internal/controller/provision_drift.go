@@ -0,0 +1,297 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ncputil "github.com/cloud-club/Aviator-service/pkg"
+	server "github.com/cloud-club/Aviator-service/types/server"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+// Fields compared on resync, named after their spec json fields.
+const (
+	driftFieldProductCode   = "serverProductCode"
+	driftFieldPowerState    = "powerState"
+	driftFieldACG           = "accessControlGroupNoList"
+	driftFieldPublicIp      = "associateWithPublicIp"
+	driftFieldProtectServer = "isProtectServerTermination"
+)
+
+// drift is one spec field whose live value differs.
+type drift struct {
+	field   string
+	desired string
+	actual  string
+}
+
+func (d drift) String() string {
+	return fmt.Sprintf("%s (spec %q, live %q)", d.field, d.desired, d.actual)
+}
+
+// liveServer is what resync reads back from NCP for a Provision.
+type liveServer struct {
+	instance         *ncp.ServerInstance
+	networkInterface *ncp.NetworkInterface
+}
+
+// managedServerInstanceNo returns the server a Provision manages, preferring
+// the one it created or adopted over the spec's explicit instance numbers.
+func managedServerInstanceNo(original *vmv1.Provision) string {
+	switch {
+	case original.Status.ServerInstanceNo != "":
+		return original.Status.ServerInstanceNo
+	case original.Spec.ServerInstanceNo != "":
+		return original.Spec.ServerInstanceNo
+	default:
+		return original.Spec.ServerNo
+	}
+}
+
+// resyncEnabled reports whether the phase describes a steady state drift can be measured against.
+func resyncEnabled(original *vmv1.Provision) bool {
+	switch original.Spec.Phase {
+	case "", vmv1.ProvisionPhaseCreate, vmv1.ProvisionPhaseGet, vmv1.ProvisionPhaseStop:
+		return managedServerInstanceNo(original) != ""
+	}
+	return false
+}
+
+// resync compares the live server with the spec, records the result in the
+// Drifted condition and, with the Correct policy, reverts what it can.
+func resync(ctx context.Context, r *ProvisionReconciler, log logr.Logger, original *vmv1.Provision) (err error) {
+	ctx, span := tracer.Start(ctx, "Provision.resync")
+	defer func() { endSpan(span, err) }()
+
+	serverInstanceNo := managedServerInstanceNo(original)
+	span.SetAttributes(attribute.String("ncp.server_instance_no", serverInstanceNo))
+	live, err := r.readLiveServer(ctx, original.Spec.RegionCode, serverInstanceNo)
+	if err != nil {
+		return err
+	}
+	now := metav1.Now()
+	original.Status.ServerStatus = live.instance.ServerInstanceStatus.Code
+	original.Status.ZoneCode = live.instance.ZoneCode
+	original.Status.LastSyncTime = &now
+
+	if !live.instance.Stable() {
+		log.V(LogLevelDebug).Info("Skipping drift check while server is transitioning",
+			"serverInstanceNo", serverInstanceNo, "status", live.instance.ServerInstanceStatus.Code,
+			"operation", live.instance.ServerInstanceOperation.Code)
+		return nil
+	}
+
+	drifts := detectDrift(&original.Spec, live)
+	if len(drifts) == 0 {
+		meta.SetStatusCondition(&original.Status.Conditions, metav1.Condition{
+			Type:               vmv1.ConditionTypeDrifted,
+			Status:             metav1.ConditionFalse,
+			Reason:             "InSync",
+			Message:            "live server matches the spec",
+			ObservedGeneration: original.Generation,
+		})
+		return nil
+	}
+
+	message := joinDrifts(drifts)
+	log.V(LogLevelInfo).Info("Live server differs from spec",
+		"serverInstanceNo", serverInstanceNo, "drift", message)
+
+	// only what was actually reverted is reported as corrected
+	remaining := drifts
+	reason := "DriftDetected"
+	if original.Spec.DriftPolicy == vmv1.DriftPolicyCorrect {
+		corrected, left, correctErr := r.correctDrift(ctx, original, live, drifts)
+		remaining = left
+		if len(corrected) > 0 {
+			r.event(ctx, original, corev1.EventTypeNormal, eventReasonDriftCorrected, "%s", joinDrifts(corrected))
+		}
+		if correctErr != nil {
+			reason = "DriftCorrectionFailed"
+			message += "; correction failed: " + correctErr.Error()
+		} else if len(remaining) == 0 {
+			reason = "DriftCorrected"
+		}
+	}
+	if len(remaining) > 0 {
+		r.event(ctx, original, corev1.EventTypeWarning, eventReasonDriftDetected, "%s", joinDrifts(remaining))
+	}
+	meta.SetStatusCondition(&original.Status.Conditions, metav1.Condition{
+		Type:               vmv1.ConditionTypeDrifted,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: original.Generation,
+	})
+	return nil
+}
+
+func joinDrifts(drifts []drift) string {
+	messages := make([]string, 0, len(drifts))
+	for _, d := range drifts {
+		messages = append(messages, d.String())
+	}
+	return strings.Join(messages, "; ")
+}
+
+func (r *ProvisionReconciler) readLiveServer(ctx context.Context, regionCode, serverInstanceNo string) (*liveServer, error) {
+	instance, err := r.serverCache.Get(ctx, regionCode, serverInstanceNo)
+	if err != nil {
+		return nil, err
+	}
+	live := &liveServer{instance: instance}
+	for _, no := range instance.NetworkInterfaceNoList {
+		nic, err := r.ncpClient.GetNetworkInterfaceDetail(ctx, regionCode, no)
+		if err != nil {
+			return nil, err
+		}
+		if nic.IsDefault || live.networkInterface == nil {
+			live.networkInterface = nic
+		}
+	}
+	return live, nil
+}
+
+// detectDrift lists the spec fields the operator applies whose live value
+// differs. Fields left empty in the spec are not compared, and the public
+// IP, which is only applied on creation, not for adopted servers.
+func detectDrift(spec *vmv1.ProvisionSpec, live *liveServer) []drift {
+	var drifts []drift
+	instance := live.instance
+
+	if spec.Server.ProductCode != "" && spec.Server.ProductCode != instance.ServerProductCode {
+		drifts = append(drifts, drift{driftFieldProductCode, spec.Server.ProductCode, instance.ServerProductCode})
+	}
+	// outside the Stop phase a power schedule starts and stops the server itself
+	scheduled := spec.PowerSchedule != nil && spec.Phase != vmv1.ProvisionPhaseStop
+	if want := desiredServerStatus(spec); !scheduled && want != instance.ServerInstanceStatus.Code {
+		drifts = append(drifts, drift{driftFieldPowerState, want, instance.ServerInstanceStatus.Code})
+	}
+	if want := accessControlGroups(spec.AccessControlGroupNoListN); len(want) > 0 && live.networkInterface != nil {
+		have := append([]string(nil), live.networkInterface.AccessControlGroupNoList...)
+		sort.Strings(have)
+		if strings.Join(want, ",") != strings.Join(have, ",") {
+			drifts = append(drifts, drift{driftFieldACG, strings.Join(want, ","), strings.Join(have, ",")})
+		}
+	}
+	if hasPublicIp := instance.PublicIp != ""; spec.Adopt == nil && spec.AssociateWithPublicIp != hasPublicIp {
+		drifts = append(drifts, drift{driftFieldPublicIp,
+			strconv.FormatBool(spec.AssociateWithPublicIp), strconv.FormatBool(hasPublicIp)})
+	}
+	if spec.IsProtectServerTermination != instance.IsProtectServerTermination {
+		drifts = append(drifts, drift{driftFieldProtectServer,
+			strconv.FormatBool(spec.IsProtectServerTermination), strconv.FormatBool(instance.IsProtectServerTermination)})
+	}
+	return drifts
+}
+
+func desiredServerStatus(spec *vmv1.ProvisionSpec) string {
+	if spec.Phase == vmv1.ProvisionPhaseStop {
+		return ncp.ServerStatusStopped
+	}
+	return ncp.ServerStatusRunning
+}
+
+// accessControlGroups splits the comma separated accessControlGroupNoList into a sorted list.
+func accessControlGroups(list string) []string {
+	var acgs []string
+	for _, no := range strings.Split(list, ",") {
+		if no = strings.TrimSpace(no); no != "" {
+			acgs = append(acgs, no)
+		}
+	}
+	sort.Strings(acgs)
+	return acgs
+}
+
+// correctDrift reverts drifted fields to the spec and returns the drifts it
+// reverted and those it left. A public IP is only reported, and the product
+// is only changed while the server is stopped.
+func (r *ProvisionReconciler) correctDrift(ctx context.Context, original *vmv1.Provision, live *liveServer, drifts []drift) (corrected, remaining []drift, err error) {
+	spec := &original.Spec
+	regionCode := spec.RegionCode
+	serverInstanceNo := live.instance.ServerInstanceNo
+	defer r.serverCache.Invalidate(regionCode)
+
+	for i, d := range drifts {
+		var err error
+		switch {
+		case d.field == driftFieldPowerState && d.desired == ncp.ServerStatusRunning:
+			_, err = ncp.WithContext(ctx, r.ncpService.Server).Start(ncputil.API_URL+ncputil.START_SERVER_INSTANCE_PATH,
+				&server.StartServerRequest{ServerNo: serverInstanceNo})
+		case d.field == driftFieldPowerState:
+			_, err = ncp.WithContext(ctx, r.ncpService.Server).Stop(ncputil.API_URL+ncputil.STOP_SERVER_INSTANCE_PATH,
+				&server.StopServerRequest{ServerNo: serverInstanceNo})
+		case d.field == driftFieldProtectServer:
+			err = r.ncpClient.SetProtectServerTermination(ctx, regionCode, serverInstanceNo, spec.IsProtectServerTermination)
+		case d.field == driftFieldProductCode && live.instance.ServerInstanceStatus.Code == ncp.ServerStatusStopped:
+			err = r.ncpClient.ChangeServerInstanceSpec(ctx, regionCode, serverInstanceNo, spec.Server.ProductCode)
+		case d.field == driftFieldACG:
+			err = r.correctAccessControlGroups(ctx, regionCode, live, accessControlGroups(spec.AccessControlGroupNoListN))
+		default:
+			remaining = append(remaining, d)
+			continue
+		}
+		if err != nil {
+			return corrected, append(remaining, drifts[i:]...), fmt.Errorf("%s: %w", d.field, err)
+		}
+		corrected = append(corrected, d)
+	}
+	return corrected, remaining, nil
+}
+
+func (r *ProvisionReconciler) correctAccessControlGroups(ctx context.Context, regionCode string, live *liveServer, want []string) error {
+	nic := live.networkInterface
+	wanted := make(map[string]bool, len(want))
+	for _, no := range want {
+		wanted[no] = true
+	}
+	var add, remove []string
+	for _, no := range nic.AccessControlGroupNoList {
+		if !wanted[no] {
+			remove = append(remove, no)
+		}
+		delete(wanted, no)
+	}
+	for _, no := range want {
+		if wanted[no] {
+			add = append(add, no)
+		}
+	}
+	if len(add) > 0 {
+		if err := r.ncpClient.AddNetworkInterfaceAccessControlGroup(ctx, regionCode, live.instance.VpcNo, nic.NetworkInterfaceNo, add); err != nil {
+			return err
+		}
+	}
+	if len(remove) > 0 {
+		return r.ncpClient.RemoveNetworkInterfaceAccessControlGroup(ctx, regionCode, live.instance.VpcNo, nic.NetworkInterfaceNo, remove)
+	}
+	return nil
+}
@@ -0,0 +1,195 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+	"vm.cloudclub.io/internal/placement"
+	"vm.cloudclub.io/internal/policy"
+	"vm.cloudclub.io/internal/quota"
+)
+
+// reconcileError records a failed NCP call in an event and decides how the
+// reconcile is retried. Terminal errors, ProvisionPolicy violations and
+// placements without a candidate subnet in an allowed zone are recorded in
+// the Failed condition and not retried until the spec changes. Throttled
+// calls are retried after throttledRequeueInterval, servers the VMQuotas do
+// not allow after quotaRequeueInterval, objects waiting for another object
+// after dependencyRequeueInterval, failed pre-stop hooks after
+// resizeRequeueInterval and anything else with the controller's exponential
+// backoff.
+func (r *ProvisionReconciler) reconcileError(ctx context.Context, log logr.Logger, original *vmv1.Provision, msg string, err error) (ctrl.Result, error) {
+	log.Error(err, msg)
+
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		return ctrl.Result{}, err
+	}
+	var notReady *dependencyNotReadyError
+	if errors.As(err, &notReady) {
+		r.event(ctx, original, corev1.EventTypeNormal, eventReasonWaiting, "%s: %s", msg, notReady.Error())
+		return ctrl.Result{RequeueAfter: dependencyRequeueInterval}, nil
+	}
+	var hookErr *preStopHookError
+	if errors.As(err, &hookErr) {
+		r.event(ctx, original, corev1.EventTypeWarning, eventReasonPreStopHookFailed, "%s: %s", msg, hookErr.Error())
+		return ctrl.Result{RequeueAfter: resizeRequeueInterval}, nil
+	}
+	var exceeded *quota.ExceededError
+	if errors.As(err, &exceeded) {
+		r.event(ctx, original, corev1.EventTypeWarning, eventReasonQuotaExceeded, "%s: %s", msg, exceeded.Error())
+		return ctrl.Result{RequeueAfter: quotaRequeueInterval}, nil
+	}
+	var violation *policy.ViolationError
+	if errors.As(err, &violation) {
+		r.event(ctx, original, corev1.EventTypeWarning, eventReasonPolicyViolation, "%s: %s", msg, violation.Error())
+		return r.setFailed(ctx, log, original, eventReasonPolicyViolation, msg+": "+violation.Error())
+	}
+	var noSubnet *placement.NoSubnetError
+	if errors.As(err, &noSubnet) {
+		r.event(ctx, original, corev1.EventTypeWarning, eventReasonNoZoneSubnet, "%s: %s", msg, noSubnet.Error())
+		return r.setFailed(ctx, log, original, eventReasonNoZoneSubnet, msg+": "+noSubnet.Error())
+	}
+	ncpErr := ncp.AsError(err)
+	r.event(ctx, original, corev1.EventTypeWarning, string(ncpErr.Kind), "%s: %s", msg, ncpErr.Error())
+	if ncpErr.Kind == ncp.ErrorKindThrottled {
+		return ctrl.Result{RequeueAfter: throttledRequeueInterval}, nil
+	}
+	if !ncpErr.Terminal() {
+		return ctrl.Result{}, err
+	}
+	return r.setFailed(ctx, log, original, string(ncpErr.Kind), msg+": "+ncpErr.Message)
+}
+
+// resourceError records the failed reconcile of an object backed by an NCP
+// resource, such as a ServerImage, in an event and decides how it is retried.
+// Terminal errors are recorded in the Failed condition of conditions and not
+// retried until the spec changes. Without conditions, as when deleting, they
+// are retried like any other error.
+func resourceError(ctx context.Context, recorder record.EventRecorder, object client.Object, conditions *[]metav1.Condition, err error) (ctrl.Result, error) {
+	var status apierrors.APIStatus
+	if errors.As(err, &status) {
+		return ctrl.Result{}, err
+	}
+	var notReady *dependencyNotReadyError
+	if errors.As(err, &notReady) {
+		recordEvent(ctx, recorder, object, corev1.EventTypeNormal, eventReasonWaiting, "%s", notReady.Error())
+		return ctrl.Result{RequeueAfter: dependencyRequeueInterval}, nil
+	}
+	ncpErr := ncp.AsError(err)
+	recordEvent(ctx, recorder, object, corev1.EventTypeWarning, string(ncpErr.Kind), "%s", ncpErr.Error())
+	if ncpErr.Kind == ncp.ErrorKindThrottled {
+		return ctrl.Result{RequeueAfter: throttledRequeueInterval}, nil
+	}
+	if conditions == nil || !ncpErr.Terminal() {
+		return ctrl.Result{}, err
+	}
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               vmv1.ConditionTypeFailed,
+		Status:             metav1.ConditionTrue,
+		Reason:             string(ncpErr.Kind),
+		Message:            ncpErr.Message,
+		ObservedGeneration: object.GetGeneration(),
+	})
+	return ctrl.Result{}, nil
+}
+
+// setFailed records in the Failed condition that the current spec cannot be reconciled.
+func (r *ProvisionReconciler) setFailed(ctx context.Context, log logr.Logger, original *vmv1.Provision, reason, message string) (ctrl.Result, error) {
+	meta.SetStatusCondition(&original.Status.Conditions, metav1.Condition{
+		Type:               vmv1.ConditionTypeFailed,
+		Status:             metav1.ConditionTrue,
+		Reason:             reason,
+		Message:            message,
+		ObservedGeneration: original.Generation,
+	})
+	if err := r.Status().Update(ctx, original); err != nil {
+		log.Error(err, "Failed to update Provision status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// dependencyNotReadyError is returned while an object another refers to,
+// such as a ServerImage, does not exist or is not ready for use yet.
+type dependencyNotReadyError struct {
+	kind, name, reason string
+}
+
+func (e *dependencyNotReadyError) Error() string {
+	return fmt.Sprintf("%s %s is %s", e.kind, e.name, e.reason)
+}
+
+// preStopHookError is a failed call of the pre-stop hook of a resize. The
+// hook may answer later, so the resize is retried after
+// resizeRequeueInterval.
+type preStopHookError struct {
+	url string
+	err error
+}
+
+func (e *preStopHookError) Error() string {
+	return fmt.Sprintf("pre-stop hook %s: %v", e.url, e.err)
+}
+
+func (e *preStopHookError) Unwrap() error {
+	return e.err
+}
+
+// failedForGeneration reports whether the current spec already failed terminally.
+func failedForGeneration(original *vmv1.Provision) bool {
+	return failedFor(original.Status.Conditions, original.Generation)
+}
+
+// failedFor reports whether conditions record a terminal failure of generation.
+func failedFor(conditions []metav1.Condition, generation int64) bool {
+	failed := meta.FindStatusCondition(conditions, vmv1.ConditionTypeFailed)
+	return failed != nil && failed.Status == metav1.ConditionTrue && failed.ObservedGeneration == generation
+}
+
+// clearFailed resets a Failed condition left by an earlier spec once reconciling succeeds.
+func clearFailed(original *vmv1.Provision) {
+	clearFailedCondition(&original.Status.Conditions, original.Generation)
+}
+
+// clearFailedCondition is clearFailed for the conditions of any object.
+func clearFailedCondition(conditions *[]metav1.Condition, generation int64) {
+	if !meta.IsStatusConditionTrue(*conditions, vmv1.ConditionTypeFailed) {
+		return
+	}
+	meta.SetStatusCondition(conditions, metav1.Condition{
+		Type:               vmv1.ConditionTypeFailed,
+		Status:             metav1.ConditionFalse,
+		Reason:             "Reconciled",
+		Message:            "the spec was reconciled",
+		ObservedGeneration: generation,
+	})
+}
@@ -0,0 +1,259 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	ncputil "github.com/cloud-club/Aviator-service/pkg"
+	server "github.com/cloud-club/Aviator-service/types/server"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+// Reasons of the Expired condition.
+const (
+	expiredReasonStopped    = "Stopped"
+	expiredReasonTerminated = "Terminated"
+)
+
+// expiryTime returns when a Provision expires, or nil when it does not. A
+// malformed ExpiresAtAnnotation is logged and ignored.
+func expiryTime(log logr.Logger, original *vmv1.Provision) *time.Time {
+	expiry := original.Spec.Expiry
+	if expiry == nil {
+		return nil
+	}
+	if value, ok := original.Annotations[vmv1.ExpiresAtAnnotation]; ok {
+		t, err := time.Parse(time.RFC3339, value)
+		if err == nil {
+			return &t
+		}
+		log.Error(err, "Ignoring invalid expiry annotation", "annotation", vmv1.ExpiresAtAnnotation)
+	}
+
+	var at *time.Time
+	if expiry.TTL != nil {
+		t := original.CreationTimestamp.Add(expiry.TTL.Duration)
+		at = &t
+	}
+	if expiry.ExpiresAt != nil && (at == nil || expiry.ExpiresAt.Time.Before(*at)) {
+		t := expiry.ExpiresAt.Time
+		at = &t
+	}
+	return at
+}
+
+// checkExpiry records when the Provision expires, warns about it ahead of
+// time and reports whether it has expired. Provisions being deleted do not
+// expire. Extending the expiry of a Provision whose server was terminated
+// creates a new server, unless the server was named in the spec.
+func (r *ProvisionReconciler) checkExpiry(ctx context.Context, log logr.Logger, original *vmv1.Provision) bool {
+	at := expiryTime(log, original)
+	if at == nil || original.Spec.Phase == vmv1.ProvisionPhaseDelete {
+		original.Status.ExpiresAt, original.Status.ExpiryWarningTime = nil, nil
+		return false
+	}
+	if original.Status.ExpiresAt == nil || !original.Status.ExpiresAt.Time.Equal(*at) {
+		expiresAt := metav1.NewTime(*at)
+		original.Status.ExpiresAt = &expiresAt
+	}
+
+	now := time.Now()
+	if !now.Before(*at) {
+		return true
+	}
+	if expired := meta.FindStatusCondition(original.Status.Conditions, vmv1.ConditionTypeExpired); expired != nil &&
+		expired.Status == metav1.ConditionTrue {
+		message := "the expiry was extended to " + at.Format(time.RFC3339)
+		if expired.Reason == expiredReasonTerminated {
+			// A server named in the spec cannot be replaced, so the
+			// Provision stays expired.
+			if original.Spec.Adopt != nil || original.Spec.ServerInstanceNo != "" || original.Spec.ServerNo != "" {
+				r.event(ctx, original, corev1.EventTypeWarning, eventReasonExpired,
+					"Provision cannot be extended, its server %s was terminated", managedServerInstanceNo(original))
+				return true
+			}
+			forgetServer(original)
+			message += "; its server was terminated, so a new one is created"
+		}
+		meta.SetStatusCondition(&original.Status.Conditions, metav1.Condition{
+			Type:               vmv1.ConditionTypeExpired,
+			Status:             metav1.ConditionFalse,
+			Reason:             "Extended",
+			Message:            message,
+			ObservedGeneration: original.Generation,
+		})
+	}
+	warnAt := at.Add(-warnBefore(original))
+	warned := original.Status.ExpiryWarningTime
+	if !now.Before(warnAt) && (warned == nil || warned.Time.Before(warnAt)) {
+		r.event(ctx, original, corev1.EventTypeWarning, eventReasonExpiringSoon,
+			"Provision expires at %s, when its server will be %s", at.Format(time.RFC3339), expiryOutcome(original))
+		warningTime := metav1.NewTime(now)
+		original.Status.ExpiryWarningTime = &warningTime
+	}
+	return false
+}
+
+// forgetServer clears the status describing a server terminated on expiry,
+// so it is not mistaken for the server of the Provision once it is extended.
+func forgetServer(original *vmv1.Provision) {
+	original.Status.ServerInstanceNo = ""
+	original.Status.ServerStatus = ""
+	original.Status.ServerTagsHash = ""
+	original.Status.BlockStorages = nil
+	original.Status.ZoneCode = ""
+	original.Status.SubnetNo = ""
+	original.Status.Resize = nil
+}
+
+func warnBefore(original *vmv1.Provision) time.Duration {
+	if w := original.Spec.Expiry.WarnBefore; w != nil {
+		return w.Duration
+	}
+	return time.Hour
+}
+
+func expiryAction(original *vmv1.Provision) vmv1.ExpiryAction {
+	if original.Spec.Expiry.Action == "" {
+		return vmv1.ExpiryActionStop
+	}
+	return original.Spec.Expiry.Action
+}
+
+func expiryOutcome(original *vmv1.Provision) string {
+	if expiryAction(original) == vmv1.ExpiryActionTerminate {
+		return "terminated"
+	}
+	return "stopped"
+}
+
+// untilExpiryCheck returns how long until the expiry warning or the expiry
+// is due, or 0 when neither is pending.
+func untilExpiryCheck(original *vmv1.Provision) time.Duration {
+	if original.Status.ExpiresAt == nil {
+		return 0
+	}
+	at := original.Status.ExpiresAt.Time
+	next := at
+	if warnAt := at.Add(-warnBefore(original)); original.Status.ExpiryWarningTime == nil ||
+		original.Status.ExpiryWarningTime.Time.Before(warnAt) {
+		next = warnAt
+	}
+	if until := time.Until(next); until > minScheduleRequeue {
+		return until
+	}
+	return minScheduleRequeue
+}
+
+// expire stops the server of an expired Provision and, for the Terminate
+// action, terminates it once stopped and optionally deletes the Provision.
+// Nothing else is reconciled until the expiry is extended.
+func (r *ProvisionReconciler) expire(ctx context.Context, log logr.Logger, original *vmv1.Provision, observed *vmv1.ProvisionStatus) (ctrl.Result, error) {
+	action := expiryAction(original)
+	serverInstanceNo := managedServerInstanceNo(original)
+	result := ctrl.Result{}
+	reason := ""
+
+	expired := meta.FindStatusCondition(original.Status.Conditions, vmv1.ConditionTypeExpired)
+	switch {
+	case expired != nil && expired.Status == metav1.ConditionTrue && expired.Reason == expiredReasonTerminated:
+		reason = expiredReasonTerminated
+	case serverInstanceNo == "":
+		reason = expiredReasonStopped
+		if action == vmv1.ExpiryActionTerminate {
+			reason = expiredReasonTerminated
+		}
+	default:
+		instance, err := r.serverCache.Get(ctx, original.Spec.RegionCode, serverInstanceNo)
+		if err != nil {
+			if ncp.AsError(err).Kind != ncp.ErrorKindNotFound {
+				return r.reconcileError(ctx, log, original, "Failed to get expired VM", err)
+			}
+			reason = expiredReasonTerminated
+			break
+		}
+		original.Status.ServerStatus = instance.ServerInstanceStatus.Code
+		switch {
+		case !instance.Stable():
+			result.RequeueAfter = expiryRequeueInterval
+		case instance.ServerInstanceStatus.Code == ncp.ServerStatusRunning:
+			log.V(LogLevelInfo).Info("Stopping expired VM", "serverInstanceNo", serverInstanceNo)
+			resp, err := ncp.WithContext(ctx, r.ncpService.Server).Stop(ncputil.API_URL+ncputil.STOP_SERVER_INSTANCE_PATH,
+				&server.StopServerRequest{ServerNo: serverInstanceNo})
+			logAPIPayload(log, "stopServerInstances response", resp)
+			r.serverCache.Invalidate(original.Spec.RegionCode)
+			if err != nil {
+				return r.reconcileError(ctx, log, original, "Failed to stop expired VM", err)
+			}
+			r.event(ctx, original, corev1.EventTypeNormal, eventReasonExpired,
+				"Provision expired, requested stop of server %s", serverInstanceNo)
+			result.RequeueAfter = expiryRequeueInterval
+		case action == vmv1.ExpiryActionStop:
+			reason = expiredReasonStopped
+		default:
+			log.V(LogLevelInfo).Info("Terminating expired VM", "serverInstanceNo", serverInstanceNo)
+			resp, err := ncp.WithContext(ctx, r.ncpService.Server).Delete(ncputil.API_URL+ncputil.DELETE_SERVER_INSTANCE_PATH,
+				&server.DeleteServerRequest{ServerNo: serverInstanceNo})
+			logAPIPayload(log, "terminateServerInstances response", resp)
+			r.serverCache.Invalidate(original.Spec.RegionCode)
+			if err != nil {
+				return r.reconcileError(ctx, log, original, "Failed to terminate expired VM", err)
+			}
+			r.event(ctx, original, corev1.EventTypeNormal, eventReasonExpired,
+				"Provision expired, requested termination of server %s", serverInstanceNo)
+			reason = expiredReasonTerminated
+		}
+	}
+
+	if reason != "" {
+		meta.SetStatusCondition(&original.Status.Conditions, metav1.Condition{
+			Type:               vmv1.ConditionTypeExpired,
+			Status:             metav1.ConditionTrue,
+			Reason:             reason,
+			Message:            "the Provision expired at " + original.Status.ExpiresAt.Format(time.RFC3339),
+			ObservedGeneration: original.Generation,
+		})
+	}
+	if reason == expiredReasonTerminated && original.Spec.Expiry.DeleteProvision {
+		log.V(LogLevelInfo).Info("Deleting expired Provision")
+		if err := r.Delete(ctx, original); err != nil && !apierrors.IsNotFound(err) {
+			log.Error(err, "Failed to delete expired Provision")
+			return ctrl.Result{}, err
+		}
+		return ctrl.Result{}, nil
+	}
+
+	if !equality.Semantic.DeepEqual(observed, &original.Status) {
+		if err := r.Status().Update(ctx, original); err != nil {
+			log.Error(err, "Failed to update Provision status")
+			return ctrl.Result{}, err
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,79 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+var _ = Describe("Expiring a Provision whose server is already gone", func() {
+	var (
+		ctx     context.Context
+		key     types.NamespacedName
+		fakeAPI *fakeNCP
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		key = types.NamespacedName{Namespace: "default", Name: "batch"}
+		fakeAPI = newFakeNCP()
+	})
+
+	expired := func(expiry *vmv1.Expiry) client.Client {
+		return newFakeClient(&vmv1.Provision{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name,
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-2 * time.Hour))},
+			Spec:   vmv1.ProvisionSpec{RegionCode: "KR", Expiry: expiry},
+			Status: vmv1.ProvisionStatus{ServerInstanceNo: "111"},
+		})
+	}
+
+	It("records the server as terminated", func() {
+		c := expired(&vmv1.Expiry{TTL: &metav1.Duration{Duration: time.Hour}, Action: vmv1.ExpiryActionTerminate})
+
+		_, err := newTestProvisionReconciler(c, fakeAPI, &fakeServerService{}).Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		provision := &vmv1.Provision{}
+		Expect(c.Get(ctx, key, provision)).To(Succeed())
+		condition := meta.FindStatusCondition(provision.Status.Conditions, vmv1.ConditionTypeExpired)
+		Expect(condition).NotTo(BeNil())
+		Expect(condition.Status).To(Equal(metav1.ConditionTrue))
+		Expect(condition.Reason).To(Equal(expiredReasonTerminated))
+	})
+
+	It("deletes the Provision when asked to", func() {
+		c := expired(&vmv1.Expiry{TTL: &metav1.Duration{Duration: time.Hour}, Action: vmv1.ExpiryActionTerminate,
+			DeleteProvision: true})
+
+		_, err := newTestProvisionReconciler(c, fakeAPI, &fakeServerService{}).Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(apierrors.IsNotFound(c.Get(ctx, key, &vmv1.Provision{}))).To(BeTrue())
+	})
+})
@@ -0,0 +1,205 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+// Tags marking the cluster and Provision that own a server.
+const (
+	tagKeyCluster   = "aviator-cluster"
+	tagKeyNamespace = "aviator-namespace"
+	tagKeyName      = "aviator-name"
+	tagKeyUID       = "aviator-uid"
+
+	// NCP server and image names are 3 to 30 lowercase letters, digits and hyphens
+	maxServerNameLength = 30
+	serverNameHashLen   = 6
+
+	maxTagKeyLength   = 127
+	maxTagValueLength = 255
+)
+
+// serverName returns spec.server.serverName or, when unset, a name derived
+// from the Provision's namespace and name that carries the ownership prefix.
+// Names too long for NCP are shortened and suffixed with a hash of the full
+// key so that they stay unique and deterministic.
+func serverName(original *vmv1.Provision) string {
+	if original.Spec.Server.Name != "" {
+		return original.Spec.Server.Name
+	}
+	return ncpName(ownedServerNamePrefix, original.Namespace, original.Name)
+}
+
+// ncpName derives the NCP name of a resource owned by the object namespace/name.
+func ncpName(prefix, namespace, name string) string {
+	n := sanitizeServerName(prefix + namespace + "-" + name)
+	if len(n) > maxServerNameLength {
+		sum := sha256.Sum256([]byte(namespace + "/" + name))
+		n = strings.TrimRight(n[:maxServerNameLength-serverNameHashLen-1], "-") +
+			"-" + hex.EncodeToString(sum[:])[:serverNameHashLen]
+	}
+	return n
+}
+
+func sanitizeServerName(name string) string {
+	var b strings.Builder
+	for _, c := range strings.ToLower(name) {
+		if (c >= 'a' && c <= 'z') || (c >= '0' && c <= '9') {
+			b.WriteRune(c)
+		} else {
+			b.WriteRune('-')
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
+
+func serverDescription(original *vmv1.Provision) string {
+	if original.Spec.Server.Description != "" {
+		return original.Spec.Server.Description
+	}
+	return fmt.Sprintf("managed by aviator Provision %s/%s", original.Namespace, original.Name)
+}
+
+// ownershipTags returns the tags a Provision's server should carry: its owner
+// plus the Provision's labels. Label keys are sanitized into tag keys, and
+// those that would shadow an ownership tag are left out.
+func ownershipTags(clusterID string, original *vmv1.Provision) map[string]string {
+	tags := make(map[string]string, len(original.Labels)+4)
+	keys := make([]string, 0, len(original.Labels))
+	for k := range original.Labels {
+		keys = append(keys, k)
+	}
+	// sorted, so that the first of two labels sanitized alike always wins
+	sort.Strings(keys)
+	for _, k := range keys {
+		key := tagKey(k)
+		if _, ok := tags[key]; ok || key == "" || strings.HasPrefix(key, ownedServerNamePrefix) {
+			continue
+		}
+		tags[key] = truncate(original.Labels[k], maxTagValueLength)
+	}
+	if clusterID != "" {
+		tags[tagKeyCluster] = clusterID
+	}
+	tags[tagKeyNamespace] = original.Namespace
+	tags[tagKeyName] = original.Name
+	tags[tagKeyUID] = string(original.UID)
+	return tags
+}
+
+// tagKey turns a label key such as app.kubernetes.io/name into a tag key of
+// letters, digits, hyphens and underscores.
+func tagKey(label string) string {
+	var b strings.Builder
+	for _, c := range label {
+		if (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') || c == '-' || c == '_' {
+			b.WriteRune(c)
+		} else {
+			b.WriteRune('_')
+		}
+	}
+	return truncate(b.String(), maxTagKeyLength)
+}
+
+func truncate(s string, n int) string {
+	if len(s) > n {
+		return s[:n]
+	}
+	return s
+}
+
+// tagsHash identifies a set of tags independent of map order.
+func tagsHash(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for k := range tags {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	h := sha256.New()
+	for _, k := range keys {
+		fmt.Fprintf(h, "%s=%s\n", k, tags[k])
+	}
+	return hex.EncodeToString(h.Sum(nil))[:16]
+}
+
+// ensureServerTags brings the server's tags in line with ownershipTags,
+// replacing tags whose value changed. Tags set by others are left alone.
+// NCP is only read when the tags differ from those applied last, i.e. after
+// creation, adoption or a change of the Provision's labels.
+func ensureServerTags(ctx context.Context, r *ProvisionReconciler, log logr.Logger, original *vmv1.Provision) error {
+	desired := ownershipTags(r.ClusterID, original)
+	hash := tagsHash(desired)
+	if original.Status.ServerTagsHash == hash {
+		return nil
+	}
+	serverInstanceNo := original.Status.ServerInstanceNo
+	resp, err := r.ncpClient.GetInstanceTagList(ctx, original.Spec.RegionCode, serverInstanceNo)
+	if err != nil {
+		return err
+	}
+	current := make(map[string]string, len(resp.InstanceTagList))
+	for _, t := range resp.InstanceTagList {
+		current[t.TagKey] = t.TagValue
+	}
+
+	missing := map[string]string{}
+	var changed []string
+	for k, v := range desired {
+		if have, ok := current[k]; !ok {
+			missing[k] = v
+		} else if have != v {
+			missing[k] = v
+			changed = append(changed, k)
+		}
+	}
+	if len(missing) == 0 {
+		original.Status.ServerTagsHash = hash
+		return nil
+	}
+
+	instanceNos := []string{serverInstanceNo}
+	if len(changed) > 0 {
+		if err := r.ncpClient.DeleteInstanceTags(ctx, original.Spec.RegionCode, instanceNos, changed); err != nil {
+			return err
+		}
+	}
+	if err := r.ncpClient.CreateInstanceTags(ctx, original.Spec.RegionCode, instanceNos, missing); err != nil {
+		return err
+	}
+	original.Status.ServerTagsHash = hash
+	log.V(LogLevelInfo).Info("Tagged VM", "serverInstanceNo", serverInstanceNo, "tags", len(missing))
+	return nil
+}
+
+// foreignServer reports whether tags mark a server as owned by another
+// cluster. Servers of Provisions in this cluster are told apart by
+// serverOwner, which also lets a recreated Provision adopt its old server.
+func foreignServer(clusterID string, tags map[string]string) bool {
+	owner, ok := tags[tagKeyCluster]
+	return ok && owner != clusterID
+}
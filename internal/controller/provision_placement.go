@@ -0,0 +1,117 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+	"vm.cloudclub.io/internal/placement"
+	"vm.cloudclub.io/internal/policy"
+)
+
+// zoneSubnet returns the VPC and subnet a Provision's server is created in:
+// those of the spec or, with a placement, the candidate subnet it chooses.
+// Candidates must be in the VPC of the spec, if any; without one the VPC of
+// the chosen subnet is checked against the ProvisionPolicies instead.
+// The chosen zone and subnet are written to the status before the server is
+// created, so that a retried creation keeps them and the Provisions spreading
+// across the same zones, such as replicas created together, count it at once.
+func zoneSubnet(ctx context.Context, r *ProvisionReconciler, original *vmv1.Provision) (vpcNo, subnetNo string, err error) {
+	p := original.Spec.Placement
+	if p == nil {
+		return original.Spec.VpcNo, original.Spec.SubnetNo, nil
+	}
+
+	candidates := make([]placement.Subnet, 0, len(p.SubnetNos))
+	for _, no := range p.SubnetNos {
+		subnet, err := r.ncpClient.GetSubnetDetail(ctx, original.Spec.RegionCode, no)
+		if err != nil {
+			return "", "", err
+		}
+		if original.Spec.VpcNo != "" && subnet.VpcNo != original.Spec.VpcNo {
+			return "", "", &ncp.Error{Kind: ncp.ErrorKindInvalidArgument,
+				Message: fmt.Sprintf("subnet %s is in VPC %s, not %s", subnet.SubnetNo, subnet.VpcNo, original.Spec.VpcNo)}
+		}
+		candidates = append(candidates, placement.Subnet{SubnetNo: subnet.SubnetNo, VpcNo: subnet.VpcNo, ZoneCode: subnet.ZoneCode})
+	}
+	counts := map[string]int{}
+	if p.Strategy != vmv1.ZoneStrategyOrdered {
+		if counts, err = zoneCounts(ctx, r, original); err != nil {
+			return "", "", err
+		}
+	}
+	chosen, err := placement.Choose(candidates, p.Zones, p.Strategy, counts, original.Status.SubnetNo)
+	if err != nil {
+		return "", "", err
+	}
+	vpcNo = original.Spec.VpcNo
+	if vpcNo == "" {
+		vpcNo = chosen.VpcNo
+		resolved := original.DeepCopy()
+		resolved.Spec.VpcNo = vpcNo
+		if err := policy.Check(ctx, r, resolved, nil); err != nil {
+			return "", "", err
+		}
+	}
+	if original.Status.ZoneCode != chosen.ZoneCode || original.Status.SubnetNo != chosen.SubnetNo {
+		original.Status.ZoneCode = chosen.ZoneCode
+		original.Status.SubnetNo = chosen.SubnetNo
+		if err := r.Status().Update(ctx, original); err != nil {
+			return "", "", err
+		}
+	}
+	return vpcNo, chosen.SubnetNo, nil
+}
+
+// zoneCounts counts the zones chosen for the other Provisions a placement
+// spreads across, whether or not their servers exist yet: those of its spreadSelector or the other replicas of its
+// ProvisionSet. Without either nothing is counted.
+func zoneCounts(ctx context.Context, r *ProvisionReconciler, original *vmv1.Provision) (map[string]int, error) {
+	var selector labels.Selector
+	switch {
+	case original.Spec.Placement.SpreadSelector != nil:
+		s, err := metav1.LabelSelectorAsSelector(original.Spec.Placement.SpreadSelector)
+		if err != nil {
+			return nil, err
+		}
+		selector = s
+	case original.Labels[vmv1.ProvisionSetLabel] != "":
+		selector = labels.SelectorFromSet(labels.Set{vmv1.ProvisionSetLabel: original.Labels[vmv1.ProvisionSetLabel]})
+	default:
+		return map[string]int{}, nil
+	}
+
+	peers := &vmv1.ProvisionList{}
+	if err := r.List(ctx, peers, client.InNamespace(original.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	counts := map[string]int{}
+	for _, peer := range peers.Items {
+		if peer.UID == original.UID || peer.Status.ZoneCode == "" {
+			continue
+		}
+		counts[peer.Status.ZoneCode]++
+	}
+	return counts, nil
+}
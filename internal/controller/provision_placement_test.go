@@ -0,0 +1,91 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+	"vm.cloudclub.io/internal/policy"
+)
+
+var _ = Describe("Choosing the subnet of a Provision with a placement", func() {
+	var (
+		ctx       context.Context
+		fakeAPI   *fakeNCP
+		provision *vmv1.Provision
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		fakeAPI = newFakeNCP()
+		fakeAPI.answer("getSubnetDetail", "<getSubnetDetailResponse><returnCode>0</returnCode><subnetList><subnet>"+
+			"<subnetNo>31</subnetNo><vpcNo>2</vpcNo><zoneCode>KR-1</zoneCode>"+
+			"</subnet></subnetList></getSubnetDetailResponse>")
+		provision = &vmv1.Provision{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-1"},
+			Spec: vmv1.ProvisionSpec{RegionCode: "KR",
+				Placement: &vmv1.ZonePlacement{SubnetNos: []string{"31"}, Strategy: vmv1.ZoneStrategyOrdered}},
+		}
+	})
+
+	zoneSubnetOf := func(objects ...client.Object) (string, string, error) {
+		c := newFakeClient(append(objects, provision)...)
+		r := newTestProvisionReconciler(c, fakeAPI, &fakeServerService{})
+		return zoneSubnet(ctx, r, provision)
+	}
+
+	It("uses the VPC of the chosen subnet without one in the spec", func() {
+		vpcNo, subnetNo, err := zoneSubnetOf()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(vpcNo).To(Equal("2"))
+		Expect(subnetNo).To(Equal("31"))
+		Expect(provision.Status.ZoneCode).To(Equal("KR-1"))
+	})
+
+	It("checks the VPC of the chosen subnet against the policies", func() {
+		namespace := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}}
+		denyVPC := &vmv1.ProvisionPolicy{
+			ObjectMeta: metav1.ObjectMeta{Name: "vpcs"},
+			Spec: vmv1.ProvisionPolicySpec{Rules: []vmv1.ProvisionPolicyRule{
+				{Name: "no-2", Field: vmv1.ProvisionPolicyFieldVpcNo, Deny: []string{"2"}},
+			}},
+		}
+		_, _, err := zoneSubnetOf(namespace, denyVPC)
+		var violation *policy.ViolationError
+		Expect(errors.As(err, &violation)).To(BeTrue())
+		Expect(violation.Value).To(Equal("2"))
+		Expect(provision.Status.ZoneCode).To(BeEmpty())
+	})
+
+	It("rejects candidate subnets outside the VPC of the spec", func() {
+		provision.Spec.VpcNo = "1"
+		_, _, err := zoneSubnetOf()
+		Expect(err).To(HaveOccurred())
+		Expect(ncp.AsError(err).Kind).To(Equal(ncp.ErrorKindInvalidArgument))
+		Expect(ncp.AsError(err).Terminal()).To(BeTrue())
+	})
+})
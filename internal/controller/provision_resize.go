@@ -0,0 +1,280 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-logr/logr"
+	"go.opentelemetry.io/otel/attribute"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	ncputil "github.com/cloud-club/Aviator-service/pkg"
+	server "github.com/cloud-club/Aviator-service/types/server"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+	"vm.cloudclub.io/internal/policy"
+	"vm.cloudclub.io/internal/powerschedule"
+	"vm.cloudclub.io/internal/resizeplan"
+)
+
+// preStopHookRequest is the body POSTed to a pre-stop hook.
+type preStopHookRequest struct {
+	Namespace        string `json:"namespace"`
+	Name             string `json:"name"`
+	ServerInstanceNo string `json:"serverInstanceNo"`
+	FromProductCode  string `json:"fromProductCode"`
+	ToProductCode    string `json:"toProductCode"`
+}
+
+// preStopHookClient calls pre-stop hooks. It does not follow redirects, so a
+// hook cannot send the operator's requests on to another address.
+var preStopHookClient = &http.Client{
+	Timeout: preStopHookMaxTimeout,
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// resize applies a change of server.serverProductCode, which NCP only allows
+// on a stopped server: once the maintenance window is open it calls the
+// pre-stop hook, stops the server, changes its product and starts it again
+// if it was running. The resize in progress is kept in status, and each
+// call advances it as far as the server's current state allows.
+func resize(ctx context.Context, r *ProvisionReconciler, log logr.Logger, original *vmv1.Provision) (err error) {
+	ctx, span := tracer.Start(ctx, "Provision.resize")
+	defer func() { endSpan(span, err) }()
+
+	serverInstanceNo := managedServerInstanceNo(original)
+	if serverInstanceNo == "" {
+		log.V(LogLevelInfo).Info("No VM to resize")
+		return nil
+	}
+	span.SetAttributes(attribute.String("ncp.server_instance_no", serverInstanceNo))
+	instance, err := r.ncpClient.GetServerInstanceDetail(ctx, original.Spec.RegionCode, serverInstanceNo)
+	if err != nil {
+		return err
+	}
+	original.Status.ServerStatus = instance.ServerInstanceStatus.Code
+
+	// Only the product is changed on update, so policies are checked against
+	// the server's current product rather than every field of the spec.
+	applied := original.DeepCopy()
+	applied.Spec.Server.ProductCode = instance.ServerProductCode
+	if err := policy.Check(ctx, r, original, applied); err != nil {
+		return err
+	}
+
+	if status := original.Status.Resize; status == nil || status.Step == vmv1.ResizeStepWaitingForWindow {
+		if !beginResize(ctx, r, log, original, instance) {
+			return nil
+		}
+	}
+	original.Status.Resize.ToProductCode = original.Spec.Server.ProductCode
+	for {
+		advanced, err := resizeStep(ctx, r, log, original, instance)
+		if err != nil || !advanced {
+			return err
+		}
+	}
+}
+
+// beginResize starts a resize when the product differs from the spec and the
+// maintenance window is open, and otherwise records that it waits for it.
+func beginResize(ctx context.Context, r *ProvisionReconciler, log logr.Logger, original *vmv1.Provision, instance *ncp.ServerInstance) bool {
+	want := original.Spec.Server.ProductCode
+	if want == "" || want == instance.ServerProductCode {
+		original.Status.Resize = nil
+		return false
+	}
+	if !instance.Stable() {
+		log.V(LogLevelDebug).Info("Waiting for VM to settle before resizing",
+			"status", instance.ServerInstanceStatus.Code, "operation", instance.ServerInstanceOperation.Code)
+		return false
+	}
+
+	now := time.Now()
+	if window := resizePolicy(original).MaintenanceWindow; window != nil {
+		parsed, err := powerschedule.ParseWindow(window)
+		if err != nil {
+			log.Error(err, "Not resizing with an invalid maintenance window")
+			r.event(ctx, original, corev1.EventTypeWarning, eventReasonInvalidWindow, "%s", err.Error())
+			return false
+		}
+		if open, next := parsed.Open(now); !open {
+			if original.Status.Resize == nil {
+				log.V(LogLevelInfo).Info("Waiting for maintenance window to resize VM", "opens", next)
+				r.event(ctx, original, corev1.EventTypeNormal, eventReasonResizeWaiting,
+					"Resizing to %s when the maintenance window opens at %s", want, next.Format(time.RFC3339))
+			}
+			original.Status.Resize = &vmv1.ResizeStatus{
+				Step:            vmv1.ResizeStepWaitingForWindow,
+				FromProductCode: instance.ServerProductCode,
+				ToProductCode:   want,
+				StartTime:       metav1.NewTime(now),
+				NextWindowTime:  &metav1.Time{Time: next},
+			}
+			return false
+		}
+	}
+
+	original.Status.Resize = &vmv1.ResizeStatus{
+		Step:            vmv1.ResizeStepPreStop,
+		FromProductCode: instance.ServerProductCode,
+		ToProductCode:   want,
+		Restart:         instance.ServerInstanceStatus.Code == ncp.ServerStatusRunning,
+		StartTime:       metav1.NewTime(now),
+	}
+	log.V(LogLevelInfo).Info("Resizing VM", "serverInstanceNo", instance.ServerInstanceNo,
+		"from", instance.ServerProductCode, "to", want)
+	r.event(ctx, original, corev1.EventTypeNormal, eventReasonResizeStarted,
+		"Resizing server %s from %s to %s", instance.ServerInstanceNo, instance.ServerProductCode, want)
+	return true
+}
+
+// resizeStep acts on the current step and reports whether it moved on to a
+// step that can act on the same server state.
+func resizeStep(ctx context.Context, r *ProvisionReconciler, log logr.Logger, original *vmv1.Provision, instance *ncp.ServerInstance) (bool, error) {
+	status := original.Status.Resize
+	serverInstanceNo := instance.ServerInstanceNo
+	step, err := resizeplan.Next(status, instance)
+	if err != nil {
+		return false, err
+	}
+	if step.Action == resizeplan.ActionNone && !step.Continue {
+		log.V(LogLevelDebug).Info("Waiting for VM during resize", "step", status.Step,
+			"status", instance.ServerInstanceStatus.Code, "operation", instance.ServerInstanceOperation.Code)
+		return false, nil
+	}
+	defer r.serverCache.Invalidate(original.Spec.RegionCode)
+
+	switch step.Action {
+	case resizeplan.ActionCallPreStopHook:
+		if hook := resizePolicy(original).PreStopHook; hook != nil {
+			if err := callPreStopHook(ctx, original, instance, hook); err != nil {
+				if !hook.IgnoreFailure {
+					return false, err
+				}
+				log.Error(err, "Ignoring failed pre-stop hook")
+				r.event(ctx, original, corev1.EventTypeWarning, eventReasonPreStopHookFailed, "%s", err.Error())
+			}
+		}
+
+	case resizeplan.ActionStop:
+		resp, err := ncp.WithContext(ctx, r.ncpService.Server).Stop(ncputil.API_URL+ncputil.STOP_SERVER_INSTANCE_PATH,
+			&server.StopServerRequest{ServerNo: serverInstanceNo})
+		logAPIPayload(log, "stopServerInstances response", resp)
+		if err != nil {
+			return false, err
+		}
+		r.event(ctx, original, corev1.EventTypeNormal, eventReasonStopRequested,
+			"Requested stop of server %s to resize it", serverInstanceNo)
+
+	case resizeplan.ActionChangeSpec:
+		if err := r.ncpClient.ChangeServerInstanceSpec(ctx, original.Spec.RegionCode, serverInstanceNo, status.ToProductCode); err != nil {
+			return false, err
+		}
+		r.event(ctx, original, corev1.EventTypeNormal, eventReasonSpecChangeRequested,
+			"Requested change of server %s to product %s", serverInstanceNo, status.ToProductCode)
+
+	case resizeplan.ActionStart:
+		resp, err := ncp.WithContext(ctx, r.ncpService.Server).Start(ncputil.API_URL+ncputil.START_SERVER_INSTANCE_PATH,
+			&server.StartServerRequest{ServerNo: serverInstanceNo})
+		logAPIPayload(log, "startServerInstances response", resp)
+		if err != nil {
+			return false, err
+		}
+		r.event(ctx, original, corev1.EventTypeNormal, eventReasonStartRequested,
+			"Requested start of server %s after resizing it", serverInstanceNo)
+
+	case resizeplan.ActionFinish:
+		finishResize(ctx, r, log, original)
+		return false, nil
+	}
+	status.Step = step.Next
+	return step.Continue, nil
+}
+
+func finishResize(ctx context.Context, r *ProvisionReconciler, log logr.Logger, original *vmv1.Provision) {
+	status := original.Status.Resize
+	log.V(LogLevelInfo).Info("Resized VM", "from", status.FromProductCode, "to", status.ToProductCode,
+		"duration", time.Since(status.StartTime.Time))
+	r.event(ctx, original, corev1.EventTypeNormal, eventReasonResizeCompleted,
+		"Resized server from %s to %s", status.FromProductCode, status.ToProductCode)
+	original.Status.Resize = nil
+}
+
+// callPreStopHook POSTs the resize to the hook and fails unless it answers
+// 2xx. A redirect is not followed and fails the call.
+func callPreStopHook(ctx context.Context, original *vmv1.Provision, instance *ncp.ServerInstance, hook *vmv1.PreStopHook) error {
+	body, err := json.Marshal(&preStopHookRequest{
+		Namespace:        original.Namespace,
+		Name:             original.Name,
+		ServerInstanceNo: instance.ServerInstanceNo,
+		FromProductCode:  original.Status.Resize.FromProductCode,
+		ToProductCode:    original.Status.Resize.ToProductCode,
+	})
+	if err != nil {
+		return err
+	}
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(hook.TimeoutSeconds)*time.Second)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, hook.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := preStopHookClient.Do(req)
+	if err != nil {
+		return &preStopHookError{url: hook.URL, err: err}
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &preStopHookError{url: hook.URL, err: fmt.Errorf("answered %s", resp.Status)}
+	}
+	return nil
+}
+
+func resizePolicy(original *vmv1.Provision) *vmv1.ResizePolicy {
+	if original.Spec.Resize == nil {
+		return &vmv1.ResizePolicy{}
+	}
+	return original.Spec.Resize
+}
+
+// untilResizeCheck returns when a resize in progress should be checked next.
+// Resizes only progress in the Update phase.
+func untilResizeCheck(original *vmv1.Provision) time.Duration {
+	status := original.Status.Resize
+	switch {
+	case status == nil || original.Spec.Phase != vmv1.ProvisionPhaseUpdate:
+		return 0
+	case status.Step == vmv1.ResizeStepWaitingForWindow && status.NextWindowTime != nil:
+		if until := time.Until(status.NextWindowTime.Time); until > minScheduleRequeue {
+			return until
+		}
+		return minScheduleRequeue
+	}
+	return resizeRequeueInterval
+}
@@ -0,0 +1,58 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+var _ = Describe("Calling a pre-stop hook", func() {
+	var (
+		provision *vmv1.Provision
+		instance  *ncp.ServerInstance
+		followed  int
+		hook      *httptest.Server
+	)
+
+	BeforeEach(func() {
+		provision = &vmv1.Provision{Status: vmv1.ProvisionStatus{Resize: &vmv1.ResizeStatus{FromProductCode: "a", ToProductCode: "b"}}}
+		instance = &ncp.ServerInstance{ServerInstanceNo: "111"}
+		followed = 0
+		hook = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/moved" {
+				followed++
+				return
+			}
+			http.Redirect(w, req, "/moved", http.StatusTemporaryRedirect)
+		}))
+		DeferCleanup(hook.Close)
+	})
+
+	It("fails on a redirect instead of following it", func() {
+		err := callPreStopHook(context.Background(), provision, instance, &vmv1.PreStopHook{URL: hook.URL, TimeoutSeconds: 5})
+		Expect(err).To(MatchError(ContainSubstring("307")))
+		Expect(followed).To(BeZero())
+	})
+})
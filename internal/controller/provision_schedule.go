@@ -0,0 +1,110 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+
+	ncputil "github.com/cloud-club/Aviator-service/pkg"
+	server "github.com/cloud-club/Aviator-service/types/server"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+	"vm.cloudclub.io/internal/powerschedule"
+)
+
+// minScheduleRequeue keeps a transition due now from requeueing in a tight loop.
+const minScheduleRequeue = time.Second
+
+// powerScheduleApplies reports whether the power schedule decides the power
+// state: the Stop phase and transient phases take precedence over it.
+func powerScheduleApplies(original *vmv1.Provision) bool {
+	if original.Spec.PowerSchedule == nil || managedServerInstanceNo(original) == "" {
+		return false
+	}
+	switch original.Spec.Phase {
+	case "", vmv1.ProvisionPhaseCreate, vmv1.ProvisionPhaseGet:
+		return true
+	}
+	return false
+}
+
+// applyPowerSchedule starts or stops the server when its status, as last
+// read by resync, differs from what the schedule wants, and records the next
+// transition in status. An invalid schedule is reported in an event and
+// ignored until the spec changes.
+func applyPowerSchedule(ctx context.Context, r *ProvisionReconciler, log logr.Logger, original *vmv1.Provision) error {
+	if !powerScheduleApplies(original) {
+		original.Status.NextPowerTransition = nil
+		return nil
+	}
+	schedule, err := powerschedule.Parse(original.Spec.PowerSchedule)
+	if err != nil {
+		log.Error(err, "Ignoring invalid power schedule")
+		r.event(ctx, original, corev1.EventTypeWarning, eventReasonInvalidPowerSchedule, "%s", err.Error())
+		original.Status.NextPowerTransition = nil
+		return nil
+	}
+	running, next := schedule.At(time.Now())
+	if previous := original.Status.NextPowerTransition; previous == nil ||
+		previous.Action != next.Action || !previous.Time.Equal(&next.Time) {
+		original.Status.NextPowerTransition = &next
+	}
+
+	serverInstanceNo := managedServerInstanceNo(original)
+	switch {
+	case running && original.Status.ServerStatus == ncp.ServerStatusStopped:
+		log.V(LogLevelInfo).Info("Starting VM on schedule", "serverInstanceNo", serverInstanceNo)
+		resp, err := ncp.WithContext(ctx, r.ncpService.Server).Start(ncputil.API_URL+ncputil.START_SERVER_INSTANCE_PATH,
+			&server.StartServerRequest{ServerNo: serverInstanceNo})
+		logAPIPayload(log, "startServerInstances response", resp)
+		r.serverCache.Invalidate(original.Spec.RegionCode)
+		if err != nil {
+			return err
+		}
+		r.event(ctx, original, corev1.EventTypeNormal, eventReasonScheduledStart,
+			"Requested start of server %s on schedule", serverInstanceNo)
+	case !running && original.Status.ServerStatus == ncp.ServerStatusRunning:
+		log.V(LogLevelInfo).Info("Stopping VM on schedule", "serverInstanceNo", serverInstanceNo)
+		resp, err := ncp.WithContext(ctx, r.ncpService.Server).Stop(ncputil.API_URL+ncputil.STOP_SERVER_INSTANCE_PATH,
+			&server.StopServerRequest{ServerNo: serverInstanceNo})
+		logAPIPayload(log, "stopServerInstances response", resp)
+		r.serverCache.Invalidate(original.Spec.RegionCode)
+		if err != nil {
+			return err
+		}
+		r.event(ctx, original, corev1.EventTypeNormal, eventReasonScheduledStop,
+			"Requested stop of server %s on schedule", serverInstanceNo)
+	}
+	return nil
+}
+
+// untilPowerTransition returns how long until the next scheduled transition, or 0 without one.
+func untilPowerTransition(original *vmv1.Provision) time.Duration {
+	next := original.Status.NextPowerTransition
+	if next == nil {
+		return 0
+	}
+	if until := time.Until(next.Time.Time); until > minScheduleRequeue {
+		return until
+	}
+	return minScheduleRequeue
+}
@@ -0,0 +1,103 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	ncputil "github.com/cloud-club/Aviator-service/pkg"
+	server "github.com/cloud-club/Aviator-service/types/server"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+// provisionFinalizer keeps a Provision until its server is terminated. The
+// ProvisionSet controller adds it to its replicas, so scaling a set down or
+// deleting it does not leave their servers behind.
+const provisionFinalizer = "vm.cloudclub.io/provision"
+
+// finalize terminates the server of a Provision being deleted, stopping it
+// first as NCP only terminates stopped servers, and removes
+// provisionFinalizer once the server is gone. Nothing else is reconciled.
+// Failed calls are retried with backoff rather than recorded as Failed, as
+// with the finalizers of other NCP resources.
+func (r *ProvisionReconciler) finalize(ctx context.Context, log logr.Logger, original *vmv1.Provision) (ctrl.Result, error) {
+	if !controllerutil.ContainsFinalizer(original, provisionFinalizer) {
+		return ctrl.Result{}, nil
+	}
+	if serverInstanceNo := managedServerInstanceNo(original); serverInstanceNo != "" {
+		instance, err := r.serverCache.Get(ctx, original.Spec.RegionCode, serverInstanceNo)
+		if err == nil {
+			return r.terminate(ctx, log, original, instance)
+		}
+		if ncp.AsError(err).Kind != ncp.ErrorKindNotFound {
+			log.Error(err, "Failed to get VM of deleted Provision")
+			return resourceError(ctx, r.Recorder, original, nil, err)
+		}
+	}
+
+	log.V(LogLevelInfo).Info("Server gone, releasing deleted Provision")
+	controllerutil.RemoveFinalizer(original, provisionFinalizer)
+	if err := r.Update(ctx, original); err != nil {
+		log.Error(err, "Failed to remove Provision finalizer")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// terminate moves the server of a deleted Provision one step towards
+// termination and requeues until it is gone.
+func (r *ProvisionReconciler) terminate(ctx context.Context, log logr.Logger, original *vmv1.Provision, instance *ncp.ServerInstance) (ctrl.Result, error) {
+	serverInstanceNo := instance.ServerInstanceNo
+	result := ctrl.Result{RequeueAfter: terminationRequeueInterval}
+	switch {
+	case !instance.Stable():
+		log.V(LogLevelDebug).Info("Waiting for VM of deleted Provision", "serverInstanceNo", serverInstanceNo,
+			"status", instance.ServerInstanceStatus.Code)
+	case instance.ServerInstanceStatus.Code == ncp.ServerStatusRunning:
+		log.V(LogLevelInfo).Info("Stopping VM of deleted Provision", "serverInstanceNo", serverInstanceNo)
+		resp, err := ncp.WithContext(ctx, r.ncpService.Server).Stop(ncputil.API_URL+ncputil.STOP_SERVER_INSTANCE_PATH,
+			&server.StopServerRequest{ServerNo: serverInstanceNo})
+		logAPIPayload(log, "stopServerInstances response", resp)
+		r.serverCache.Invalidate(original.Spec.RegionCode)
+		if err != nil {
+			log.Error(err, "Failed to stop VM of deleted Provision")
+			return resourceError(ctx, r.Recorder, original, nil, err)
+		}
+		r.event(ctx, original, corev1.EventTypeNormal, eventReasonStopRequested,
+			"Provision deleted, requested stop of server %s", serverInstanceNo)
+	default:
+		log.V(LogLevelInfo).Info("Terminating VM of deleted Provision", "serverInstanceNo", serverInstanceNo)
+		resp, err := ncp.WithContext(ctx, r.ncpService.Server).Delete(ncputil.API_URL+ncputil.DELETE_SERVER_INSTANCE_PATH,
+			&server.DeleteServerRequest{ServerNo: serverInstanceNo})
+		logAPIPayload(log, "terminateServerInstances response", resp)
+		r.serverCache.Invalidate(original.Spec.RegionCode)
+		if err != nil {
+			log.Error(err, "Failed to terminate VM of deleted Provision")
+			return resourceError(ctx, r.Recorder, original, nil, err)
+		}
+		r.event(ctx, original, corev1.EventTypeNormal, eventReasonTerminationRequested,
+			"Provision deleted, requested termination of server %s", serverInstanceNo)
+	}
+	return result, nil
+}
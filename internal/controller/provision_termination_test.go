@@ -0,0 +1,90 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+var _ = Describe("Deleting a Provision with provisionFinalizer", func() {
+	var (
+		ctx        context.Context
+		key        types.NamespacedName
+		c          client.Client
+		fakeAPI    *fakeNCP
+		servers    *fakeServerService
+		reconciler *ProvisionReconciler
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		key = types.NamespacedName{Namespace: "default", Name: "web-1"}
+		now := metav1.Now()
+		provision := &vmv1.Provision{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name,
+				Finalizers: []string{provisionFinalizer}, DeletionTimestamp: &now},
+			Spec:   vmv1.ProvisionSpec{RegionCode: "KR"},
+			Status: vmv1.ProvisionStatus{ServerInstanceNo: "111"},
+		}
+		c = newFakeClient(provision)
+		fakeAPI = newFakeNCP()
+		servers = &fakeServerService{}
+		reconciler = newTestProvisionReconciler(c, fakeAPI, servers)
+	})
+
+	It("releases the Provision once its server is gone", func() {
+		result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result).To(Equal(ctrl.Result{}))
+		Expect(fakeAPI.called()).To(ContainElement("getServerInstanceDetail"))
+		Expect(apierrors.IsNotFound(c.Get(ctx, key, &vmv1.Provision{}))).To(BeTrue())
+	})
+
+	It("stops a running server first", func() {
+		fakeAPI.answer("getServerInstanceList", serverList("111", ncp.ServerStatusRunning))
+
+		result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(terminationRequeueInterval))
+		Expect(servers.stopped).To(Equal([]string{"111"}))
+		Expect(servers.deleted).To(BeEmpty())
+		Expect(c.Get(ctx, key, &vmv1.Provision{})).To(Succeed())
+	})
+
+	It("terminates a stopped server", func() {
+		fakeAPI.answer("getServerInstanceList", serverList("111", ncp.ServerStatusStopped))
+
+		result, err := reconciler.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(result.RequeueAfter).To(Equal(terminationRequeueInterval))
+		Expect(servers.stopped).To(BeEmpty())
+		Expect(servers.deleted).To(Equal([]string{"111"}))
+		Expect(c.Get(ctx, key, &vmv1.Provision{})).To(Succeed())
+	})
+})
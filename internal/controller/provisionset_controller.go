@@ -0,0 +1,250 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+// ProvisionSetReconciler keeps the replicas of a ProvisionSet and the
+// PlacementGroup they are spread across.
+type ProvisionSetReconciler struct {
+	client.Client
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+}
+
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=provisionsets,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=provisionsets/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=provisionsets/scale,verbs=get;update;patch
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=provisions,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=placementgroups,verbs=get;list;watch;create;update;patch;delete
+
+// Reconcile creates missing replicas, deletes those beyond spec.replicas and
+// reports how many are ready. Deleting the set deletes its replicas and
+// placement group through their owner references. Replicas carry
+// provisionFinalizer, so their servers are terminated before they, and then
+// the placement group, are gone.
+func (r *ProvisionSetReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	set := &vmv1.ProvisionSet{}
+	if err := r.Get(ctx, req.NamespacedName, set); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get ProvisionSet resource")
+		return ctrl.Result{}, err
+	}
+	if !set.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, nil
+	}
+
+	group, err := r.placementGroup(ctx, set)
+	if err != nil {
+		log.Error(err, "Failed to create placement group")
+		return ctrl.Result{}, err
+	}
+	replicas, err := r.replicas(ctx, set)
+	if err != nil {
+		log.Error(err, "Failed to list replicas")
+		return ctrl.Result{}, err
+	}
+
+	var current, ready int32
+	for index := int32(0); index < set.Spec.Replicas; index++ {
+		provision, ok := replicas[index]
+		if !ok {
+			provision, err = r.createReplica(ctx, set, index, group)
+			if err != nil {
+				log.Error(err, "Failed to create replica", "index", index)
+				return ctrl.Result{}, err
+			}
+			log.V(LogLevelInfo).Info("Replica created", "provision", provision.Name)
+			recordEvent(ctx, r.Recorder, set, corev1.EventTypeNormal, eventReasonReplicaCreated, "Created Provision %s", provision.Name)
+		} else if err := r.addFinalizer(ctx, provision); err != nil {
+			log.Error(err, "Failed to add finalizer to replica", "provision", provision.Name)
+			return ctrl.Result{}, err
+		}
+		delete(replicas, index)
+		current++
+		if provision.Status.ServerStatus == ncp.ServerStatusRunning {
+			ready++
+		}
+	}
+	// what is left is beyond spec.replicas, highest indexes first
+	excess := make([]int32, 0, len(replicas))
+	for index := range replicas {
+		excess = append(excess, index)
+	}
+	sort.Slice(excess, func(i, j int) bool { return excess[i] > excess[j] })
+	for _, index := range excess {
+		provision := replicas[index]
+		if !provision.DeletionTimestamp.IsZero() {
+			continue
+		}
+		if err := r.addFinalizer(ctx, provision); err != nil {
+			log.Error(err, "Failed to add finalizer to replica", "provision", provision.Name)
+			return ctrl.Result{}, err
+		}
+		if err := r.Delete(ctx, provision); client.IgnoreNotFound(err) != nil {
+			log.Error(err, "Failed to delete replica", "provision", provision.Name)
+			return ctrl.Result{}, err
+		}
+		log.V(LogLevelInfo).Info("Replica deleted", "provision", provision.Name)
+		recordEvent(ctx, r.Recorder, set, corev1.EventTypeNormal, eventReasonReplicaDeleted, "Deleted Provision %s", provision.Name)
+	}
+
+	if set.Status.Replicas != current || set.Status.ReadyReplicas != ready || set.Status.PlacementGroupName != group {
+		set.Status.Replicas = current
+		set.Status.ReadyReplicas = ready
+		set.Status.PlacementGroupName = group
+		if err := r.Status().Update(ctx, set); err != nil {
+			log.Error(err, "Failed to update ProvisionSet status")
+			return ctrl.Result{}, err
+		}
+	}
+	return ctrl.Result{}, nil
+}
+
+// placementGroup returns the PlacementGroup the replicas join: the set's
+// placementGroupRef or, unless the template places them itself or spreading
+// is turned off, a PlacementGroup named after the set that it owns.
+func (r *ProvisionSetReconciler) placementGroup(ctx context.Context, set *vmv1.ProvisionSet) (string, error) {
+	if set.Spec.PlacementGroupRef != "" {
+		return set.Spec.PlacementGroupRef, nil
+	}
+	template := &set.Spec.Template.Spec
+	if template.PlacementGroupRef != "" || template.PlacementGroupNo != "" {
+		return "", nil
+	}
+	if set.Spec.SpreadReplicas != nil && !*set.Spec.SpreadReplicas {
+		return "", nil
+	}
+
+	group := &vmv1.PlacementGroup{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      set.Name,
+			Namespace: set.Namespace,
+			Labels:    map[string]string{vmv1.ProvisionSetLabel: set.Name},
+		},
+		Spec: vmv1.PlacementGroupSpec{
+			RegionCode:         template.RegionCode,
+			PlacementGroupType: vmv1.PlacementGroupTypeAntiAffinity,
+		},
+	}
+	if err := controllerutil.SetControllerReference(set, group, r.Scheme); err != nil {
+		return "", err
+	}
+	if err := r.Create(ctx, group); err != nil && !errors.IsAlreadyExists(err) {
+		return "", err
+	}
+	return group.Name, nil
+}
+
+// replicas returns the Provisions the set controls by their index.
+func (r *ProvisionSetReconciler) replicas(ctx context.Context, set *vmv1.ProvisionSet) (map[int32]*vmv1.Provision, error) {
+	list := &vmv1.ProvisionList{}
+	if err := r.List(ctx, list, client.InNamespace(set.Namespace),
+		client.MatchingLabels{vmv1.ProvisionSetLabel: set.Name}); err != nil {
+		return nil, err
+	}
+	replicas := make(map[int32]*vmv1.Provision, len(list.Items))
+	for i := range list.Items {
+		provision := &list.Items[i]
+		if !metav1.IsControlledBy(provision, set) {
+			continue
+		}
+		index, err := strconv.ParseInt(strings.TrimPrefix(provision.Name, set.Name+"-"), 10, 32)
+		if err != nil {
+			continue
+		}
+		replicas[int32(index)] = provision
+	}
+	return replicas, nil
+}
+
+// createReplica creates the Provision of one index from the template. An
+// explicit server name in the template gets the index appended to stay unique.
+func (r *ProvisionSetReconciler) createReplica(ctx context.Context, set *vmv1.ProvisionSet, index int32, group string) (*vmv1.Provision, error) {
+	template := set.Spec.Template
+	labels := make(map[string]string, len(template.Labels)+1)
+	for k, v := range template.Labels {
+		labels[k] = v
+	}
+	labels[vmv1.ProvisionSetLabel] = set.Name
+
+	provision := &vmv1.Provision{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        fmt.Sprintf("%s-%d", set.Name, index),
+			Namespace:   set.Namespace,
+			Labels:      labels,
+			Annotations: template.Annotations,
+			Finalizers:  []string{provisionFinalizer},
+		},
+		Spec: *template.Spec.DeepCopy(),
+	}
+	if provision.Spec.Server.Name != "" {
+		provision.Spec.Server.Name = fmt.Sprintf("%s-%d", provision.Spec.Server.Name, index)
+	}
+	if group != "" {
+		provision.Spec.PlacementGroupRef = group
+	}
+	if err := controllerutil.SetControllerReference(set, provision, r.Scheme); err != nil {
+		return nil, err
+	}
+	if err := r.Create(ctx, provision); err != nil {
+		return nil, err
+	}
+	return provision, nil
+}
+
+// addFinalizer adds provisionFinalizer to a replica created before replicas
+// carried it, so that deleting it terminates its server.
+func (r *ProvisionSetReconciler) addFinalizer(ctx context.Context, provision *vmv1.Provision) error {
+	if !provision.DeletionTimestamp.IsZero() || !controllerutil.AddFinalizer(provision, provisionFinalizer) {
+		return nil
+	}
+	return r.Update(ctx, provision)
+}
+
+// SetupWithManager sets up the controller with the Manager. Owning the
+// replicas keeps the ready count current and replaces deleted ones.
+func (r *ProvisionSetReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&vmv1.ProvisionSet{}).
+		Owns(&vmv1.Provision{}).
+		Owns(&vmv1.PlacementGroup{}).
+		Complete(traced("ProvisionSet", r))
+}
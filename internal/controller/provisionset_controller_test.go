@@ -0,0 +1,88 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+var _ = Describe("Scaling a ProvisionSet down", func() {
+	It("deletes the replicas beyond spec.replicas through provisionFinalizer", func() {
+		ctx := context.Background()
+		set := &vmv1.ProvisionSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "set-uid"},
+			Spec: vmv1.ProvisionSetSpec{Replicas: 1,
+				Template: vmv1.ProvisionTemplate{Spec: vmv1.ProvisionSpec{RegionCode: "KR"}}},
+		}
+		c := newFakeClient(set)
+		replica := func(name string, finalizers ...string) client.Object {
+			provision := &vmv1.Provision{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name,
+				Labels: map[string]string{vmv1.ProvisionSetLabel: "web"}, Finalizers: finalizers}}
+			Expect(controllerutil.SetControllerReference(set, provision, c.Scheme())).To(Succeed())
+			return provision
+		}
+		// web-1 was created before replicas carried the finalizer
+		Expect(c.Create(ctx, replica("web-0", provisionFinalizer))).To(Succeed())
+		Expect(c.Create(ctx, replica("web-1"))).To(Succeed())
+
+		r := &ProvisionSetReconciler{Client: c, Scheme: c.Scheme(), Recorder: record.NewFakeRecorder(100)}
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		excess := &vmv1.Provision{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "web-1"}, excess)).To(Succeed())
+		Expect(excess.DeletionTimestamp.IsZero()).To(BeFalse())
+		Expect(excess.Finalizers).To(ConsistOf(provisionFinalizer))
+
+		kept := &vmv1.Provision{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "web-0"}, kept)).To(Succeed())
+		Expect(kept.DeletionTimestamp.IsZero()).To(BeTrue())
+
+		updated := &vmv1.ProvisionSet{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "web"}, updated)).To(Succeed())
+		Expect(updated.Status.Replicas).To(BeEquivalentTo(1))
+	})
+
+	It("creates replicas carrying provisionFinalizer", func() {
+		ctx := context.Background()
+		c := newFakeClient(&vmv1.ProvisionSet{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web", UID: "set-uid"},
+			Spec: vmv1.ProvisionSetSpec{Replicas: 1,
+				Template: vmv1.ProvisionTemplate{Spec: vmv1.ProvisionSpec{RegionCode: "KR"}}},
+		})
+
+		r := &ProvisionSetReconciler{Client: c, Scheme: c.Scheme(), Recorder: record.NewFakeRecorder(100)}
+		_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "web"}})
+		Expect(err).NotTo(HaveOccurred())
+
+		created := &vmv1.Provision{}
+		Expect(c.Get(ctx, types.NamespacedName{Namespace: "default", Name: "web-0"}, created)).To(Succeed())
+		Expect(created.Finalizers).To(ConsistOf(provisionFinalizer))
+	})
+})
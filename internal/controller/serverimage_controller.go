@@ -0,0 +1,206 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+// serverImageFinalizer keeps a ServerImage until its NCP image is deleted.
+const serverImageFinalizer = "vm.cloudclub.io/server-image"
+
+// serverImageNamePrefix starts the NCP names derived for ServerImages.
+const serverImageNamePrefix = "aviator-img-"
+
+// ServerImageReconciler creates NCP member server images from the servers of
+// Provisions and deletes them with their ServerImage.
+type ServerImageReconciler struct {
+	client.Client
+	Scheme    *runtime.Scheme
+	Recorder  record.EventRecorder
+	ncpClient *ncp.Client
+}
+
+func NewServerImageReconciler(client client.Client, scheme *runtime.Scheme, recorder record.EventRecorder, ncpClient *ncp.Client) *ServerImageReconciler {
+	return &ServerImageReconciler{
+		Client:    client,
+		Scheme:    scheme,
+		Recorder:  recorder,
+		ncpClient: ncpClient,
+	}
+}
+
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=serverimages,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=serverimages/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=serverimages/finalizers,verbs=update
+
+// Reconcile creates the image of a ServerImage once its Provision has a
+// server and follows it until it is available.
+func (r *ServerImageReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return reconcileNCPResource(ctx, r.Client, r.Recorder, req, &vmv1.ServerImage{}, ncpResource[*vmv1.ServerImage]{
+		kind:       "ServerImage",
+		finalizer:  serverImageFinalizer,
+		status:     func(image *vmv1.ServerImage) interface{} { return image.Status.DeepCopy() },
+		conditions: func(image *vmv1.ServerImage) *[]metav1.Condition { return &image.Status.Conditions },
+		reconcile:  r.reconcileImage,
+		remove:     r.remove,
+	})
+}
+
+func (r *ServerImageReconciler) reconcileImage(ctx context.Context, log logr.Logger, image *vmv1.ServerImage) (ctrl.Result, error) {
+	if image.Status.Phase == vmv1.ServerImagePhaseAvailable || image.Status.Phase == vmv1.ServerImagePhaseFailed {
+		return ctrl.Result{}, nil
+	}
+	if image.Status.MemberServerImageInstanceNo == "" {
+		return r.create(ctx, log, image)
+	}
+
+	instance, err := r.ncpClient.GetMemberServerImageInstanceDetail(ctx, image.Status.RegionCode, image.Status.MemberServerImageInstanceNo)
+	if err != nil {
+		if ncp.AsError(err).Kind != ncp.ErrorKindNotFound {
+			return ctrl.Result{}, err
+		}
+		image.Status.Phase = vmv1.ServerImagePhaseFailed
+		recordEvent(ctx, r.Recorder, image, corev1.EventTypeWarning, eventReasonImageFailed,
+			"Server image %s no longer exists", image.Status.MemberServerImageInstanceNo)
+		return ctrl.Result{}, nil
+	}
+	if instance.Creating() {
+		log.V(LogLevelDebug).Info("Waiting for server image", "memberServerImageInstanceNo", instance.MemberServerImageInstanceNo,
+			"status", instance.MemberServerImageInstanceStatus.Code)
+		return ctrl.Result{RequeueAfter: creationPollInterval}, nil
+	}
+	if !instance.Available() {
+		image.Status.Phase = vmv1.ServerImagePhaseFailed
+		recordEvent(ctx, r.Recorder, image, corev1.EventTypeWarning, eventReasonImageFailed,
+			"Server image %s failed with status %s", instance.MemberServerImageInstanceNo, instance.MemberServerImageInstanceStatus.Code)
+		return ctrl.Result{}, nil
+	}
+	image.Status.Phase = vmv1.ServerImagePhaseAvailable
+	recordEvent(ctx, r.Recorder, image, corev1.EventTypeNormal, eventReasonImageAvailable,
+		"Server image %s is available", instance.MemberServerImageInstanceNo)
+	return ctrl.Result{}, nil
+}
+
+// create requests the image once the Provision has a server.
+func (r *ServerImageReconciler) create(ctx context.Context, log logr.Logger, image *vmv1.ServerImage) (ctrl.Result, error) {
+	provision := &vmv1.Provision{}
+	err := r.Get(ctx, types.NamespacedName{Namespace: image.Namespace, Name: image.Spec.ProvisionName}, provision)
+	if err != nil && !errors.IsNotFound(err) {
+		return ctrl.Result{}, err
+	}
+	serverInstanceNo := ""
+	if err == nil {
+		serverInstanceNo = managedServerInstanceNo(provision)
+	}
+	if serverInstanceNo == "" {
+		log.V(LogLevelDebug).Info("Waiting for the Provision to have a server", "provision", image.Spec.ProvisionName)
+		image.Status.Phase = vmv1.ServerImagePhasePending
+		return ctrl.Result{RequeueAfter: dependencyRequeueInterval}, nil
+	}
+
+	name := image.Spec.ImageName
+	if name == "" {
+		name = ncpName(serverImageNamePrefix, image.Namespace, image.Name)
+	}
+	// An image requested by an earlier reconcile whose status update was lost
+	// is picked up again rather than requested twice.
+	instance, err := r.ncpClient.FindMemberServerImageInstance(ctx, provision.Spec.RegionCode, name)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if instance != nil && instance.OriginalServerInstanceNo != serverInstanceNo {
+		image.Status.Phase = vmv1.ServerImagePhaseFailed
+		recordEvent(ctx, r.Recorder, image, corev1.EventTypeWarning, eventReasonImageFailed,
+			"Image name %s is used by image %s of server %s", name,
+			instance.MemberServerImageInstanceNo, instance.OriginalServerInstanceNo)
+		return ctrl.Result{}, nil
+	}
+	if instance == nil {
+		instance, err = r.ncpClient.CreateMemberServerImageInstance(ctx, provision.Spec.RegionCode, serverInstanceNo, name, image.Spec.Description)
+		if err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	image.Status.Phase = vmv1.ServerImagePhaseCreating
+	image.Status.MemberServerImageInstanceNo = instance.MemberServerImageInstanceNo
+	image.Status.RegionCode = provision.Spec.RegionCode
+	image.Status.ServerInstanceNo = serverInstanceNo
+	image.Status.ImageName = name
+	log.V(LogLevelInfo).Info("Server image creation requested", "memberServerImageInstanceNo", instance.MemberServerImageInstanceNo,
+		"serverInstanceNo", serverInstanceNo)
+	recordEvent(ctx, r.Recorder, image, corev1.EventTypeNormal, eventReasonImageCreationStarted,
+		"Requested image %s (%s) of server %s", name, instance.MemberServerImageInstanceNo, serverInstanceNo)
+	return ctrl.Result{RequeueAfter: creationPollInterval}, nil
+}
+
+// remove deletes the NCP image, if any.
+func (r *ServerImageReconciler) remove(ctx context.Context, log logr.Logger, image *vmv1.ServerImage) error {
+	no := image.Status.MemberServerImageInstanceNo
+	if no == "" {
+		return nil
+	}
+	if err := r.ncpClient.DeleteMemberServerImageInstances(ctx, image.Status.RegionCode, no); err != nil {
+		return err
+	}
+	log.V(LogLevelInfo).Info("Server image deleted", "memberServerImageInstanceNo", no)
+	recordEvent(ctx, r.Recorder, image, corev1.EventTypeNormal, eventReasonImageDeleted, "Deleted image %s", no)
+	return nil
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *ServerImageReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&vmv1.ServerImage{}).
+		Complete(traced("ServerImage", r))
+}
+
+// memberServerImage returns the member server image a Provision boots from:
+// that of its ServerImageRef once available, or memberServerImageInstanceNo.
+func memberServerImage(ctx context.Context, r *ProvisionReconciler, original *vmv1.Provision) (string, error) {
+	if original.Spec.ServerImageRef == "" {
+		return original.Spec.MemberServerImageInstanceNo, nil
+	}
+	image := &vmv1.ServerImage{}
+	key := types.NamespacedName{Namespace: original.Namespace, Name: original.Spec.ServerImageRef}
+	if err := r.Get(ctx, key, image); err != nil {
+		if errors.IsNotFound(err) {
+			return "", &dependencyNotReadyError{kind: "ServerImage", name: key.Name, reason: "not found"}
+		}
+		return "", err
+	}
+	if image.Status.Phase == vmv1.ServerImagePhaseFailed {
+		return "", &ncp.Error{Kind: ncp.ErrorKindInvalidArgument, Message: "ServerImage " + key.Name + " failed"}
+	}
+	if image.Status.Phase != vmv1.ServerImagePhaseAvailable {
+		return "", &dependencyNotReadyError{kind: "ServerImage", name: key.Name, reason: "not available yet"}
+	}
+	return image.Status.MemberServerImageInstanceNo, nil
+}
@@ -18,6 +18,7 @@ package controller
 
 import (
 	"fmt"
+	"os"
 	"path/filepath"
 	"runtime"
 	"testing"
@@ -65,6 +66,13 @@ var _ = BeforeSuite(func() {
 		BinaryAssetsDirectory: filepath.Join("..", "..", "bin", "k8s",
 			fmt.Sprintf("1.28.3-%s-%s", runtime.GOOS, runtime.GOARCH)),
 	}
+	// The reconcilers are specified against a fake client and NCP server and
+	// do not need the test environment, so they still run without it.
+	if _, err := os.Stat(testEnv.BinaryAssetsDirectory); err != nil && os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		GinkgoWriter.Println("envtest binaries not found, not starting the test environment")
+		testEnv = nil
+		return
+	}
 
 	var err error
 	// cfg is defined in this file globally.
@@ -84,6 +92,9 @@ var _ = BeforeSuite(func() {
 })
 
 var _ = AfterSuite(func() {
+	if testEnv == nil {
+		return
+	}
 	By("tearing down the test environment")
 	err := testEnv.Stop()
 	Expect(err).NotTo(HaveOccurred())
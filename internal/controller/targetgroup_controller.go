@@ -0,0 +1,249 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/go-logr/logr"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+// targetGroupFinalizer keeps a TargetGroup until its NCP target group is deleted.
+const targetGroupFinalizer = "vm.cloudclub.io/target-group"
+
+// targetGroupNamePrefix starts the NCP names derived for TargetGroups.
+const targetGroupNamePrefix = "aviator-tg-"
+
+// TargetGroupReconciler creates NCP target groups, keeps the running servers
+// of the Provisions they select as targets and deletes them with their
+// TargetGroup.
+type TargetGroupReconciler struct {
+	client.Client
+	Scheme    *runtime.Scheme
+	Recorder  record.EventRecorder
+	ncpClient *ncp.Client
+}
+
+func NewTargetGroupReconciler(client client.Client, scheme *runtime.Scheme, recorder record.EventRecorder, ncpClient *ncp.Client) *TargetGroupReconciler {
+	return &TargetGroupReconciler{
+		Client:    client,
+		Scheme:    scheme,
+		Recorder:  recorder,
+		ncpClient: ncpClient,
+	}
+}
+
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=targetgroups,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=targetgroups/status,verbs=get;update;patch
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=targetgroups/finalizers,verbs=update
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=provisions,verbs=get;list;watch
+
+// Reconcile creates the NCP target group of a TargetGroup and brings its
+// targets in line with the Provisions it selects.
+func (r *TargetGroupReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	return reconcileNCPResource(ctx, r.Client, r.Recorder, req, &vmv1.TargetGroup{}, ncpResource[*vmv1.TargetGroup]{
+		kind:       "TargetGroup",
+		finalizer:  targetGroupFinalizer,
+		status:     func(group *vmv1.TargetGroup) interface{} { return group.Status.DeepCopy() },
+		conditions: func(group *vmv1.TargetGroup) *[]metav1.Condition { return &group.Status.Conditions },
+		reconcile:  r.reconcileTargetGroup,
+		remove:     r.remove,
+	})
+}
+
+func (r *TargetGroupReconciler) reconcileTargetGroup(ctx context.Context, log logr.Logger, group *vmv1.TargetGroup) (ctrl.Result, error) {
+	if group.Status.TargetGroupNo == "" {
+		if err := r.create(ctx, log, group); err != nil {
+			return ctrl.Result{}, err
+		}
+	}
+	desired, err := r.targets(ctx, group)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+	if err := r.syncTargets(ctx, log, group, desired); err != nil {
+		return ctrl.Result{}, err
+	}
+	group.Status.Targets = desired
+	return ctrl.Result{}, nil
+}
+
+func (r *TargetGroupReconciler) create(ctx context.Context, log logr.Logger, group *vmv1.TargetGroup) error {
+	name := group.Spec.TargetGroupName
+	if name == "" {
+		name = ncpName(targetGroupNamePrefix, group.Namespace, group.Name)
+	}
+	// A group under the derived name was created by an earlier attempt whose
+	// status update was lost and is reused. One named in the spec may serve
+	// other load balancers and would have its targets replaced.
+	tg, err := r.ncpClient.FindTargetGroup(ctx, group.Spec.RegionCode, group.Spec.VpcNo, name)
+	if err != nil {
+		return err
+	}
+	if tg != nil && group.Spec.TargetGroupName != "" {
+		return &ncp.Error{Kind: ncp.ErrorKindInvalidArgument,
+			Message: fmt.Sprintf("target group %s already exists as %s", name, tg.TargetGroupNo)}
+	}
+	if tg != nil && (tg.TargetGroupProtocolType.Code != string(group.Spec.Protocol) || tg.TargetGroupPort != int(group.Spec.Port)) {
+		return &ncp.Error{Kind: ncp.ErrorKindInvalidArgument,
+			Message: fmt.Sprintf("target group name %s is used by group %s of %s port %d", name,
+				tg.TargetGroupNo, tg.TargetGroupProtocolType.Code, tg.TargetGroupPort)}
+	}
+	if tg == nil {
+		check := group.Spec.HealthCheck
+		tg, err = r.ncpClient.CreateTargetGroup(ctx, &ncp.CreateTargetGroupRequest{
+			RegionCode:                  group.Spec.RegionCode,
+			VpcNo:                       group.Spec.VpcNo,
+			TargetGroupName:             name,
+			TargetGroupDescription:      group.Spec.Description,
+			ProtocolTypeCode:            string(group.Spec.Protocol),
+			Port:                        int(group.Spec.Port),
+			HealthCheckProtocolTypeCode: check.Protocol,
+			HealthCheckPort:             int(check.Port),
+			HealthCheckURLPath:          check.URLPath,
+			HealthCheckHTTPMethod:       check.HTTPMethod,
+			HealthCheckCycle:            int(check.CycleSeconds),
+			HealthCheckUpThreshold:      int(check.UpThreshold),
+			HealthCheckDownThreshold:    int(check.DownThreshold),
+		})
+		if err != nil {
+			return err
+		}
+		recordEvent(ctx, r.Recorder, group, corev1.EventTypeNormal, eventReasonTargetGroupCreated,
+			"Created target group %s (%s)", name, tg.TargetGroupNo)
+	}
+	group.Status.TargetGroupNo = tg.TargetGroupNo
+	group.Status.TargetGroupName = name
+	group.Status.RegionCode = group.Spec.RegionCode
+	log.V(LogLevelInfo).Info("Target group ready", "targetGroupNo", tg.TargetGroupNo, "name", name)
+	return nil
+}
+
+// targets returns the running servers of the selected Provisions that are
+// not being deleted, ordered by Provision.
+func (r *TargetGroupReconciler) targets(ctx context.Context, group *vmv1.TargetGroup) ([]vmv1.Target, error) {
+	selector, err := metav1.LabelSelectorAsSelector(&group.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	provisions := &vmv1.ProvisionList{}
+	if err := r.List(ctx, provisions, client.InNamespace(group.Namespace), client.MatchingLabelsSelector{Selector: selector}); err != nil {
+		return nil, err
+	}
+	var targets []vmv1.Target
+	for i := range provisions.Items {
+		provision := &provisions.Items[i]
+		serverInstanceNo := managedServerInstanceNo(provision)
+		if !provision.DeletionTimestamp.IsZero() || serverInstanceNo == "" ||
+			provision.Status.ServerStatus != ncp.ServerStatusRunning {
+			continue
+		}
+		targets = append(targets, vmv1.Target{ProvisionName: provision.Name, ServerInstanceNo: serverInstanceNo})
+	}
+	sort.Slice(targets, func(i, j int) bool { return targets[i].ProvisionName < targets[j].ProvisionName })
+	return targets, nil
+}
+
+// syncTargets adds the desired servers missing from the NCP target group and
+// removes the servers no longer desired.
+func (r *TargetGroupReconciler) syncTargets(ctx context.Context, log logr.Logger, group *vmv1.TargetGroup, desired []vmv1.Target) error {
+	regionCode, targetGroupNo := group.Status.RegionCode, group.Status.TargetGroupNo
+	current, err := r.ncpClient.GetTargetList(ctx, regionCode, targetGroupNo)
+	if err != nil {
+		return err
+	}
+	desiredNos := make([]string, 0, len(desired))
+	for _, t := range desired {
+		desiredNos = append(desiredNos, t.ServerInstanceNo)
+	}
+	add, remove := ncp.DiffTargets(current, desiredNos)
+
+	if len(add) > 0 {
+		if err := r.ncpClient.AddTarget(ctx, regionCode, targetGroupNo, add); err != nil {
+			return err
+		}
+		log.V(LogLevelInfo).Info("Targets added", "targetGroupNo", targetGroupNo, "servers", add)
+		recordEvent(ctx, r.Recorder, group, corev1.EventTypeNormal, eventReasonTargetAdded, "Added servers %s", strings.Join(add, ", "))
+	}
+	if len(remove) > 0 {
+		if err := r.ncpClient.RemoveTarget(ctx, regionCode, targetGroupNo, remove); err != nil {
+			return err
+		}
+		log.V(LogLevelInfo).Info("Targets removed", "targetGroupNo", targetGroupNo, "servers", remove)
+		recordEvent(ctx, r.Recorder, group, corev1.EventTypeNormal, eventReasonTargetRemoved, "Removed servers %s", strings.Join(remove, ", "))
+	}
+	return nil
+}
+
+// remove deletes the NCP target group, if any.
+func (r *TargetGroupReconciler) remove(ctx context.Context, log logr.Logger, group *vmv1.TargetGroup) error {
+	no := group.Status.TargetGroupNo
+	if no == "" {
+		return nil
+	}
+	if err := r.ncpClient.DeleteTargetGroups(ctx, group.Status.RegionCode, no); err != nil {
+		return err
+	}
+	log.V(LogLevelInfo).Info("Target group deleted", "targetGroupNo", no)
+	recordEvent(ctx, r.Recorder, group, corev1.EventTypeNormal, eventReasonTargetGroupDeleted, "Deleted target group %s", no)
+	return nil
+}
+
+// targetGroupsForProvision maps a Provision to the TargetGroups selecting it.
+func (r *TargetGroupReconciler) targetGroupsForProvision(ctx context.Context, obj client.Object) []reconcile.Request {
+	groups := &vmv1.TargetGroupList{}
+	if err := r.List(ctx, groups, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list TargetGroups")
+		return nil
+	}
+	var requests []reconcile.Request
+	for _, group := range groups.Items {
+		selector, err := metav1.LabelSelectorAsSelector(&group.Spec.Selector)
+		if err != nil || !selector.Matches(labels.Set(obj.GetLabels())) {
+			continue
+		}
+		requests = append(requests, reconcile.Request{NamespacedName: types.NamespacedName{Namespace: group.Namespace, Name: group.Name}})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager. Provisions are
+// watched so that targets follow them as their servers start, stop or go
+// away; a Provision whose labels change is mapped both before and after.
+func (r *TargetGroupReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&vmv1.TargetGroup{}).
+		Watches(&vmv1.Provision{}, handler.EnqueueRequestsFromMapFunc(r.targetGroupsForProvision)).
+		Complete(traced("TargetGroup", r))
+}
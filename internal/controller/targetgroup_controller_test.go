@@ -0,0 +1,117 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+var _ = Describe("Creating load balancing resources", func() {
+	var (
+		ctx     context.Context
+		key     types.NamespacedName
+		fakeAPI *fakeNCP
+	)
+
+	BeforeEach(func() {
+		ctx = context.Background()
+		key = types.NamespacedName{Namespace: "default", Name: "web"}
+		fakeAPI = newFakeNCP()
+	})
+
+	Context("of a TargetGroup", func() {
+		existing := func(name string) {
+			fakeAPI.answer("getTargetGroupList", fmt.Sprintf("<getTargetGroupListResponse><totalRows>1</totalRows>"+
+				"<targetGroupList><targetGroup><targetGroupNo>61</targetGroupNo><targetGroupName>%s</targetGroupName>"+
+				"<targetGroupProtocolType><code>HTTP</code></targetGroupProtocolType><targetGroupPort>80</targetGroupPort>"+
+				"</targetGroup></targetGroupList></getTargetGroupListResponse>", name))
+		}
+
+		reconcile := func(targetGroupName string) *vmv1.TargetGroup {
+			c := newFakeClient(&vmv1.TargetGroup{
+				ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+				Spec: vmv1.TargetGroupSpec{RegionCode: "KR", VpcNo: "52833", TargetGroupName: targetGroupName,
+					Protocol: "HTTP", Port: 80},
+			})
+			r := NewTargetGroupReconciler(c, c.Scheme(), record.NewFakeRecorder(100), fakeAPI.client)
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			group := &vmv1.TargetGroup{}
+			Expect(c.Get(ctx, key, group)).To(Succeed())
+			return group
+		}
+
+		It("reuses the group an earlier attempt created under the derived name", func() {
+			existing(ncpName(targetGroupNamePrefix, key.Namespace, key.Name))
+
+			group := reconcile("")
+			Expect(group.Status.TargetGroupNo).To(Equal("61"))
+			Expect(fakeAPI.called()).NotTo(ContainElement("createTargetGroup"))
+		})
+
+		It("does not take over a group named in the spec", func() {
+			existing("shared")
+
+			group := reconcile("shared")
+			Expect(group.Status.TargetGroupNo).To(BeEmpty())
+			Expect(meta.IsStatusConditionTrue(group.Status.Conditions, vmv1.ConditionTypeFailed)).To(BeTrue())
+			Expect(fakeAPI.called()).NotTo(ContainElement("createTargetGroup"))
+			Expect(fakeAPI.called()).NotTo(ContainElement("getTargetList"))
+		})
+	})
+
+	Context("of a LoadBalancer", func() {
+		It("does not take over a load balancer named in the spec", func() {
+			c := newFakeClient(
+				&vmv1.TargetGroup{
+					ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+					Status:     vmv1.TargetGroupStatus{TargetGroupNo: "61"},
+				},
+				&vmv1.LoadBalancer{
+					ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+					Spec: vmv1.LoadBalancerSpec{RegionCode: "KR", VpcNo: "52833", LoadBalancerName: "shared",
+						Listeners: []vmv1.Listener{{Protocol: "HTTP", Port: 80, TargetGroupRef: key.Name}}},
+				},
+			)
+			fakeAPI.answer("getLoadBalancerInstanceList", "<getLoadBalancerInstanceListResponse><totalRows>1</totalRows>"+
+				"<loadBalancerInstanceList><loadBalancerInstance><loadBalancerInstanceNo>71</loadBalancerInstanceNo>"+
+				"<loadBalancerName>shared</loadBalancerName></loadBalancerInstance></loadBalancerInstanceList>"+
+				"</getLoadBalancerInstanceListResponse>")
+			r := NewLoadBalancerReconciler(c, c.Scheme(), record.NewFakeRecorder(100), fakeAPI.client)
+
+			_, err := r.Reconcile(ctx, ctrl.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			lb := &vmv1.LoadBalancer{}
+			Expect(c.Get(ctx, key, lb)).To(Succeed())
+			Expect(lb.Status.LoadBalancerInstanceNo).To(BeEmpty())
+			Expect(meta.IsStatusConditionTrue(lb.Status.Conditions, vmv1.ConditionTypeFailed)).To(BeTrue())
+			Expect(fakeAPI.called()).NotTo(ContainElement("createLoadBalancerInstance"))
+		})
+	})
+})
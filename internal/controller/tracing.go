@@ -0,0 +1,89 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	"vm.cloudclub.io/internal/tracing"
+)
+
+// traceIDAnnotation is set on recorded events to the trace they happened in.
+const traceIDAnnotation = "vm.cloudclub.io/trace-id"
+
+var tracer = otel.Tracer("vm.cloudclub.io/internal/controller")
+
+// endSpan records err, if any, on span and ends it.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// tracedReconciler runs every reconcile of a kind in its own span.
+type tracedReconciler struct {
+	kind       string
+	reconciler reconcile.Reconciler
+}
+
+// traced wraps the reconciler of kind so each reconcile starts a span named
+// after the kind, e.g. Provision.Reconcile.
+func traced(kind string, reconciler reconcile.Reconciler) reconcile.Reconciler {
+	return &tracedReconciler{kind: kind, reconciler: reconciler}
+}
+
+func (t *tracedReconciler) Reconcile(ctx context.Context, req ctrl.Request) (result ctrl.Result, err error) {
+	prefix := strings.ToLower(t.kind)
+	ctx, span := tracer.Start(ctx, t.kind+".Reconcile", trace.WithAttributes(
+		attribute.String(prefix+".namespace", req.Namespace),
+		attribute.String(prefix+".name", req.Name),
+	))
+	defer func() {
+		span.SetAttributes(attribute.Int64("reconcile.requeue_after_ms", result.RequeueAfter.Milliseconds()))
+		endSpan(span, err)
+	}()
+	return t.reconciler.Reconcile(ctx, req)
+}
+
+// recordEvent records an event on object, annotated with the ID of the trace
+// ctx belongs to.
+func recordEvent(ctx context.Context, recorder record.EventRecorder, object runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	message := fmt.Sprintf(messageFmt, args...)
+	if traceID := tracing.TraceID(ctx); traceID != "" {
+		recorder.AnnotatedEventf(object, map[string]string{traceIDAnnotation: traceID}, eventType, reason, "%s", message)
+		return
+	}
+	recorder.Event(object, eventType, reason, message)
+}
+
+// event records an event on a Provision or one of its dependents.
+func (r *ProvisionReconciler) event(ctx context.Context, object runtime.Object, eventType, reason, messageFmt string, args ...interface{}) {
+	recordEvent(ctx, r.Recorder, object, eventType, reason, messageFmt, args...)
+}
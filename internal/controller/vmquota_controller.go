@@ -0,0 +1,97 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package controller
+
+import (
+	"context"
+
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/quota"
+)
+
+// VMQuotaReconciler reports what the Provisions of a namespace use in the
+// status of its VMQuotas. The quotas are enforced by the Provision webhook
+// and by the ProvisionReconciler before creating servers.
+type VMQuotaReconciler struct {
+	client.Client
+	Scheme *runtime.Scheme
+}
+
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=vmquotas,verbs=get;list;watch;create;update;patch;delete
+//+kubebuilder:rbac:groups=vm.cloudclub.io,resources=vmquotas/status,verbs=get;update;patch
+
+// Reconcile recomputes the usage of a VMQuota.
+func (r *VMQuotaReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	vmQuota := &vmv1.VMQuota{}
+	if err := r.Get(ctx, req.NamespacedName, vmQuota); err != nil {
+		if errors.IsNotFound(err) {
+			return ctrl.Result{}, nil
+		}
+		log.Error(err, "Failed to get VMQuota resource")
+		return ctrl.Result{}, err
+	}
+
+	used, err := quota.NamespaceUsage(ctx, r, vmQuota.Namespace, "")
+	if err != nil {
+		log.Error(err, "Failed to list Provisions")
+		return ctrl.Result{}, err
+	}
+	if used == vmQuota.Status.Used {
+		return ctrl.Result{}, nil
+	}
+	vmQuota.Status.Used = used
+	if err := r.Status().Update(ctx, vmQuota); err != nil {
+		log.Error(err, "Failed to update VMQuota status")
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{}, nil
+}
+
+// quotasForProvision enqueues the VMQuotas of a Provision's namespace.
+func (r *VMQuotaReconciler) quotasForProvision(ctx context.Context, obj client.Object) []reconcile.Request {
+	quotas := &vmv1.VMQuotaList{}
+	if err := r.List(ctx, quotas, client.InNamespace(obj.GetNamespace())); err != nil {
+		log.FromContext(ctx).Error(err, "Failed to list VMQuotas")
+		return nil
+	}
+	requests := make([]reconcile.Request, 0, len(quotas.Items))
+	for _, q := range quotas.Items {
+		requests = append(requests, reconcile.Request{
+			NamespacedName: types.NamespacedName{Namespace: q.Namespace, Name: q.Name},
+		})
+	}
+	return requests
+}
+
+// SetupWithManager sets up the controller with the Manager.
+func (r *VMQuotaReconciler) SetupWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewControllerManagedBy(mgr).
+		For(&vmv1.VMQuota{}).
+		Watches(&vmv1.Provision{}, handler.EnqueueRequestsFromMapFunc(r.quotasForProvision)).
+		Complete(traced("VMQuota", r))
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ncp
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+const (
+	getBlockStorageInstanceListAction = "getBlockStorageInstanceList"
+
+	// BlockStorageTypeBasic is the boot volume created with every server,
+	// BlockStorageTypeServer an additional volume attached to it.
+	BlockStorageTypeBasic  = "BASIC"
+	BlockStorageTypeServer = "SVRBS"
+
+	gibibyte = 1 << 30
+)
+
+type BlockStorageInstance struct {
+	BlockStorageInstanceNo        string     `xml:"blockStorageInstanceNo"`
+	ServerInstanceNo              string     `xml:"serverInstanceNo"`
+	BlockStorageName              string     `xml:"blockStorageName"`
+	BlockStorageType              CommonCode `xml:"blockStorageType"`
+	BlockStorageSize              int64      `xml:"blockStorageSize"`
+	DeviceName                    string     `xml:"deviceName"`
+	BlockStorageInstanceStatus    CommonCode `xml:"blockStorageInstanceStatus"`
+	BlockStorageInstanceOperation CommonCode `xml:"blockStorageInstanceOperation"`
+	BlockStorageVolumeType        CommonCode `xml:"blockStorageVolumeType"`
+	IsEncryptedVolume             bool       `xml:"isEncryptedVolume"`
+	ZoneCode                      string     `xml:"zoneCode"`
+	RegionCode                    string     `xml:"regionCode"`
+}
+
+// SizeGiB returns the volume size, which NCP reports in bytes, in GiB.
+func (b *BlockStorageInstance) SizeGiB() int {
+	return int(b.BlockStorageSize / gibibyte)
+}
+
+type BlockStorageInstanceList struct {
+	ReturnCode               int                    `xml:"returnCode"`
+	ReturnMessage            string                 `xml:"returnMessage"`
+	TotalRows                int                    `xml:"totalRows"`
+	BlockStorageInstanceList []BlockStorageInstance `xml:"blockStorageInstanceList>blockStorageInstance"`
+}
+
+// GetBlockStorageInstanceList lists the volumes attached to a server.
+func (c *Client) GetBlockStorageInstanceList(ctx context.Context, regionCode, serverInstanceNo string) (*BlockStorageInstanceList, error) {
+	v := regionValues(regionCode)
+	v.Set("serverInstanceNo", serverInstanceNo)
+
+	resp := &BlockStorageInstanceList{}
+	if err := c.call(ctx, getBlockStorageInstanceListAction, v, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+const (
+	createBlockStorageInstanceAction    = "createBlockStorageInstance"
+	getBlockStorageInstanceDetailAction = "getBlockStorageInstanceDetail"
+	deleteBlockStorageInstancesAction   = "deleteBlockStorageInstances"
+	changeBlockStorageVolumeSizeAction  = "changeBlockStorageVolumeSize"
+
+	BlockStorageStatusInit     = "INIT"
+	BlockStorageStatusCreated  = "CREAT"
+	BlockStorageStatusAttached = "ATTAC"
+)
+
+// Attached reports whether the volume is attached to its server and no
+// operation is in progress.
+func (b *BlockStorageInstance) Attached() bool {
+	return b.BlockStorageInstanceStatus.Code == BlockStorageStatusAttached &&
+		(b.BlockStorageInstanceOperation.Code == "" || b.BlockStorageInstanceOperation.Code == ServerOperationNone)
+}
+
+// CreateBlockStorageInstanceRequest holds the parameters of createBlockStorageInstance.
+type CreateBlockStorageInstanceRequest struct {
+	RegionCode       string
+	ServerInstanceNo string
+	// SnapshotInstanceNo restores the volume from a snapshot.
+	SnapshotInstanceNo         string
+	BlockStorageName           string
+	BlockStorageDescription    string
+	BlockStorageSize           int
+	BlockStorageVolumeTypeCode string
+}
+
+func (r *CreateBlockStorageInstanceRequest) values() url.Values {
+	v := regionValues(r.RegionCode)
+	v.Set("serverInstanceNo", r.ServerInstanceNo)
+	if r.SnapshotInstanceNo != "" {
+		v.Set("blockStorageSnapshotInstanceNo", r.SnapshotInstanceNo)
+	}
+	if r.BlockStorageName != "" {
+		v.Set("blockStorageName", r.BlockStorageName)
+	}
+	if r.BlockStorageDescription != "" {
+		v.Set("blockStorageDescription", r.BlockStorageDescription)
+	}
+	if r.BlockStorageSize > 0 {
+		v.Set("blockStorageSize", strconv.Itoa(r.BlockStorageSize))
+	}
+	if r.BlockStorageVolumeTypeCode != "" {
+		v.Set("blockStorageVolumeTypeCode", r.BlockStorageVolumeTypeCode)
+	}
+	return v
+}
+
+// CreateBlockStorageInstance creates a volume attached to a server.
+func (c *Client) CreateBlockStorageInstance(ctx context.Context, request *CreateBlockStorageInstanceRequest) (*BlockStorageInstance, error) {
+	resp := &BlockStorageInstanceList{}
+	if err := c.call(ctx, createBlockStorageInstanceAction, request.values(), resp); err != nil {
+		return nil, err
+	}
+	if len(resp.BlockStorageInstanceList) == 0 {
+		return nil, fmt.Errorf("%s returned no block storage", createBlockStorageInstanceAction)
+	}
+	return &resp.BlockStorageInstanceList[0], nil
+}
+
+// GetBlockStorageInstanceDetail reads one volume.
+func (c *Client) GetBlockStorageInstanceDetail(ctx context.Context, regionCode, blockStorageInstanceNo string) (*BlockStorageInstance, error) {
+	v := regionValues(regionCode)
+	v.Set("blockStorageInstanceNo", blockStorageInstanceNo)
+
+	resp := &BlockStorageInstanceList{}
+	if err := c.call(ctx, getBlockStorageInstanceDetailAction, v, resp); err != nil {
+		return nil, err
+	}
+	if len(resp.BlockStorageInstanceList) == 0 {
+		return nil, notFound("block storage", blockStorageInstanceNo)
+	}
+	return &resp.BlockStorageInstanceList[0], nil
+}
+
+// DeleteBlockStorageInstances deletes volumes.
+func (c *Client) DeleteBlockStorageInstances(ctx context.Context, regionCode string, blockStorageInstanceNos ...string) error {
+	v := regionValues(regionCode)
+	for i, no := range blockStorageInstanceNos {
+		v.Set(fmt.Sprintf("blockStorageInstanceNoList.%d", i+1), no)
+	}
+	return c.call(ctx, deleteBlockStorageInstancesAction, v, &BlockStorageInstanceList{})
+}
+
+// ChangeBlockStorageVolumeSize grows a volume to sizeGiB. NCP cannot shrink volumes.
+func (c *Client) ChangeBlockStorageVolumeSize(ctx context.Context, regionCode, blockStorageInstanceNo string, sizeGiB int) error {
+	v := regionValues(regionCode)
+	v.Set("blockStorageInstanceNo", blockStorageInstanceNo)
+	v.Set("blockStorageSize", strconv.Itoa(sizeGiB))
+	return c.call(ctx, changeBlockStorageVolumeSizeAction, v, &BlockStorageInstanceList{})
+}
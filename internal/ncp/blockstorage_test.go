@@ -0,0 +1,41 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ncp
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("CreateBlockStorageInstanceRequest", func() {
+	It("restores from a snapshot and leaves the size to it when unset", func() {
+		req := &CreateBlockStorageInstanceRequest{
+			RegionCode:         "KR",
+			ServerInstanceNo:   "1234",
+			SnapshotInstanceNo: "5678",
+			BlockStorageName:   "aviator-data-default-db",
+		}
+
+		v := req.values()
+		Expect(v.Get("regionCode")).To(Equal("KR"))
+		Expect(v.Get("serverInstanceNo")).To(Equal("1234"))
+		Expect(v.Get("blockStorageSnapshotInstanceNo")).To(Equal("5678"))
+		Expect(v.Get("blockStorageName")).To(Equal("aviator-data-default-db"))
+		Expect(v.Has("blockStorageSize")).To(BeFalse())
+		Expect(v.Has("blockStorageVolumeTypeCode")).To(BeFalse())
+	})
+})
@@ -0,0 +1,132 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ncp calls the NCP vserver actions that the Aviator-service
+// ServerInterface does not expose, signing requests the same way it does.
+package ncp
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	ncputil "github.com/cloud-club/Aviator-service/pkg"
+	"github.com/cloud-club/Aviator-service/types/auth"
+)
+
+// Client is a thin NCP API client sharing the operator's access key and,
+// through limiter, its API rate limit.
+type Client struct {
+	keyService *auth.KeyService
+	httpClient *http.Client
+	baseURL    string
+	vpcURL     string
+	lbURL      string
+	billingURL string
+	limiter    *rate.Limiter
+}
+
+func NewClient(keyService *auth.KeyService, limiter *rate.Limiter) *Client {
+	return &Client{
+		keyService: keyService,
+		httpClient: http.DefaultClient,
+		baseURL:    ncputil.API_URL,
+		vpcURL:     ncputil.VPC_API_URL,
+		lbURL:      loadBalancerAPIURL,
+		billingURL: billingAPIURL,
+		limiter:    limiter,
+	}
+}
+
+// WithAPIGateway returns a copy of c that sends its calls to gateway, such as
+// a proxy or a test server, under the paths of the NCP API gateways.
+func (c *Client) WithAPIGateway(gateway string) *Client {
+	gateway = strings.TrimSuffix(gateway, "/")
+	copied := *c
+	for _, u := range []*string{&copied.baseURL, &copied.vpcURL, &copied.lbURL, &copied.billingURL} {
+		if parsed, err := url.Parse(*u); err == nil {
+			*u = gateway + parsed.Path
+		}
+	}
+	return &copied
+}
+
+// call issues a signed GET for a vserver action and unmarshals the XML response into out.
+func (c *Client) call(ctx context.Context, action string, params url.Values, out interface{}) error {
+	return c.callURL(ctx, c.baseURL, action, params, out)
+}
+
+// callURL is call for an action of the API at baseURL.
+func (c *Client) callURL(ctx context.Context, baseURL, action string, params url.Values, out interface{}) (err error) {
+	ctx, span := startSpan(ctx, action, params.Get("regionCode"))
+	statusCode, requestID := 0, ""
+	defer func(start time.Time) {
+		observeCall(action, start, err)
+		endSpan(span, statusCode, requestID, err)
+	}(time.Now())
+
+	if c.limiter != nil {
+		if err := c.limiter.Wait(ctx); err != nil {
+			return err
+		}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, baseURL+action+"?"+params.Encode(), nil)
+	if err != nil {
+		return err
+	}
+	ncputil.SetNCPHeader(req, c.keyService.GetAccessKey(), c.keyService.GetSecretKey())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	statusCode, requestID = resp.StatusCode, resp.Header.Get(requestIDHeader)
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return newError(resp.StatusCode, body)
+	}
+	if err := xml.Unmarshal(body, out); err != nil {
+		return fmt.Errorf("error unmarshalling %s response: %v", action, err)
+	}
+	return nil
+}
+
+// regionValues starts the query of an action that takes an optional regionCode.
+func regionValues(regionCode string) url.Values {
+	v := url.Values{}
+	if regionCode != "" {
+		v.Set("regionCode", regionCode)
+	}
+	return v
+}
+
+// CommonCode mirrors the code/codeName pairs NCP uses for enumerations.
+type CommonCode struct {
+	Code     string `xml:"code"`
+	CodeName string `xml:"codeName"`
+}
@@ -0,0 +1,193 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ncp
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrorKind tells how a failed NCP call should be handled.
+type ErrorKind string
+
+const (
+	// ErrorKindRetryable is a server side or network failure worth retrying.
+	ErrorKindRetryable ErrorKind = "Retryable"
+	// ErrorKindThrottled means the API gateway rejected the call for its rate.
+	ErrorKindThrottled ErrorKind = "Throttled"
+	// ErrorKindQuota means an account limit on resources was reached.
+	ErrorKindQuota ErrorKind = "QuotaExceeded"
+	// ErrorKindInvalidArgument means the request itself was rejected.
+	ErrorKindInvalidArgument ErrorKind = "InvalidArgument"
+	// ErrorKindAuth means the access key is invalid or lacks permission.
+	ErrorKindAuth ErrorKind = "AuthFailed"
+	// ErrorKindNotFound means the referenced resource does not exist.
+	ErrorKindNotFound ErrorKind = "NotFound"
+)
+
+// Error is a failed NCP call.
+type Error struct {
+	Kind       ErrorKind
+	StatusCode int
+	// Code is the API gateway errorCode or the API returnCode.
+	Code    string
+	Message string
+}
+
+func (e *Error) Error() string {
+	if e.Code == "" {
+		return fmt.Sprintf("ncp: %s: %s", e.Kind, e.Message)
+	}
+	return fmt.Sprintf("ncp: %s (code %s): %s", e.Kind, e.Code, e.Message)
+}
+
+// notFound is the error of a detail action that answered with an empty list,
+// which is how NCP reports most resources that do not exist.
+func notFound(resource, no string) *Error {
+	return &Error{Kind: ErrorKindNotFound, Message: fmt.Sprintf("%s %s not found", resource, no)}
+}
+
+// Terminal reports whether retrying the same request cannot succeed.
+func (e *Error) Terminal() bool {
+	switch e.Kind {
+	case ErrorKindQuota, ErrorKindInvalidArgument, ErrorKindAuth, ErrorKindNotFound:
+		return true
+	}
+	return false
+}
+
+// errorBody covers both error formats NCP answers with: the API gateway's
+// {"error": {...}} and the API's responseError, in JSON or XML.
+type errorBody struct {
+	Error struct {
+		ErrorCode string `json:"errorCode"`
+		Message   string `json:"message"`
+		Details   string `json:"details"`
+	} `json:"error"`
+	ResponseError responseError `json:"responseError"`
+}
+
+type responseError struct {
+	ReturnCode    string `json:"returnCode" xml:"returnCode"`
+	ReturnMessage string `json:"returnMessage" xml:"returnMessage"`
+}
+
+// newError classifies an unsuccessful response. A zero statusCode means it is
+// unknown, as with the errors of the Aviator-service, which only keep the body.
+func newError(statusCode int, body []byte) *Error {
+	e := &Error{StatusCode: statusCode, Message: strings.TrimSpace(string(body))}
+	parseErrorBody(e, body)
+	if e.Kind == "" {
+		e.Kind = apiErrorKind(statusCode, e.Message)
+	}
+	return e
+}
+
+// parseErrorBody fills e from an NCP error body and reports whether body was one.
+func parseErrorBody(e *Error, body []byte) bool {
+	var parsed errorBody
+	var xmlError responseError
+	switch {
+	case json.Unmarshal(body, &parsed) == nil && parsed.Error.ErrorCode != "":
+		e.Code, e.Message = parsed.Error.ErrorCode, parsed.Error.Message
+		if parsed.Error.Details != "" {
+			e.Message += ": " + parsed.Error.Details
+		}
+		e.Kind = gatewayErrorKind(e.Code)
+	case json.Unmarshal(body, &parsed) == nil && parsed.ResponseError.ReturnCode != "":
+		e.Code, e.Message = parsed.ResponseError.ReturnCode, parsed.ResponseError.ReturnMessage
+	case xml.Unmarshal(body, &xmlError) == nil && xmlError.ReturnCode != "":
+		e.Code, e.Message = xmlError.ReturnCode, xmlError.ReturnMessage
+	default:
+		return false
+	}
+	return true
+}
+
+// serviceError turns an error of the Aviator-service that carries an NCP
+// error body into an *Error. Other errors, such as network failures, are
+// returned unchanged.
+func serviceError(err error) error {
+	if err == nil {
+		return nil
+	}
+	body := []byte(err.Error())
+	e := &Error{Message: strings.TrimSpace(string(body))}
+	if !parseErrorBody(e, body) {
+		return err
+	}
+	if e.Kind == "" {
+		e.Kind = apiErrorKind(0, e.Message)
+	}
+	return e
+}
+
+// gatewayErrorKind maps the API gateway's documented error codes.
+func gatewayErrorKind(code string) ErrorKind {
+	switch code {
+	case "100":
+		return ErrorKindInvalidArgument
+	case "200", "210":
+		return ErrorKindAuth
+	case "300":
+		return ErrorKindNotFound
+	case "400", "410", "420", "430":
+		return ErrorKindThrottled
+	}
+	return ErrorKindRetryable
+}
+
+// apiErrorKind classifies errors of the API behind the gateway, whose return
+// codes are not documented as stable, by status and message.
+func apiErrorKind(statusCode int, message string) ErrorKind {
+	message = strings.ToLower(message)
+	switch {
+	case statusCode == http.StatusUnauthorized || statusCode == http.StatusForbidden:
+		return ErrorKindAuth
+	case statusCode == http.StatusTooManyRequests:
+		return ErrorKindThrottled
+	case strings.Contains(message, "quota") || strings.Contains(message, "exceeded the limit") ||
+		strings.Contains(message, "limit exceeded"):
+		return ErrorKindQuota
+	case statusCode == http.StatusNotFound || strings.Contains(message, "not found") ||
+		strings.Contains(message, "does not exist"):
+		return ErrorKindNotFound
+	case statusCode >= 500:
+		return ErrorKindRetryable
+	case statusCode >= 400 || strings.Contains(message, "invalid"):
+		return ErrorKindInvalidArgument
+	}
+	return ErrorKindRetryable
+}
+
+// AsError returns err as an *Error. Only responses of NCP are classified;
+// any other error, including those of this package and the controllers, is
+// retryable. It returns nil for a nil err.
+func AsError(err error) *Error {
+	if err == nil {
+		return nil
+	}
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+	return &Error{Kind: ErrorKindRetryable, Message: err.Error()}
+}
@@ -0,0 +1,146 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ncp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloud-club/Aviator-service/types/auth"
+)
+
+var _ = Describe("Error classification", func() {
+	DescribeTable("classifies responses",
+		func(statusCode int, body string, kind ErrorKind, terminal bool) {
+			e := newError(statusCode, []byte(body))
+			Expect(e.Kind).To(Equal(kind))
+			Expect(e.Terminal()).To(Equal(terminal))
+		},
+		Entry("gateway auth failure", http.StatusUnauthorized,
+			`{"error":{"errorCode":"200","message":"Authentication Failed","details":"Invalid authentication information."}}`,
+			ErrorKindAuth, true),
+		Entry("gateway throttling", http.StatusTooManyRequests,
+			`{"error":{"errorCode":"420","message":"Rate Limited"}}`, ErrorKindThrottled, false),
+		Entry("api quota", http.StatusBadRequest,
+			`{"responseError":{"returnCode":"1300","returnMessage":"You have exceeded the limit of servers."}}`,
+			ErrorKindQuota, true),
+		Entry("api invalid argument", http.StatusBadRequest,
+			`<responseError><returnCode>24002</returnCode><returnMessage>Invalid serverProductCode.</returnMessage></responseError>`,
+			ErrorKindInvalidArgument, true),
+		Entry("api server error", http.StatusInternalServerError, `internal error`, ErrorKindRetryable, false),
+	)
+
+	It("classifies the body text of Aviator-service errors", func() {
+		err := serviceError(fmt.Errorf("%s", `{"error":{"errorCode":"300","message":"Not Found"}}`))
+		Expect(AsError(err).Kind).To(Equal(ErrorKindNotFound))
+		Expect(AsError(err).Terminal()).To(BeTrue())
+	})
+
+	It("leaves Aviator-service errors without an NCP body alone", func() {
+		err := errors.New("dial tcp: connection refused")
+		Expect(serviceError(err)).To(BeIdenticalTo(err))
+	})
+
+	DescribeTable("never classifies local errors as terminal",
+		func(err error) {
+			Expect(AsError(err).Kind).To(Equal(ErrorKindRetryable))
+			Expect(AsError(err).Terminal()).To(BeFalse())
+		},
+		Entry("missing response field", fmt.Errorf("createServerInstances returned no server instance")),
+		Entry("unmarshal failure", errors.New("error unmarshalling getServerInstanceList response: invalid character")),
+		Entry("pre-stop hook", errors.New("pre-stop hook answered 404 Not Found")),
+		Entry("quota wording", errors.New("exceeded the limit of retries")),
+	)
+
+	It("keeps wrapped errors", func() {
+		e := &Error{Kind: ErrorKindQuota}
+		Expect(AsError(fmt.Errorf("create: %w", e))).To(BeIdenticalTo(e))
+		Expect(AsError(errors.New("connection reset")).Kind).To(Equal(ErrorKindRetryable))
+	})
+})
+
+var _ = Describe("Detail actions", func() {
+	var (
+		srv    *httptest.Server
+		client *Client
+	)
+
+	BeforeEach(func() {
+		// NCP answers the detail actions of missing resources with an empty list
+		srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			fmt.Fprint(w, "<response><returnCode>0</returnCode><totalRows>0</totalRows></response>")
+		}))
+		url := srv.URL + "/"
+		client = &Client{keyService: auth.NewKeyService("ak", "sk"), httpClient: srv.Client(),
+			baseURL: url, vpcURL: url, lbURL: url}
+	})
+
+	AfterEach(func() {
+		srv.Close()
+	})
+
+	DescribeTable("report an empty list as not found",
+		func(get func(ctx context.Context, c *Client) error) {
+			err := get(context.Background(), client)
+			Expect(err).To(HaveOccurred())
+			Expect(AsError(err).Kind).To(Equal(ErrorKindNotFound))
+			Expect(AsError(err).Terminal()).To(BeTrue())
+		},
+		Entry("server instance", func(ctx context.Context, c *Client) error {
+			_, err := c.GetServerInstanceDetail(ctx, "KR", "1")
+			return err
+		}),
+		Entry("block storage", func(ctx context.Context, c *Client) error {
+			_, err := c.GetBlockStorageInstanceDetail(ctx, "KR", "1")
+			return err
+		}),
+		Entry("block storage snapshot", func(ctx context.Context, c *Client) error {
+			_, err := c.GetBlockStorageSnapshotInstanceDetail(ctx, "KR", "1")
+			return err
+		}),
+		Entry("member server image", func(ctx context.Context, c *Client) error {
+			_, err := c.GetMemberServerImageInstanceDetail(ctx, "KR", "1")
+			return err
+		}),
+		Entry("placement group", func(ctx context.Context, c *Client) error {
+			_, err := c.GetPlacementGroupDetail(ctx, "KR", "1")
+			return err
+		}),
+		Entry("target group", func(ctx context.Context, c *Client) error {
+			_, err := c.GetTargetGroupDetail(ctx, "KR", "1")
+			return err
+		}),
+		Entry("load balancer", func(ctx context.Context, c *Client) error {
+			_, err := c.GetLoadBalancerInstanceDetail(ctx, "KR", "1")
+			return err
+		}),
+		Entry("subnet", func(ctx context.Context, c *Client) error {
+			_, err := c.GetSubnetDetail(ctx, "KR", "1")
+			return err
+		}),
+		Entry("network interface", func(ctx context.Context, c *Client) error {
+			_, err := c.GetNetworkInterfaceDetail(ctx, "KR", "1")
+			return err
+		}),
+	)
+})
@@ -0,0 +1,388 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ncp
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+)
+
+const (
+	loadBalancerAPIURL = "https://ncloud.apigw.ntruss.com/vloadbalancer/v2/"
+
+	createTargetGroupAction             = "createTargetGroup"
+	getTargetGroupDetailAction          = "getTargetGroupDetail"
+	getTargetGroupListAction            = "getTargetGroupList"
+	deleteTargetGroupsAction            = "deleteTargetGroups"
+	getTargetListAction                 = "getTargetList"
+	addTargetAction                     = "addTarget"
+	removeTargetAction                  = "removeTarget"
+	createLoadBalancerInstanceAction    = "createLoadBalancerInstance"
+	getLoadBalancerInstanceDetailAction = "getLoadBalancerInstanceDetail"
+	getLoadBalancerInstanceListAction   = "getLoadBalancerInstanceList"
+	deleteLoadBalancerInstancesAction   = "deleteLoadBalancerInstances"
+
+	LoadBalancerStatusInit    = "INIT"
+	LoadBalancerOperationNone = "NULL"
+
+	targetTypeServer = "VSVR"
+
+	loadBalancerListPageSize = 100
+)
+
+type TargetGroup struct {
+	TargetGroupNo           string     `xml:"targetGroupNo"`
+	TargetGroupName         string     `xml:"targetGroupName"`
+	TargetGroupProtocolType CommonCode `xml:"targetGroupProtocolType"`
+	TargetGroupPort         int        `xml:"targetGroupPort"`
+	VpcNo                   string     `xml:"vpcNo"`
+	LoadBalancerInstanceNo  string     `xml:"loadBalancerInstanceNo"`
+	TargetNoList            []string   `xml:"targetNoList>targetNo"`
+}
+
+type TargetGroupList struct {
+	ReturnCode      int           `xml:"returnCode"`
+	ReturnMessage   string        `xml:"returnMessage"`
+	TotalRows       int           `xml:"totalRows"`
+	TargetGroupList []TargetGroup `xml:"targetGroupList>targetGroup"`
+}
+
+// Target is a server of a target group and its health.
+type Target struct {
+	TargetNo          string     `xml:"targetNo"`
+	TargetName        string     `xml:"targetName"`
+	TargetIp          string     `xml:"targetIp"`
+	HealthCheckStatus CommonCode `xml:"healthCheckStatus"`
+}
+
+type TargetList struct {
+	ReturnCode    int      `xml:"returnCode"`
+	ReturnMessage string   `xml:"returnMessage"`
+	TotalRows     int      `xml:"totalRows"`
+	TargetList    []Target `xml:"targetList>target"`
+}
+
+// CreateTargetGroupRequest holds the parameters of createTargetGroup.
+type CreateTargetGroupRequest struct {
+	RegionCode             string
+	VpcNo                  string
+	TargetGroupName        string
+	TargetGroupDescription string
+	ProtocolTypeCode       string
+	Port                   int
+	// HealthCheckPort defaults to Port and HealthCheckURLPath and
+	// HealthCheckHTTPMethod only apply to HTTP and HTTPS checks.
+	HealthCheckProtocolTypeCode string
+	HealthCheckPort             int
+	HealthCheckURLPath          string
+	HealthCheckHTTPMethod       string
+	HealthCheckCycle            int
+	HealthCheckUpThreshold      int
+	HealthCheckDownThreshold    int
+}
+
+func (r *CreateTargetGroupRequest) values() url.Values {
+	v := regionValues(r.RegionCode)
+	v.Set("vpcNo", r.VpcNo)
+	v.Set("targetTypeCode", targetTypeServer)
+	v.Set("targetGroupProtocolTypeCode", r.ProtocolTypeCode)
+	v.Set("targetGroupPort", strconv.Itoa(r.Port))
+	if r.TargetGroupName != "" {
+		v.Set("targetGroupName", r.TargetGroupName)
+	}
+	if r.TargetGroupDescription != "" {
+		v.Set("targetGroupDescription", r.TargetGroupDescription)
+	}
+	v.Set("healthCheckProtocolTypeCode", r.HealthCheckProtocolTypeCode)
+	if r.HealthCheckPort > 0 {
+		v.Set("healthCheckPort", strconv.Itoa(r.HealthCheckPort))
+	}
+	if r.HealthCheckURLPath != "" {
+		v.Set("healthCheckUrlPath", r.HealthCheckURLPath)
+	}
+	if r.HealthCheckHTTPMethod != "" {
+		v.Set("healthCheckHttpMethodTypeCode", r.HealthCheckHTTPMethod)
+	}
+	if r.HealthCheckCycle > 0 {
+		v.Set("healthCheckCycle", strconv.Itoa(r.HealthCheckCycle))
+	}
+	if r.HealthCheckUpThreshold > 0 {
+		v.Set("healthCheckUpThreshold", strconv.Itoa(r.HealthCheckUpThreshold))
+	}
+	if r.HealthCheckDownThreshold > 0 {
+		v.Set("healthCheckDownThreshold", strconv.Itoa(r.HealthCheckDownThreshold))
+	}
+	return v
+}
+
+// CreateTargetGroup creates an empty target group of servers.
+func (c *Client) CreateTargetGroup(ctx context.Context, request *CreateTargetGroupRequest) (*TargetGroup, error) {
+	resp := &TargetGroupList{}
+	if err := c.callURL(ctx, c.lbURL, createTargetGroupAction, request.values(), resp); err != nil {
+		return nil, err
+	}
+	if len(resp.TargetGroupList) == 0 {
+		return nil, fmt.Errorf("%s returned no target group", createTargetGroupAction)
+	}
+	return &resp.TargetGroupList[0], nil
+}
+
+// GetTargetGroupDetail reads one target group.
+func (c *Client) GetTargetGroupDetail(ctx context.Context, regionCode, targetGroupNo string) (*TargetGroup, error) {
+	v := regionValues(regionCode)
+	v.Set("targetGroupNo", targetGroupNo)
+
+	resp := &TargetGroupList{}
+	if err := c.callURL(ctx, c.lbURL, getTargetGroupDetailAction, v, resp); err != nil {
+		return nil, err
+	}
+	if len(resp.TargetGroupList) == 0 {
+		return nil, notFound("target group", targetGroupNo)
+	}
+	return &resp.TargetGroupList[0], nil
+}
+
+// FindTargetGroup returns the target group of a VPC named name, or nil when
+// the VPC has none. NCP does not filter target groups by name, so every page
+// is read.
+func (c *Client) FindTargetGroup(ctx context.Context, regionCode, vpcNo, name string) (*TargetGroup, error) {
+	read := 0
+	for pageNo := 1; ; pageNo++ {
+		v := regionValues(regionCode)
+		v.Set("vpcNo", vpcNo)
+		v.Set("pageNo", strconv.Itoa(pageNo))
+		v.Set("pageSize", strconv.Itoa(loadBalancerListPageSize))
+
+		resp := &TargetGroupList{}
+		if err := c.callURL(ctx, c.lbURL, getTargetGroupListAction, v, resp); err != nil {
+			return nil, err
+		}
+		for i := range resp.TargetGroupList {
+			if resp.TargetGroupList[i].TargetGroupName == name {
+				return &resp.TargetGroupList[i], nil
+			}
+		}
+		read += len(resp.TargetGroupList)
+		if len(resp.TargetGroupList) < loadBalancerListPageSize || read >= resp.TotalRows {
+			return nil, nil
+		}
+	}
+}
+
+// DeleteTargetGroups deletes target groups no load balancer uses.
+func (c *Client) DeleteTargetGroups(ctx context.Context, regionCode string, targetGroupNos ...string) error {
+	v := regionValues(regionCode)
+	for i, no := range targetGroupNos {
+		v.Set(fmt.Sprintf("targetGroupNoList.%d", i+1), no)
+	}
+	return c.callURL(ctx, c.lbURL, deleteTargetGroupsAction, v, &TargetGroupList{})
+}
+
+// GetTargetList returns the targets of a target group.
+func (c *Client) GetTargetList(ctx context.Context, regionCode, targetGroupNo string) ([]Target, error) {
+	v := regionValues(regionCode)
+	v.Set("targetGroupNo", targetGroupNo)
+
+	resp := &TargetList{}
+	if err := c.callURL(ctx, c.lbURL, getTargetListAction, v, resp); err != nil {
+		return nil, err
+	}
+	return resp.TargetList, nil
+}
+
+// AddTarget adds servers to a target group.
+func (c *Client) AddTarget(ctx context.Context, regionCode, targetGroupNo string, serverInstanceNos []string) error {
+	return c.callURL(ctx, c.lbURL, addTargetAction, targetValues(regionCode, targetGroupNo, serverInstanceNos), &TargetGroupList{})
+}
+
+// RemoveTarget removes servers from a target group.
+func (c *Client) RemoveTarget(ctx context.Context, regionCode, targetGroupNo string, serverInstanceNos []string) error {
+	return c.callURL(ctx, c.lbURL, removeTargetAction, targetValues(regionCode, targetGroupNo, serverInstanceNos), &TargetGroupList{})
+}
+
+// DiffTargets returns the servers of desired missing from current, in the
+// order of desired, and the targets of current no longer desired.
+func DiffTargets(current []Target, desired []string) (add, remove []string) {
+	wanted := make(map[string]bool, len(desired))
+	for _, no := range desired {
+		wanted[no] = true
+	}
+	for _, t := range current {
+		if !wanted[t.TargetNo] {
+			remove = append(remove, t.TargetNo)
+		}
+		delete(wanted, t.TargetNo)
+	}
+	for _, no := range desired {
+		if wanted[no] {
+			add = append(add, no)
+			delete(wanted, no)
+		}
+	}
+	return add, remove
+}
+
+func targetValues(regionCode, targetGroupNo string, serverInstanceNos []string) url.Values {
+	v := regionValues(regionCode)
+	v.Set("targetGroupNo", targetGroupNo)
+	for i, no := range serverInstanceNos {
+		v.Set(fmt.Sprintf("targetNoList.%d", i+1), no)
+	}
+	return v
+}
+
+type LoadBalancerInstance struct {
+	LoadBalancerInstanceNo        string     `xml:"loadBalancerInstanceNo"`
+	LoadBalancerName              string     `xml:"loadBalancerName"`
+	LoadBalancerDomain            string     `xml:"loadBalancerDomain"`
+	LoadBalancerIpList            []string   `xml:"loadBalancerIpList>loadBalancerIp"`
+	LoadBalancerInstanceStatus    CommonCode `xml:"loadBalancerInstanceStatus"`
+	LoadBalancerInstanceOperation CommonCode `xml:"loadBalancerInstanceOperation"`
+	LoadBalancerType              CommonCode `xml:"loadBalancerType"`
+	VpcNo                         string     `xml:"vpcNo"`
+}
+
+// Ready reports whether the load balancer finished initializing and no operation is in progress.
+func (l *LoadBalancerInstance) Ready() bool {
+	operation := l.LoadBalancerInstanceOperation.Code
+	return l.LoadBalancerInstanceStatus.Code != LoadBalancerStatusInit &&
+		(operation == "" || operation == LoadBalancerOperationNone)
+}
+
+type LoadBalancerInstanceList struct {
+	ReturnCode               int                    `xml:"returnCode"`
+	ReturnMessage            string                 `xml:"returnMessage"`
+	TotalRows                int                    `xml:"totalRows"`
+	LoadBalancerInstanceList []LoadBalancerInstance `xml:"loadBalancerInstanceList>loadBalancerInstance"`
+}
+
+// LoadBalancerListener is one entry of loadBalancerListenerList.N.
+type LoadBalancerListener struct {
+	ProtocolTypeCode string
+	Port             int
+	TargetGroupNo    string
+	SSLCertificateNo string
+}
+
+// CreateLoadBalancerInstanceRequest holds the parameters of createLoadBalancerInstance.
+type CreateLoadBalancerInstanceRequest struct {
+	RegionCode                  string
+	VpcNo                       string
+	LoadBalancerName            string
+	LoadBalancerDescription     string
+	LoadBalancerTypeCode        string
+	LoadBalancerNetworkTypeCode string
+	IdleTimeout                 int
+	SubnetNoList                []string
+	LoadBalancerListenerList    []LoadBalancerListener
+}
+
+func (r *CreateLoadBalancerInstanceRequest) values() url.Values {
+	v := regionValues(r.RegionCode)
+	v.Set("vpcNo", r.VpcNo)
+	v.Set("loadBalancerTypeCode", r.LoadBalancerTypeCode)
+	if r.LoadBalancerNetworkTypeCode != "" {
+		v.Set("loadBalancerNetworkTypeCode", r.LoadBalancerNetworkTypeCode)
+	}
+	if r.LoadBalancerName != "" {
+		v.Set("loadBalancerName", r.LoadBalancerName)
+	}
+	if r.LoadBalancerDescription != "" {
+		v.Set("loadBalancerDescription", r.LoadBalancerDescription)
+	}
+	if r.IdleTimeout > 0 {
+		v.Set("idleTimeout", strconv.Itoa(r.IdleTimeout))
+	}
+	for i, no := range r.SubnetNoList {
+		v.Set(fmt.Sprintf("subnetNoList.%d", i+1), no)
+	}
+	for i, l := range r.LoadBalancerListenerList {
+		prefix := fmt.Sprintf("loadBalancerListenerList.%d.", i+1)
+		v.Set(prefix+"protocolTypeCode", l.ProtocolTypeCode)
+		v.Set(prefix+"port", strconv.Itoa(l.Port))
+		v.Set(prefix+"targetGroupNo", l.TargetGroupNo)
+		if l.SSLCertificateNo != "" {
+			v.Set(prefix+"sslCertificateNo", l.SSLCertificateNo)
+		}
+	}
+	return v
+}
+
+// CreateLoadBalancerInstance requests a load balancer with its listeners.
+func (c *Client) CreateLoadBalancerInstance(ctx context.Context, request *CreateLoadBalancerInstanceRequest) (*LoadBalancerInstance, error) {
+	resp := &LoadBalancerInstanceList{}
+	if err := c.callURL(ctx, c.lbURL, createLoadBalancerInstanceAction, request.values(), resp); err != nil {
+		return nil, err
+	}
+	if len(resp.LoadBalancerInstanceList) == 0 {
+		return nil, fmt.Errorf("%s returned no load balancer", createLoadBalancerInstanceAction)
+	}
+	return &resp.LoadBalancerInstanceList[0], nil
+}
+
+// GetLoadBalancerInstanceDetail reads one load balancer.
+func (c *Client) GetLoadBalancerInstanceDetail(ctx context.Context, regionCode, loadBalancerInstanceNo string) (*LoadBalancerInstance, error) {
+	v := regionValues(regionCode)
+	v.Set("loadBalancerInstanceNo", loadBalancerInstanceNo)
+
+	resp := &LoadBalancerInstanceList{}
+	if err := c.callURL(ctx, c.lbURL, getLoadBalancerInstanceDetailAction, v, resp); err != nil {
+		return nil, err
+	}
+	if len(resp.LoadBalancerInstanceList) == 0 {
+		return nil, notFound("load balancer", loadBalancerInstanceNo)
+	}
+	return &resp.LoadBalancerInstanceList[0], nil
+}
+
+// FindLoadBalancerInstance returns the load balancer of a VPC named name, or
+// nil when the VPC has none. NCP does not filter load balancers by name, so
+// every page is read.
+func (c *Client) FindLoadBalancerInstance(ctx context.Context, regionCode, vpcNo, name string) (*LoadBalancerInstance, error) {
+	read := 0
+	for pageNo := 1; ; pageNo++ {
+		v := regionValues(regionCode)
+		v.Set("vpcNo", vpcNo)
+		v.Set("pageNo", strconv.Itoa(pageNo))
+		v.Set("pageSize", strconv.Itoa(loadBalancerListPageSize))
+
+		resp := &LoadBalancerInstanceList{}
+		if err := c.callURL(ctx, c.lbURL, getLoadBalancerInstanceListAction, v, resp); err != nil {
+			return nil, err
+		}
+		for i := range resp.LoadBalancerInstanceList {
+			if resp.LoadBalancerInstanceList[i].LoadBalancerName == name {
+				return &resp.LoadBalancerInstanceList[i], nil
+			}
+		}
+		read += len(resp.LoadBalancerInstanceList)
+		if len(resp.LoadBalancerInstanceList) < loadBalancerListPageSize || read >= resp.TotalRows {
+			return nil, nil
+		}
+	}
+}
+
+// DeleteLoadBalancerInstances deletes load balancers.
+func (c *Client) DeleteLoadBalancerInstances(ctx context.Context, regionCode string, loadBalancerInstanceNos ...string) error {
+	v := regionValues(regionCode)
+	for i, no := range loadBalancerInstanceNos {
+		v.Set(fmt.Sprintf("loadBalancerInstanceNoList.%d", i+1), no)
+	}
+	return c.callURL(ctx, c.lbURL, deleteLoadBalancerInstancesAction, v, &LoadBalancerInstanceList{})
+}
@@ -0,0 +1,185 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ncp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloud-club/Aviator-service/types/auth"
+)
+
+var _ = Describe("CreateTargetGroupRequest", func() {
+	It("creates a server target group and leaves unset health check fields to NCP", func() {
+		req := &CreateTargetGroupRequest{
+			RegionCode:                  "KR",
+			VpcNo:                       "52833",
+			ProtocolTypeCode:            "TCP",
+			Port:                        5432,
+			HealthCheckProtocolTypeCode: "TCP",
+		}
+
+		v := req.values()
+		Expect(v.Get("targetTypeCode")).To(Equal("VSVR"))
+		Expect(v.Get("targetGroupProtocolTypeCode")).To(Equal("TCP"))
+		Expect(v.Get("targetGroupPort")).To(Equal("5432"))
+		Expect(v.Get("healthCheckProtocolTypeCode")).To(Equal("TCP"))
+		Expect(v.Has("healthCheckPort")).To(BeFalse())
+		Expect(v.Has("healthCheckUrlPath")).To(BeFalse())
+		Expect(v.Has("targetGroupName")).To(BeFalse())
+	})
+
+	It("encodes an HTTP health check", func() {
+		req := &CreateTargetGroupRequest{
+			RegionCode:                  "KR",
+			VpcNo:                       "52833",
+			TargetGroupName:             "web",
+			ProtocolTypeCode:            "HTTP",
+			Port:                        8080,
+			HealthCheckProtocolTypeCode: "HTTP",
+			HealthCheckPort:             8081,
+			HealthCheckURLPath:          "/healthz",
+			HealthCheckHTTPMethod:       "GET",
+			HealthCheckCycle:            30,
+			HealthCheckUpThreshold:      2,
+			HealthCheckDownThreshold:    3,
+		}
+
+		v := req.values()
+		Expect(v.Get("targetGroupName")).To(Equal("web"))
+		Expect(v.Get("healthCheckProtocolTypeCode")).To(Equal("HTTP"))
+		Expect(v.Get("healthCheckPort")).To(Equal("8081"))
+		Expect(v.Get("healthCheckUrlPath")).To(Equal("/healthz"))
+		Expect(v.Get("healthCheckHttpMethodTypeCode")).To(Equal("GET"))
+		Expect(v.Get("healthCheckCycle")).To(Equal("30"))
+		Expect(v.Get("healthCheckUpThreshold")).To(Equal("2"))
+		Expect(v.Get("healthCheckDownThreshold")).To(Equal("3"))
+	})
+})
+
+var _ = DescribeTable("DiffTargets",
+	func(current []string, desired []string, add, remove []string) {
+		targets := make([]Target, 0, len(current))
+		for _, no := range current {
+			targets = append(targets, Target{TargetNo: no})
+		}
+		gotAdd, gotRemove := DiffTargets(targets, desired)
+		Expect(gotAdd).To(Equal(add))
+		Expect(gotRemove).To(Equal(remove))
+	},
+	Entry("adds every desired server to an empty group", nil, []string{"1", "2"}, []string{"1", "2"}, nil),
+	Entry("removes every target when nothing is desired", []string{"1", "2"}, nil, nil, []string{"1", "2"}),
+	Entry("leaves a group in line alone", []string{"1", "2"}, []string{"2", "1"}, nil, nil),
+	Entry("adds and removes the difference", []string{"1", "3"}, []string{"1", "2"}, []string{"2"}, []string{"3"}),
+	Entry("adds a server desired twice once", nil, []string{"1", "1"}, []string{"1"}, nil),
+)
+
+var _ = Describe("Find by name", func() {
+	var (
+		srv    *httptest.Server
+		client *Client
+		pages  []string
+	)
+
+	BeforeEach(func() {
+		pages = nil
+		srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			q := req.URL.Query()
+			pages = append(pages, q.Get("vpcNo")+"/"+q.Get("pageNo"))
+			pageNo, _ := strconv.Atoi(q.Get("pageNo"))
+			// two pages: a full one of unrelated names, then the match
+			count, name := loadBalancerListPageSize, "other"
+			if pageNo == 2 {
+				count, name = 1, "web"
+			}
+			switch req.URL.Path {
+			case "/" + getTargetGroupListAction:
+				fmt.Fprintf(w, "<getTargetGroupListResponse><totalRows>%d</totalRows><targetGroupList>", loadBalancerListPageSize+1)
+				for i := 0; i < count; i++ {
+					fmt.Fprintf(w, "<targetGroup><targetGroupNo>%d%d</targetGroupNo><targetGroupName>%s</targetGroupName></targetGroup>", pageNo, i, name)
+				}
+				fmt.Fprint(w, "</targetGroupList></getTargetGroupListResponse>")
+			case "/" + getLoadBalancerInstanceListAction:
+				fmt.Fprintf(w, "<getLoadBalancerInstanceListResponse><totalRows>%d</totalRows><loadBalancerInstanceList>", loadBalancerListPageSize+1)
+				for i := 0; i < count; i++ {
+					fmt.Fprintf(w, "<loadBalancerInstance><loadBalancerInstanceNo>%d%d</loadBalancerInstanceNo><loadBalancerName>%s</loadBalancerName></loadBalancerInstance>", pageNo, i, name)
+				}
+				fmt.Fprint(w, "</loadBalancerInstanceList></getLoadBalancerInstanceListResponse>")
+			default:
+				http.NotFound(w, req)
+			}
+		}))
+		client = &Client{keyService: auth.NewKeyService("ak", "sk"), httpClient: srv.Client(), lbURL: srv.URL + "/"}
+	})
+
+	AfterEach(func() {
+		srv.Close()
+	})
+
+	It("finds a target group on a later page", func() {
+		group, err := client.FindTargetGroup(context.Background(), "KR", "52833", "web")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(group).NotTo(BeNil())
+		Expect(group.TargetGroupNo).To(Equal("20"))
+		Expect(pages).To(Equal([]string{"52833/1", "52833/2"}))
+	})
+
+	It("returns nil when no target group has the name", func() {
+		group, err := client.FindTargetGroup(context.Background(), "KR", "52833", "api")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(group).To(BeNil())
+		Expect(pages).To(Equal([]string{"52833/1", "52833/2"}))
+	})
+
+	It("finds a load balancer on a later page", func() {
+		instance, err := client.FindLoadBalancerInstance(context.Background(), "KR", "52833", "web")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(instance).NotTo(BeNil())
+		Expect(instance.LoadBalancerInstanceNo).To(Equal("20"))
+	})
+})
+
+var _ = Describe("CreateLoadBalancerInstanceRequest", func() {
+	It("numbers subnets and listeners", func() {
+		req := &CreateLoadBalancerInstanceRequest{
+			RegionCode:           "KR",
+			VpcNo:                "52833",
+			LoadBalancerTypeCode: "APPLICATION",
+			SubnetNoList:         []string{"120330", "120331"},
+			LoadBalancerListenerList: []LoadBalancerListener{
+				{ProtocolTypeCode: "HTTP", Port: 80, TargetGroupNo: "11"},
+				{ProtocolTypeCode: "HTTPS", Port: 443, TargetGroupNo: "11", SSLCertificateNo: "2203"},
+			},
+		}
+
+		v := req.values()
+		Expect(v.Get("subnetNoList.1")).To(Equal("120330"))
+		Expect(v.Get("subnetNoList.2")).To(Equal("120331"))
+		Expect(v.Get("loadBalancerListenerList.1.protocolTypeCode")).To(Equal("HTTP"))
+		Expect(v.Get("loadBalancerListenerList.1.port")).To(Equal("80"))
+		Expect(v.Has("loadBalancerListenerList.1.sslCertificateNo")).To(BeFalse())
+		Expect(v.Get("loadBalancerListenerList.2.targetGroupNo")).To(Equal("11"))
+		Expect(v.Get("loadBalancerListenerList.2.sslCertificateNo")).To(Equal("2203"))
+		Expect(v.Has("idleTimeout")).To(BeFalse())
+	})
+})
@@ -0,0 +1,130 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ncp
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	createMemberServerImageInstanceAction    = "createMemberServerImageInstance"
+	getMemberServerImageInstanceDetailAction = "getMemberServerImageInstanceDetail"
+	getMemberServerImageInstanceListAction   = "getMemberServerImageInstanceList"
+	deleteMemberServerImageInstancesAction   = "deleteMemberServerImageInstances"
+
+	MemberServerImageStatusInit    = "INIT"
+	MemberServerImageStatusCreated = "CREAT"
+)
+
+type MemberServerImageInstance struct {
+	MemberServerImageInstanceNo        string     `xml:"memberServerImageInstanceNo"`
+	MemberServerImageName              string     `xml:"memberServerImageName"`
+	MemberServerImageDescription       string     `xml:"memberServerImageDescription"`
+	OriginalServerInstanceNo           string     `xml:"originalServerInstanceNo"`
+	ServerImageProductCode             string     `xml:"serverImageProductCode"`
+	MemberServerImageInstanceStatus    CommonCode `xml:"memberServerImageInstanceStatus"`
+	MemberServerImageInstanceOperation CommonCode `xml:"memberServerImageInstanceOperation"`
+	MemberServerImageBlockStorageTotal int64      `xml:"memberServerImageBlockStorageTotalSize"`
+	CreateDate                         string     `xml:"createDate"`
+}
+
+// Available reports whether the image was created and can boot servers.
+func (m *MemberServerImageInstance) Available() bool {
+	return m.MemberServerImageInstanceStatus.Code == MemberServerImageStatusCreated &&
+		(m.MemberServerImageInstanceOperation.Code == "" || m.MemberServerImageInstanceOperation.Code == ServerOperationNone)
+}
+
+// Creating reports whether NCP is still creating the image, so that any other
+// status that is not Available means the creation failed.
+func (m *MemberServerImageInstance) Creating() bool {
+	switch m.MemberServerImageInstanceStatus.Code {
+	case MemberServerImageStatusInit:
+		return true
+	case MemberServerImageStatusCreated:
+		return !m.Available()
+	}
+	return false
+}
+
+type MemberServerImageInstanceList struct {
+	ReturnCode                    int                         `xml:"returnCode"`
+	ReturnMessage                 string                      `xml:"returnMessage"`
+	TotalRows                     int                         `xml:"totalRows"`
+	MemberServerImageInstanceList []MemberServerImageInstance `xml:"memberServerImageInstanceList>memberServerImageInstance"`
+}
+
+// CreateMemberServerImageInstance starts creating an image of a server.
+func (c *Client) CreateMemberServerImageInstance(ctx context.Context, regionCode, serverInstanceNo, name, description string) (*MemberServerImageInstance, error) {
+	v := regionValues(regionCode)
+	v.Set("serverInstanceNo", serverInstanceNo)
+	v.Set("memberServerImageName", name)
+	if description != "" {
+		v.Set("memberServerImageDescription", description)
+	}
+
+	resp := &MemberServerImageInstanceList{}
+	if err := c.call(ctx, createMemberServerImageInstanceAction, v, resp); err != nil {
+		return nil, err
+	}
+	if len(resp.MemberServerImageInstanceList) == 0 {
+		return nil, fmt.Errorf("%s returned no image", createMemberServerImageInstanceAction)
+	}
+	return &resp.MemberServerImageInstanceList[0], nil
+}
+
+// GetMemberServerImageInstanceDetail reads one image.
+func (c *Client) GetMemberServerImageInstanceDetail(ctx context.Context, regionCode, memberServerImageInstanceNo string) (*MemberServerImageInstance, error) {
+	v := regionValues(regionCode)
+	v.Set("memberServerImageInstanceNo", memberServerImageInstanceNo)
+
+	resp := &MemberServerImageInstanceList{}
+	if err := c.call(ctx, getMemberServerImageInstanceDetailAction, v, resp); err != nil {
+		return nil, err
+	}
+	if len(resp.MemberServerImageInstanceList) == 0 {
+		return nil, notFound("member server image", memberServerImageInstanceNo)
+	}
+	return &resp.MemberServerImageInstanceList[0], nil
+}
+
+// FindMemberServerImageInstance returns the image named name, or nil when the
+// region has none.
+func (c *Client) FindMemberServerImageInstance(ctx context.Context, regionCode, name string) (*MemberServerImageInstance, error) {
+	v := regionValues(regionCode)
+	v.Set("memberServerImageName", name)
+
+	resp := &MemberServerImageInstanceList{}
+	if err := c.call(ctx, getMemberServerImageInstanceListAction, v, resp); err != nil {
+		return nil, err
+	}
+	for i := range resp.MemberServerImageInstanceList {
+		if resp.MemberServerImageInstanceList[i].MemberServerImageName == name {
+			return &resp.MemberServerImageInstanceList[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// DeleteMemberServerImageInstances deletes images.
+func (c *Client) DeleteMemberServerImageInstances(ctx context.Context, regionCode string, memberServerImageInstanceNos ...string) error {
+	v := regionValues(regionCode)
+	for i, no := range memberServerImageInstanceNos {
+		v.Set(fmt.Sprintf("memberServerImageInstanceNoList.%d", i+1), no)
+	}
+	return c.call(ctx, deleteMemberServerImageInstancesAction, v, &MemberServerImageInstanceList{})
+}
@@ -0,0 +1,39 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ncp
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("MemberServerImageInstance", func() {
+	DescribeTable("tells an image being created from an available or failed one",
+		func(status, operation string, creating, available bool) {
+			image := &MemberServerImageInstance{
+				MemberServerImageInstanceStatus:    CommonCode{Code: status},
+				MemberServerImageInstanceOperation: CommonCode{Code: operation},
+			}
+			Expect(image.Creating()).To(Equal(creating))
+			Expect(image.Available()).To(Equal(available))
+		},
+		Entry("initializing", MemberServerImageStatusInit, "CREAT", true, false),
+		Entry("created", MemberServerImageStatusCreated, ServerOperationNone, false, true),
+		Entry("created, still settling", MemberServerImageStatusCreated, "CREAT", true, false),
+		Entry("unknown status", "FAIL", ServerOperationNone, false, false),
+	)
+})
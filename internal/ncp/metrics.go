@@ -0,0 +1,59 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ncp
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+var (
+	apiRequests = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "aviator_ncp_api_requests_total",
+		Help: "NCP API calls by operation and HTTP status code, 0 when it is unknown.",
+	}, []string{"operation", "code"})
+
+	apiRequestDuration = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "aviator_ncp_api_request_duration_seconds",
+		Help:    "Latency of NCP API calls by operation, including time spent waiting for the rate limiter.",
+		Buckets: prometheus.ExponentialBuckets(0.05, 2, 10),
+	}, []string{"operation"})
+
+	apiErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "aviator_ncp_api_errors_total",
+		Help: "Failed NCP API calls by operation, error kind and NCP error code.",
+	}, []string{"operation", "kind", "error_code"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(apiRequests, apiRequestDuration, apiErrors)
+}
+
+// observeCall records a finished call to operation started at start.
+func observeCall(operation string, start time.Time, err error) {
+	apiRequestDuration.WithLabelValues(operation).Observe(time.Since(start).Seconds())
+	if err == nil {
+		apiRequests.WithLabelValues(operation, "200").Inc()
+		return
+	}
+	e := AsError(err)
+	apiRequests.WithLabelValues(operation, strconv.Itoa(e.StatusCode)).Inc()
+	apiErrors.WithLabelValues(operation, string(e.Kind), e.Code).Inc()
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ncp
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+)
+
+const (
+	getNetworkInterfaceDetailAction                = "getNetworkInterfaceDetail"
+	addNetworkInterfaceAccessControlGroupAction    = "addNetworkInterfaceAccessControlGroup"
+	removeNetworkInterfaceAccessControlGroupAction = "removeNetworkInterfaceAccessControlGroup"
+)
+
+type NetworkInterface struct {
+	NetworkInterfaceNo       string   `xml:"networkInterfaceNo"`
+	NetworkInterfaceName     string   `xml:"networkInterfaceName"`
+	SubnetNo                 string   `xml:"subnetNo"`
+	IsDefault                bool     `xml:"isDefault"`
+	IP                       string   `xml:"ip"`
+	InstanceNo               string   `xml:"instanceNo"`
+	AccessControlGroupNoList []string `xml:"accessControlGroupNoList>accessControlGroupNo"`
+}
+
+type NetworkInterfaceList struct {
+	ReturnCode           int                `xml:"returnCode"`
+	ReturnMessage        string             `xml:"returnMessage"`
+	TotalRows            int                `xml:"totalRows"`
+	NetworkInterfaceList []NetworkInterface `xml:"networkInterfaceList>networkInterface"`
+}
+
+// GetNetworkInterfaceDetail returns a network interface with its access control groups.
+func (c *Client) GetNetworkInterfaceDetail(ctx context.Context, regionCode, networkInterfaceNo string) (*NetworkInterface, error) {
+	v := regionValues(regionCode)
+	v.Set("networkInterfaceNo", networkInterfaceNo)
+
+	resp := &NetworkInterfaceList{}
+	if err := c.call(ctx, getNetworkInterfaceDetailAction, v, resp); err != nil {
+		return nil, err
+	}
+	if len(resp.NetworkInterfaceList) == 0 {
+		return nil, notFound("network interface", networkInterfaceNo)
+	}
+	return &resp.NetworkInterfaceList[0], nil
+}
+
+// AddNetworkInterfaceAccessControlGroup applies access control groups to a network interface.
+func (c *Client) AddNetworkInterfaceAccessControlGroup(ctx context.Context, regionCode, vpcNo, networkInterfaceNo string, acgNoList []string) error {
+	return c.call(ctx, addNetworkInterfaceAccessControlGroupAction,
+		accessControlGroupValues(regionCode, vpcNo, networkInterfaceNo, acgNoList), &NetworkInterfaceList{})
+}
+
+// RemoveNetworkInterfaceAccessControlGroup detaches access control groups from a network interface.
+func (c *Client) RemoveNetworkInterfaceAccessControlGroup(ctx context.Context, regionCode, vpcNo, networkInterfaceNo string, acgNoList []string) error {
+	return c.call(ctx, removeNetworkInterfaceAccessControlGroupAction,
+		accessControlGroupValues(regionCode, vpcNo, networkInterfaceNo, acgNoList), &NetworkInterfaceList{})
+}
+
+func accessControlGroupValues(regionCode, vpcNo, networkInterfaceNo string, acgNoList []string) url.Values {
+	v := regionValues(regionCode)
+	v.Set("vpcNo", vpcNo)
+	v.Set("networkInterfaceNo", networkInterfaceNo)
+	for i, no := range acgNoList {
+		v.Set(fmt.Sprintf("accessControlGroupNoList.%d", i+1), no)
+	}
+	return v
+}
@@ -0,0 +1,98 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ncp
+
+import (
+	"context"
+	"fmt"
+)
+
+const (
+	createPlacementGroupAction    = "createPlacementGroup"
+	getPlacementGroupDetailAction = "getPlacementGroupDetail"
+	getPlacementGroupListAction   = "getPlacementGroupList"
+	deletePlacementGroupAction    = "deletePlacementGroup"
+)
+
+type PlacementGroup struct {
+	PlacementGroupNo   string     `xml:"placementGroupNo"`
+	PlacementGroupName string     `xml:"placementGroupName"`
+	PlacementGroupType CommonCode `xml:"placementGroupType"`
+}
+
+type PlacementGroupList struct {
+	ReturnCode         int              `xml:"returnCode"`
+	ReturnMessage      string           `xml:"returnMessage"`
+	TotalRows          int              `xml:"totalRows"`
+	PlacementGroupList []PlacementGroup `xml:"placementGroupList>placementGroup"`
+}
+
+// CreatePlacementGroup creates a placement group of typeCode, e.g. AA.
+func (c *Client) CreatePlacementGroup(ctx context.Context, regionCode, name, typeCode string) (*PlacementGroup, error) {
+	v := regionValues(regionCode)
+	v.Set("placementGroupName", name)
+	v.Set("placementGroupTypeCode", typeCode)
+
+	resp := &PlacementGroupList{}
+	if err := c.call(ctx, createPlacementGroupAction, v, resp); err != nil {
+		return nil, err
+	}
+	if len(resp.PlacementGroupList) == 0 {
+		return nil, fmt.Errorf("%s returned no placement group", createPlacementGroupAction)
+	}
+	return &resp.PlacementGroupList[0], nil
+}
+
+// GetPlacementGroupDetail reads one placement group.
+func (c *Client) GetPlacementGroupDetail(ctx context.Context, regionCode, placementGroupNo string) (*PlacementGroup, error) {
+	v := regionValues(regionCode)
+	v.Set("placementGroupNo", placementGroupNo)
+
+	resp := &PlacementGroupList{}
+	if err := c.call(ctx, getPlacementGroupDetailAction, v, resp); err != nil {
+		return nil, err
+	}
+	if len(resp.PlacementGroupList) == 0 {
+		return nil, notFound("placement group", placementGroupNo)
+	}
+	return &resp.PlacementGroupList[0], nil
+}
+
+// FindPlacementGroup returns the placement group named name, or nil when the
+// region has none.
+func (c *Client) FindPlacementGroup(ctx context.Context, regionCode, name string) (*PlacementGroup, error) {
+	v := regionValues(regionCode)
+	v.Set("placementGroupName", name)
+
+	resp := &PlacementGroupList{}
+	if err := c.call(ctx, getPlacementGroupListAction, v, resp); err != nil {
+		return nil, err
+	}
+	for i := range resp.PlacementGroupList {
+		if resp.PlacementGroupList[i].PlacementGroupName == name {
+			return &resp.PlacementGroupList[i], nil
+		}
+	}
+	return nil, nil
+}
+
+// DeletePlacementGroup deletes a placement group, which must have no servers.
+func (c *Client) DeletePlacementGroup(ctx context.Context, regionCode, placementGroupNo string) error {
+	v := regionValues(regionCode)
+	v.Set("placementGroupNo", placementGroupNo)
+	return c.call(ctx, deletePlacementGroupAction, v, &PlacementGroupList{})
+}
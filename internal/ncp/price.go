@@ -0,0 +1,83 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ncp
+
+import (
+	"context"
+	"strconv"
+)
+
+const (
+	billingAPIURL             = "https://billingapi.apigw.ntruss.com/billing/v1/"
+	getProductPriceListAction = "product/getProductPriceList"
+	priceListPageSize         = 1000
+
+	// ProductItemKindServer and ProductItemKindBlockStorage select the
+	// products getProductPriceList returns.
+	ProductItemKindServer       = "VSVR"
+	ProductItemKindBlockStorage = "BST"
+
+	// PriceTypeMeterRate is charged per hour of use, PriceTypeFixedSum per
+	// month. They match the feeSystemTypeCode a server is created with.
+	PriceTypeMeterRate = "MTRAT"
+	PriceTypeFixedSum  = "FXSUM"
+)
+
+type ProductPrice struct {
+	ProductCode     string     `xml:"productCode"`
+	ProductItemKind CommonCode `xml:"productItemKind"`
+	ProductType     CommonCode `xml:"productType"`
+	PriceList       []Price    `xml:"priceList>price"`
+}
+
+// Price is one way a product is charged. Unit is the period or quantity the
+// price is for, e.g. HOUR, MONTH or GB.
+type Price struct {
+	PriceType   CommonCode `xml:"priceType"`
+	RegionCode  string     `xml:"region>regionCode"`
+	Unit        CommonCode `xml:"unit"`
+	Price       float64    `xml:"price"`
+	PayCurrency CommonCode `xml:"payCurrency"`
+}
+
+type ProductPriceList struct {
+	ReturnCode       int            `xml:"returnCode"`
+	ReturnMessage    string         `xml:"returnMessage"`
+	TotalRows        int            `xml:"totalRows"`
+	ProductPriceList []ProductPrice `xml:"productPriceList>productPrice"`
+}
+
+// GetProductPriceList lists the prices of a kind of product in a region from
+// the billing API.
+func (c *Client) GetProductPriceList(ctx context.Context, regionCode, productItemKindCode string) ([]ProductPrice, error) {
+	var prices []ProductPrice
+	for pageNo := 1; ; pageNo++ {
+		v := regionValues(regionCode)
+		v.Set("productItemKindCode", productItemKindCode)
+		v.Set("pageNo", strconv.Itoa(pageNo))
+		v.Set("pageSize", strconv.Itoa(priceListPageSize))
+
+		resp := &ProductPriceList{}
+		if err := c.callURL(ctx, c.billingURL, getProductPriceListAction, v, resp); err != nil {
+			return nil, err
+		}
+		prices = append(prices, resp.ProductPriceList...)
+		if len(resp.ProductPriceList) < priceListPageSize || len(prices) >= resp.TotalRows {
+			return prices, nil
+		}
+	}
+}
@@ -0,0 +1,104 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ncp
+
+import (
+	"context"
+	"path"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	ncputil "github.com/cloud-club/Aviator-service/pkg"
+	server "github.com/cloud-club/Aviator-service/types/server"
+)
+
+// RateLimitedServerService wraps an Aviator-service ServerInterface so that
+// its calls take tokens from the same limiter as the Client, are counted in
+// the same metrics and traced, and fail with an *Error when NCP rejected
+// them. The status polling Update and Delete do internally is neither
+// limited, counted nor traced.
+type RateLimitedServerService struct {
+	ncputil.ServerInterface
+	limiter *rate.Limiter
+	ctx     context.Context
+}
+
+var _ ncputil.ServerInterface = &RateLimitedServerService{}
+
+func NewRateLimitedServerService(service ncputil.ServerInterface, limiter *rate.Limiter) *RateLimitedServerService {
+	return &RateLimitedServerService{ServerInterface: service, limiter: limiter, ctx: context.Background()}
+}
+
+// WithContext returns service bound to ctx, which cancels waiting for the
+// limiter and parents the spans of its calls. The Aviator-service takes no
+// context, so this is how callers pass theirs.
+func WithContext(ctx context.Context, service ncputil.ServerInterface) ncputil.ServerInterface {
+	s, ok := service.(*RateLimitedServerService)
+	if !ok {
+		return service
+	}
+	bound := *s
+	bound.ctx = ctx
+	return &bound
+}
+
+func (s *RateLimitedServerService) List(url string, request *server.ListServerRequest) (*server.ListServerResponse, error) {
+	return limitedCall(s, url, func() (*server.ListServerResponse, error) { return s.ServerInterface.List(url, request) })
+}
+
+func (s *RateLimitedServerService) Create(url string, request *server.CreateServerRequest, params []int) (*server.CreateServerResponse, error) {
+	return limitedCall(s, url, func() (*server.CreateServerResponse, error) {
+		return s.ServerInterface.Create(url, request, params)
+	})
+}
+
+func (s *RateLimitedServerService) Update(url string, request *server.UpdateServerRequest) (*server.UpdateServerResponse, error) {
+	return limitedCall(s, url, func() (*server.UpdateServerResponse, error) { return s.ServerInterface.Update(url, request) })
+}
+
+func (s *RateLimitedServerService) Start(url string, request *server.StartServerRequest) (*server.StartServerResponse, error) {
+	return limitedCall(s, url, func() (*server.StartServerResponse, error) { return s.ServerInterface.Start(url, request) })
+}
+
+func (s *RateLimitedServerService) Stop(url string, request *server.StopServerRequest) (*server.StopServerResponse, error) {
+	return limitedCall(s, url, func() (*server.StopServerResponse, error) { return s.ServerInterface.Stop(url, request) })
+}
+
+func (s *RateLimitedServerService) Delete(url string, request *server.DeleteServerRequest) (*server.DeleteServerResponse, error) {
+	return limitedCall(s, url, func() (*server.DeleteServerResponse, error) { return s.ServerInterface.Delete(url, request) })
+}
+
+// limitedCall makes call, the Aviator-service call to url, once the limiter
+// allows it, tracing it and counting it in the metrics.
+func limitedCall[T any](s *RateLimitedServerService, url string, call func() (T, error)) (resp T, err error) {
+	ctx, span := startSpan(s.ctx, operation(url), "")
+	defer func(start time.Time) {
+		observeCall(operation(url), start, err)
+		endSpan(span, 0, "", err)
+	}(time.Now())
+	if err := s.limiter.Wait(ctx); err != nil {
+		return resp, err
+	}
+	resp, err = call()
+	return resp, serviceError(err)
+}
+
+// operation names a call of the Aviator-service by the action its URL ends with.
+func operation(url string) string {
+	return path.Base(url)
+}
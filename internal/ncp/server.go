@@ -0,0 +1,263 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ncp
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strconv"
+
+	server "github.com/cloud-club/Aviator-service/types/server"
+)
+
+const createServerInstancesAction = "createServerInstances"
+
+// BlockStorageMapping is one entry of blockStorageMappingList.N.
+type BlockStorageMapping struct {
+	Order                      int
+	SnapshotInstanceNo         string
+	BlockStorageSize           int
+	BlockStorageName           string
+	BlockStorageVolumeTypeCode string
+	Encrypted                  bool
+}
+
+// CreateServerInstancesRequest extends server.CreateServerRequest with the
+// list parameters its reflection based encoder cannot express.
+type CreateServerInstancesRequest struct {
+	server.CreateServerRequest
+	RegionCode        string
+	ServerName        string
+	ServerDescription string
+	// Optional parameters the embedded request leaves out, sent when set.
+	AssociateWithPublicIp             bool
+	IsProtectServerTermination        bool
+	IsEncryptedBaseBlockStorageVolume bool
+	LoginKeyName                      string
+	FeeSystemTypeCode                 string
+	InitScriptNo                      string
+	// MemberServerImageInstanceNo boots the server from a member server
+	// image instead of ServerImageProductCode.
+	MemberServerImageInstanceNo string
+	PlacementGroupNo            string
+	BlockStorageMappingList     []BlockStorageMapping
+}
+
+func (r *CreateServerInstancesRequest) values() url.Values {
+	v := regionValues(r.RegionCode)
+	if r.MemberServerImageInstanceNo != "" {
+		v.Set("memberServerImageInstanceNo", r.MemberServerImageInstanceNo)
+	} else {
+		v.Set("serverImageProductCode", r.ServerImageProductCode)
+	}
+	v.Set("vpcNo", r.VpcNo)
+	v.Set("subnetNo", r.SubnetNo)
+	v.Set("serverProductCode", r.ServerProductCode)
+	v.Set("networkInterfaceList.1.networkInterfaceOrder", strconv.Itoa(r.NetworkInterfaceOrder))
+	v.Set("networkInterfaceList.1.accessControlGroupNoList.1", r.AccessControlGroupNoListN)
+	if r.ServerName != "" {
+		v.Set("serverName", r.ServerName)
+	}
+	if r.ServerDescription != "" {
+		v.Set("serverDescription", r.ServerDescription)
+	}
+	if r.PlacementGroupNo != "" {
+		v.Set("placementGroupNo", r.PlacementGroupNo)
+	}
+	if r.AssociateWithPublicIp {
+		v.Set("associateWithPublicIp", "true")
+	}
+	if r.IsProtectServerTermination {
+		v.Set("isProtectServerTermination", "true")
+	}
+	if r.IsEncryptedBaseBlockStorageVolume {
+		v.Set("isEncryptedBaseBlockStorageVolume", "true")
+	}
+	if r.LoginKeyName != "" {
+		v.Set("loginKeyName", r.LoginKeyName)
+	}
+	if r.FeeSystemTypeCode != "" {
+		v.Set("feeSystemTypeCode", r.FeeSystemTypeCode)
+	}
+	if r.InitScriptNo != "" {
+		v.Set("initScriptNo", r.InitScriptNo)
+	}
+
+	for i, m := range r.BlockStorageMappingList {
+		prefix := fmt.Sprintf("blockStorageMappingList.%d.", i+1)
+		v.Set(prefix+"order", strconv.Itoa(m.Order))
+		if m.SnapshotInstanceNo != "" {
+			v.Set(prefix+"snapshotInstanceNo", m.SnapshotInstanceNo)
+		}
+		if m.BlockStorageSize > 0 {
+			v.Set(prefix+"blockStorageSize", strconv.Itoa(m.BlockStorageSize))
+		}
+		if m.BlockStorageName != "" {
+			v.Set(prefix+"blockStorageName", m.BlockStorageName)
+		}
+		if m.BlockStorageVolumeTypeCode != "" {
+			v.Set(prefix+"blockStorageVolumeTypeCode", m.BlockStorageVolumeTypeCode)
+		}
+		v.Set(prefix+"encrypted", strconv.FormatBool(m.Encrypted))
+	}
+	return v
+}
+
+// CreateServerInstances calls createServerInstances with the full request,
+// including additional block storage mappings.
+func (c *Client) CreateServerInstances(ctx context.Context, request *CreateServerInstancesRequest) (*server.CreateServerResponse, error) {
+	resp := &server.CreateServerResponse{}
+	if err := c.call(ctx, createServerInstancesAction, request.values(), resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+const (
+	getServerInstanceListAction       = "getServerInstanceList"
+	getServerInstanceDetailAction     = "getServerInstanceDetail"
+	changeServerInstanceSpecAction    = "changeServerInstanceSpec"
+	setProtectServerTerminationAction = "setProtectServerTermination"
+
+	ServerStatusInit     = "INIT"
+	ServerStatusCreating = "CREAT"
+	ServerStatusRunning  = "RUN"
+	ServerStatusStopped  = "NSTOP"
+	ServerOperationNone  = "NULL"
+
+	serverListPageSize = 100
+)
+
+// ServerInstance carries the attributes the Aviator-service ServerInstance leaves out.
+type ServerInstance struct {
+	ServerInstanceNo           string     `xml:"serverInstanceNo"`
+	ServerName                 string     `xml:"serverName"`
+	ServerDescription          string     `xml:"serverDescription"`
+	ServerProductCode          string     `xml:"serverProductCode"`
+	ServerImageProductCode     string     `xml:"serverImageProductCode"`
+	ServerImageNo              string     `xml:"serverImageNo"`
+	ServerSpecCode             string     `xml:"serverSpecCode"`
+	LoginKeyName               string     `xml:"loginKeyName"`
+	ServerInstanceStatus       CommonCode `xml:"serverInstanceStatus"`
+	ServerInstanceOperation    CommonCode `xml:"serverInstanceOperation"`
+	PublicIpInstanceNo         string     `xml:"publicIpInstanceNo"`
+	PublicIp                   string     `xml:"publicIp"`
+	IsProtectServerTermination bool       `xml:"isProtectServerTermination"`
+	ZoneCode                   string     `xml:"zoneCode"`
+	RegionCode                 string     `xml:"regionCode"`
+	VpcNo                      string     `xml:"vpcNo"`
+	SubnetNo                   string     `xml:"subnetNo"`
+	NetworkInterfaceNoList     []string   `xml:"networkInterfaceNoList>networkInterfaceNo"`
+	PlacementGroupNo           string     `xml:"placementGroupNo"`
+	CreateDate                 string     `xml:"createDate"`
+}
+
+// Stable reports whether no operation is in progress and the server is either running or stopped.
+func (s *ServerInstance) Stable() bool {
+	status := s.ServerInstanceStatus.Code
+	return (status == ServerStatusRunning || status == ServerStatusStopped) &&
+		(s.ServerInstanceOperation.Code == "" || s.ServerInstanceOperation.Code == ServerOperationNone)
+}
+
+type ServerInstanceList struct {
+	ReturnCode         int              `xml:"returnCode"`
+	ReturnMessage      string           `xml:"returnMessage"`
+	TotalRows          int              `xml:"totalRows"`
+	ServerInstanceList []ServerInstance `xml:"serverInstanceList>serverInstance"`
+}
+
+// GetServerInstanceDetail returns a single server with its public IP and network interfaces.
+func (c *Client) GetServerInstanceDetail(ctx context.Context, regionCode, serverInstanceNo string) (*ServerInstance, error) {
+	v := regionValues(regionCode)
+	v.Set("serverInstanceNo", serverInstanceNo)
+
+	resp := &ServerInstanceList{}
+	if err := c.call(ctx, getServerInstanceDetailAction, v, resp); err != nil {
+		return nil, err
+	}
+	if len(resp.ServerInstanceList) == 0 {
+		return nil, notFound("server instance", serverInstanceNo)
+	}
+	return &resp.ServerInstanceList[0], nil
+}
+
+// GetServerInstanceList returns every server of a region, reading all pages.
+func (c *Client) GetServerInstanceList(ctx context.Context, regionCode string) ([]ServerInstance, error) {
+	var instances []ServerInstance
+	for pageNo := 1; ; pageNo++ {
+		v := regionValues(regionCode)
+		v.Set("pageNo", strconv.Itoa(pageNo))
+		v.Set("pageSize", strconv.Itoa(serverListPageSize))
+
+		resp := &ServerInstanceList{}
+		if err := c.call(ctx, getServerInstanceListAction, v, resp); err != nil {
+			return nil, err
+		}
+		instances = append(instances, resp.ServerInstanceList...)
+		if len(resp.ServerInstanceList) < serverListPageSize || len(instances) >= resp.TotalRows {
+			return instances, nil
+		}
+	}
+}
+
+// GetServerInstancesByName returns the servers of a region named serverName.
+func (c *Client) GetServerInstancesByName(ctx context.Context, regionCode, serverName string) ([]ServerInstance, error) {
+	v := regionValues(regionCode)
+	v.Set("serverName", serverName)
+
+	resp := &ServerInstanceList{}
+	if err := c.call(ctx, getServerInstanceListAction, v, resp); err != nil {
+		return nil, err
+	}
+	// serverName filters by prefix, keep exact matches only
+	var instances []ServerInstance
+	for _, s := range resp.ServerInstanceList {
+		if s.ServerName == serverName {
+			instances = append(instances, s)
+		}
+	}
+	return instances, nil
+}
+
+// ChangeServerInstanceSpec changes the product of a stopped server.
+func (c *Client) ChangeServerInstanceSpec(ctx context.Context, regionCode, serverInstanceNo, serverProductCode string) error {
+	v := regionValues(regionCode)
+	v.Set("serverInstanceNo", serverInstanceNo)
+	v.Set("serverProductCode", serverProductCode)
+	return c.call(ctx, changeServerInstanceSpecAction, v, &ServerInstanceList{})
+}
+
+// SetProtectServerTermination turns termination protection of a server on or off.
+func (c *Client) SetProtectServerTermination(ctx context.Context, regionCode, serverInstanceNo string, protect bool) error {
+	v := regionValues(regionCode)
+	v.Set("serverInstanceNo", serverInstanceNo)
+	v.Set("isProtectServerTermination", strconv.FormatBool(protect))
+	return c.call(ctx, setProtectServerTerminationAction, v, &ServerInstanceList{})
+}
+
+const terminateServerInstancesAction = "terminateServerInstances"
+
+// TerminateServerInstances returns stopped servers. Unlike the Aviator-service
+// Delete it does not poll for the stopped state first.
+func (c *Client) TerminateServerInstances(ctx context.Context, regionCode string, serverInstanceNos ...string) error {
+	v := regionValues(regionCode)
+	for i, no := range serverInstanceNos {
+		v.Set(fmt.Sprintf("serverInstanceNoList.%d", i+1), no)
+	}
+	return c.call(ctx, terminateServerInstancesAction, v, &ServerInstanceList{})
+}
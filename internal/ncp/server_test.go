@@ -0,0 +1,96 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ncp
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	server "github.com/cloud-club/Aviator-service/types/server"
+)
+
+var _ = Describe("CreateServerInstancesRequest", func() {
+	It("numbers block storage mappings from 1 and skips unset optional fields", func() {
+		req := &CreateServerInstancesRequest{
+			CreateServerRequest: server.CreateServerRequest{
+				ServerImageProductCode:    "SW.VSVR.OS.LNX64.CNTOS.0703.B050",
+				VpcNo:                     "52833",
+				SubnetNo:                  "120320",
+				ServerProductCode:         "SVR.VSVR.HICPU.C002.M004.NET.HDD.B050.G002",
+				AccessControlGroupNoListN: "148207",
+			},
+			BlockStorageMappingList: []BlockStorageMapping{
+				{Order: 1, BlockStorageName: "data", BlockStorageSize: 100, BlockStorageVolumeTypeCode: "SSD", Encrypted: true},
+				{Order: 2, SnapshotInstanceNo: "1234"},
+			},
+		}
+
+		v := req.values()
+		Expect(v.Get("networkInterfaceList.1.accessControlGroupNoList.1")).To(Equal("148207"))
+		Expect(v.Get("blockStorageMappingList.1.order")).To(Equal("1"))
+		Expect(v.Get("blockStorageMappingList.1.blockStorageName")).To(Equal("data"))
+		Expect(v.Get("blockStorageMappingList.1.blockStorageSize")).To(Equal("100"))
+		Expect(v.Get("blockStorageMappingList.1.blockStorageVolumeTypeCode")).To(Equal("SSD"))
+		Expect(v.Get("blockStorageMappingList.1.encrypted")).To(Equal("true"))
+		Expect(v.Get("blockStorageMappingList.2.order")).To(Equal("2"))
+		Expect(v.Get("blockStorageMappingList.2.snapshotInstanceNo")).To(Equal("1234"))
+		Expect(v.Has("blockStorageMappingList.2.blockStorageSize")).To(BeFalse())
+		Expect(v.Has("blockStorageMappingList.2.blockStorageName")).To(BeFalse())
+	})
+
+	It("boots from a member server image instead of the image product", func() {
+		req := &CreateServerInstancesRequest{
+			CreateServerRequest: server.CreateServerRequest{
+				ServerImageProductCode: "SW.VSVR.OS.LNX64.CNTOS.0703.B050",
+			},
+			MemberServerImageInstanceNo: "8870",
+		}
+
+		v := req.values()
+		Expect(v.Get("memberServerImageInstanceNo")).To(Equal("8870"))
+		Expect(v.Has("serverImageProductCode")).To(BeFalse())
+	})
+
+	It("places the server in a placement group only when one is given", func() {
+		req := &CreateServerInstancesRequest{}
+		Expect(req.values().Has("placementGroupNo")).To(BeFalse())
+
+		req.PlacementGroupNo = "2601"
+		Expect(req.values().Get("placementGroupNo")).To(Equal("2601"))
+	})
+	It("sends the region so the server is not created in the default one", func() {
+		req := &CreateServerInstancesRequest{RegionCode: "JPN"}
+		Expect(req.values().Get("regionCode")).To(Equal("JPN"))
+		Expect((&CreateServerInstancesRequest{}).values().Has("regionCode")).To(BeFalse())
+	})
+
+	DescribeTable("sends optional fields only when set",
+		func(set func(*CreateServerInstancesRequest), key, value string) {
+			req := &CreateServerInstancesRequest{}
+			Expect(req.values().Has(key)).To(BeFalse())
+
+			set(req)
+			Expect(req.values().Get(key)).To(Equal(value))
+		},
+		Entry("public IP", func(r *CreateServerInstancesRequest) { r.AssociateWithPublicIp = true }, "associateWithPublicIp", "true"),
+		Entry("termination protection", func(r *CreateServerInstancesRequest) { r.IsProtectServerTermination = true }, "isProtectServerTermination", "true"),
+		Entry("encrypted base volume", func(r *CreateServerInstancesRequest) { r.IsEncryptedBaseBlockStorageVolume = true }, "isEncryptedBaseBlockStorageVolume", "true"),
+		Entry("login key", func(r *CreateServerInstancesRequest) { r.LoginKeyName = "ops-key" }, "loginKeyName", "ops-key"),
+		Entry("fee system", func(r *CreateServerInstancesRequest) { r.FeeSystemTypeCode = "MTRAT" }, "feeSystemTypeCode", "MTRAT"),
+		Entry("init script", func(r *CreateServerInstancesRequest) { r.InitScriptNo = "4110" }, "initScriptNo", "4110"),
+	)
+})
@@ -0,0 +1,152 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ncp
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// ServerCache answers server lookups from one getServerInstanceList call per
+// region, refreshed at most every TTL, instead of one call per Provision.
+// Callers that change a server should Invalidate its region.
+type ServerCache struct {
+	client *Client
+	TTL    time.Duration
+
+	mu      sync.Mutex
+	regions map[string]*regionServers
+}
+
+// regionServers is the cached list of one region. mu is held only to read or
+// swap the list; refresh serializes the refreshes of the region so concurrent
+// lookups share one getServerInstanceList call without blocking lookups that
+// are still fresh or in other regions.
+type regionServers struct {
+	refresh sync.Mutex
+
+	mu      sync.Mutex
+	fetched time.Time
+	gen     uint64
+	list    []ServerInstance
+	byNo    map[string]int
+}
+
+func NewServerCache(client *Client, ttl time.Duration) *ServerCache {
+	return &ServerCache{
+		client:  client,
+		TTL:     ttl,
+		regions: map[string]*regionServers{},
+	}
+}
+
+// List returns a copy of the servers of a region.
+func (c *ServerCache) List(ctx context.Context, regionCode string) ([]ServerInstance, error) {
+	servers, err := c.region(ctx, regionCode)
+	if err != nil {
+		return nil, err
+	}
+	servers.mu.Lock()
+	defer servers.mu.Unlock()
+	return append([]ServerInstance(nil), servers.list...), nil
+}
+
+// Get returns a copy of a server. Servers created since the last refresh are
+// read with getServerInstanceDetail.
+func (c *ServerCache) Get(ctx context.Context, regionCode, serverInstanceNo string) (*ServerInstance, error) {
+	servers, err := c.region(ctx, regionCode)
+	if err != nil {
+		return nil, err
+	}
+	servers.mu.Lock()
+	i, ok := servers.byNo[serverInstanceNo]
+	if ok {
+		instance := servers.list[i]
+		servers.mu.Unlock()
+		return &instance, nil
+	}
+	servers.mu.Unlock()
+	return c.client.GetServerInstanceDetail(ctx, regionCode, serverInstanceNo)
+}
+
+// Invalidate makes the next lookup in the region refresh it. A refresh that
+// is in flight when Invalidate is called does not count as fresh.
+func (c *ServerCache) Invalidate(regionCode string) {
+	servers := c.entry(regionCode)
+	servers.mu.Lock()
+	defer servers.mu.Unlock()
+	servers.fetched = time.Time{}
+	servers.gen++
+}
+
+// entry returns the cache entry of a region, creating an empty one.
+func (c *ServerCache) entry(regionCode string) *regionServers {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	servers, ok := c.regions[regionCode]
+	if !ok {
+		servers = &regionServers{}
+		c.regions[regionCode] = servers
+	}
+	return servers
+}
+
+// fresh reports whether the region's list is younger than TTL.
+func (c *ServerCache) fresh(servers *regionServers) bool {
+	servers.mu.Lock()
+	defer servers.mu.Unlock()
+	return !servers.fetched.IsZero() && time.Since(servers.fetched) < c.TTL
+}
+
+// region returns the region's entry, refreshing it once it is older than TTL.
+func (c *ServerCache) region(ctx context.Context, regionCode string) (*regionServers, error) {
+	servers := c.entry(regionCode)
+	if c.fresh(servers) {
+		return servers, nil
+	}
+
+	servers.refresh.Lock()
+	defer servers.refresh.Unlock()
+	// Another lookup may have refreshed the region while this one waited.
+	if c.fresh(servers) {
+		return servers, nil
+	}
+
+	servers.mu.Lock()
+	gen := servers.gen
+	servers.mu.Unlock()
+
+	list, err := c.client.GetServerInstanceList(ctx, regionCode)
+	if err != nil {
+		return nil, err
+	}
+	byNo := make(map[string]int, len(list))
+	for i := range list {
+		byNo[list[i].ServerInstanceNo] = i
+	}
+
+	servers.mu.Lock()
+	defer servers.mu.Unlock()
+	servers.list, servers.byNo = list, byNo
+	// An Invalidate during the fetch may have missed the change it was
+	// called for, so leave the list stale for the next lookup.
+	if servers.gen == gen {
+		servers.fetched = time.Now()
+	}
+	return servers, nil
+}
@@ -0,0 +1,106 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ncp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"github.com/cloud-club/Aviator-service/types/auth"
+)
+
+var _ = Describe("ServerCache", func() {
+	var (
+		srv     *httptest.Server
+		cache   *ServerCache
+		lists   map[string]*atomic.Int32
+		release map[string]chan struct{}
+	)
+
+	BeforeEach(func() {
+		lists = map[string]*atomic.Int32{"KR": {}, "JPN": {}}
+		release = map[string]chan struct{}{"KR": make(chan struct{}), "JPN": make(chan struct{})}
+		close(release["JPN"])
+		srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			region := req.URL.Query().Get("regionCode")
+			lists[region].Add(1)
+			<-release[region]
+			fmt.Fprintf(w, "<getServerInstanceListResponse><totalRows>1</totalRows><serverInstanceList>"+
+				"<serverInstance><serverInstanceNo>%s-1</serverInstanceNo></serverInstance>"+
+				"</serverInstanceList></getServerInstanceListResponse>", region)
+		}))
+		client := &Client{keyService: auth.NewKeyService("ak", "sk"), httpClient: srv.Client(), baseURL: srv.URL + "/"}
+		cache = NewServerCache(client, time.Minute)
+	})
+
+	AfterEach(func() {
+		srv.Close()
+	})
+
+	It("shares one refresh per region without blocking other regions", func() {
+		var wg sync.WaitGroup
+		for i := 0; i < 4; i++ {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				instance, err := cache.Get(context.Background(), "KR", "KR-1")
+				Expect(err).NotTo(HaveOccurred())
+				Expect(instance.ServerInstanceNo).To(Equal("KR-1"))
+			}()
+		}
+		Eventually(lists["KR"].Load).Should(BeEquivalentTo(1))
+
+		servers, err := cache.List(context.Background(), "JPN")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(servers).To(HaveLen(1))
+
+		close(release["KR"])
+		wg.Wait()
+		Expect(lists["KR"].Load()).To(BeEquivalentTo(1))
+	})
+
+	It("refreshes again when the region is invalidated during a refresh", func() {
+		done := make(chan struct{})
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_, err := cache.List(context.Background(), "KR")
+			Expect(err).NotTo(HaveOccurred())
+		}()
+		Eventually(lists["KR"].Load).Should(BeEquivalentTo(1))
+		cache.Invalidate("KR")
+		close(release["KR"])
+		<-done
+
+		_, err := cache.List(context.Background(), "KR")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lists["KR"].Load()).To(BeEquivalentTo(2))
+
+		_, err = cache.List(context.Background(), "KR")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(lists["KR"].Load()).To(BeEquivalentTo(2))
+	})
+})
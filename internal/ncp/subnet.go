@@ -0,0 +1,52 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ncp
+
+import "context"
+
+const getSubnetDetailAction = "getSubnetDetail"
+
+type Subnet struct {
+	SubnetNo     string     `xml:"subnetNo"`
+	VpcNo        string     `xml:"vpcNo"`
+	ZoneCode     string     `xml:"zoneCode"`
+	SubnetName   string     `xml:"subnetName"`
+	Subnet       string     `xml:"subnet"`
+	SubnetStatus CommonCode `xml:"subnetStatus"`
+}
+
+type SubnetList struct {
+	ReturnCode    int      `xml:"returnCode"`
+	ReturnMessage string   `xml:"returnMessage"`
+	TotalRows     int      `xml:"totalRows"`
+	SubnetList    []Subnet `xml:"subnetList>subnet"`
+}
+
+// GetSubnetDetail reads one subnet of the VPC API, including its zone.
+func (c *Client) GetSubnetDetail(ctx context.Context, regionCode, subnetNo string) (*Subnet, error) {
+	v := regionValues(regionCode)
+	v.Set("subnetNo", subnetNo)
+
+	resp := &SubnetList{}
+	if err := c.callURL(ctx, c.vpcURL, getSubnetDetailAction, v, resp); err != nil {
+		return nil, err
+	}
+	if len(resp.SubnetList) == 0 {
+		return nil, notFound("subnet", subnetNo)
+	}
+	return &resp.SubnetList[0], nil
+}
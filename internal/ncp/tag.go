@@ -0,0 +1,90 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ncp
+
+import (
+	"context"
+	"fmt"
+	"sort"
+)
+
+const (
+	getInstanceTagListAction = "getInstanceTagList"
+	createInstanceTagsAction = "createInstanceTags"
+	deleteInstanceTagsAction = "deleteInstanceTags"
+)
+
+type InstanceTag struct {
+	InstanceNo   string     `xml:"instanceNo"`
+	InstanceType CommonCode `xml:"instanceType"`
+	TagKey       string     `xml:"tagKey"`
+	TagValue     string     `xml:"tagValue"`
+}
+
+type InstanceTagList struct {
+	ReturnCode      int           `xml:"returnCode"`
+	ReturnMessage   string        `xml:"returnMessage"`
+	TotalRows       int           `xml:"totalRows"`
+	InstanceTagList []InstanceTag `xml:"instanceTagList>instanceTag"`
+}
+
+// GetInstanceTagList returns the tags of the given instances.
+func (c *Client) GetInstanceTagList(ctx context.Context, regionCode string, instanceNos ...string) (*InstanceTagList, error) {
+	v := regionValues(regionCode)
+	for i, no := range instanceNos {
+		v.Set(fmt.Sprintf("instanceNoList.%d", i+1), no)
+	}
+	resp := &InstanceTagList{}
+	if err := c.call(ctx, getInstanceTagListAction, v, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+// CreateInstanceTags applies tags, key to value, to the given instances.
+func (c *Client) CreateInstanceTags(ctx context.Context, regionCode string, instanceNos []string, tags map[string]string) error {
+	v := regionValues(regionCode)
+	for i, no := range instanceNos {
+		v.Set(fmt.Sprintf("instanceNoList.%d", i+1), no)
+	}
+	for i, key := range sortedKeys(tags) {
+		v.Set(fmt.Sprintf("instanceTagList.%d.tagKey", i+1), key)
+		v.Set(fmt.Sprintf("instanceTagList.%d.tagValue", i+1), tags[key])
+	}
+	return c.call(ctx, createInstanceTagsAction, v, &InstanceTagList{})
+}
+
+// DeleteInstanceTags removes the tags with the given keys from the instances.
+func (c *Client) DeleteInstanceTags(ctx context.Context, regionCode string, instanceNos []string, keys []string) error {
+	v := regionValues(regionCode)
+	for i, no := range instanceNos {
+		v.Set(fmt.Sprintf("instanceNoList.%d", i+1), no)
+	}
+	for i, key := range keys {
+		v.Set(fmt.Sprintf("instanceTagList.%d.tagKey", i+1), key)
+	}
+	return c.call(ctx, deleteInstanceTagsAction, v, &InstanceTagList{})
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
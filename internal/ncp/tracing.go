@@ -0,0 +1,60 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ncp
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// requestIDHeader carries the API gateway's ID of a request, which NCP
+// support asks for when investigating a call.
+const requestIDHeader = "x-ncp-trace-id"
+
+var tracer = otel.Tracer("vm.cloudclub.io/internal/ncp")
+
+// startSpan starts the client span of one NCP API request.
+func startSpan(ctx context.Context, operation, regionCode string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "ncp "+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("ncp.operation", operation),
+			attribute.String("ncp.region", regionCode),
+		))
+}
+
+// endSpan records the outcome of a request and ends its span. A zero
+// statusCode or empty requestID means no response reported one.
+func endSpan(span trace.Span, statusCode int, requestID string, err error) {
+	if statusCode != 0 {
+		span.SetAttributes(attribute.Int("http.status_code", statusCode))
+	}
+	if requestID != "" {
+		span.SetAttributes(attribute.String("ncp.request_id", requestID))
+	}
+	if err != nil {
+		e := AsError(err)
+		span.SetAttributes(attribute.String("ncp.error_kind", string(e.Kind)), attribute.String("ncp.error_code", e.Code))
+		span.RecordError(err)
+		span.SetStatus(codes.Error, e.Message)
+	}
+	span.End()
+}
@@ -0,0 +1,93 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package placement chooses the zone and subnet a new server is created in.
+package placement
+
+import (
+	"fmt"
+	"strings"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+// Subnet is a candidate subnet and the zone it lies in.
+type Subnet struct {
+	SubnetNo string
+	VpcNo    string
+	ZoneCode string
+}
+
+// NoSubnetError is returned when no candidate subnet lies in an allowed zone.
+type NoSubnetError struct {
+	Zones []string
+}
+
+func (e *NoSubnetError) Error() string {
+	return fmt.Sprintf("no candidate subnet in zones %s", strings.Join(e.Zones, ", "))
+}
+
+// Choose picks the subnet of a new server from candidates in the allowed
+// zones, which are all zones of the candidates when zones is empty. Spread
+// picks the zone with the fewest servers in counts and Ordered the first
+// zone; ties and the subnet within a zone go to the earlier one. current,
+// the subnet chosen by an earlier attempt, is kept while still a candidate
+// in an allowed zone.
+func Choose(candidates []Subnet, zones []string, strategy vmv1.ZoneStrategy, counts map[string]int, current string) (Subnet, error) {
+	if len(zones) == 0 {
+		for _, s := range candidates {
+			if !contains(zones, s.ZoneCode) {
+				zones = append(zones, s.ZoneCode)
+			}
+		}
+	}
+	byZone := make(map[string]Subnet, len(zones))
+	for _, s := range candidates {
+		if !contains(zones, s.ZoneCode) {
+			continue
+		}
+		if s.SubnetNo == current {
+			return s, nil
+		}
+		if _, ok := byZone[s.ZoneCode]; !ok {
+			byZone[s.ZoneCode] = s
+		}
+	}
+
+	chosen, found := Subnet{}, false
+	for _, zone := range zones {
+		s, ok := byZone[zone]
+		if !ok {
+			continue
+		}
+		if !found || (strategy != vmv1.ZoneStrategyOrdered && counts[zone] < counts[chosen.ZoneCode]) {
+			chosen, found = s, true
+		}
+	}
+	if !found {
+		return Subnet{}, &NoSubnetError{Zones: zones}
+	}
+	return chosen, nil
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
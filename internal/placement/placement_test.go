@@ -0,0 +1,73 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package placement
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+var _ = Describe("Choose", func() {
+	candidates := []Subnet{
+		{SubnetNo: "101", ZoneCode: "KR-1"},
+		{SubnetNo: "102", ZoneCode: "KR-1"},
+		{SubnetNo: "201", ZoneCode: "KR-2"},
+	}
+
+	It("spreads to the zone with the fewest servers", func() {
+		s, err := Choose(candidates, nil, vmv1.ZoneStrategySpread, map[string]int{"KR-1": 2, "KR-2": 1}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.SubnetNo).To(Equal("201"))
+	})
+
+	It("breaks ties by the order of the allowed zones", func() {
+		s, err := Choose(candidates, []string{"KR-2", "KR-1"}, vmv1.ZoneStrategySpread, nil, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.SubnetNo).To(Equal("201"))
+
+		s, err = Choose(candidates, nil, vmv1.ZoneStrategySpread, map[string]int{"KR-1": 1, "KR-2": 1}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.SubnetNo).To(Equal("101"))
+	})
+
+	It("picks the first allowed zone with Ordered", func() {
+		s, err := Choose(candidates, []string{"KR-1", "KR-2"}, vmv1.ZoneStrategyOrdered, map[string]int{"KR-1": 5}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.SubnetNo).To(Equal("101"))
+	})
+
+	It("only uses subnets in the allowed zones", func() {
+		s, err := Choose(candidates, []string{"KR-2"}, vmv1.ZoneStrategySpread, map[string]int{"KR-2": 3}, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.SubnetNo).To(Equal("201"))
+
+		_, err = Choose(candidates, []string{"KR-3"}, vmv1.ZoneStrategySpread, nil, "")
+		Expect(err).To(MatchError(&NoSubnetError{Zones: []string{"KR-3"}}))
+	})
+
+	It("keeps the subnet of an earlier attempt", func() {
+		s, err := Choose(candidates, nil, vmv1.ZoneStrategySpread, map[string]int{"KR-1": 2}, "102")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.SubnetNo).To(Equal("102"))
+
+		s, err = Choose(candidates, []string{"KR-2"}, vmv1.ZoneStrategySpread, nil, "102")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(s.SubnetNo).To(Equal("201"))
+	})
+})
@@ -0,0 +1,128 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package policy evaluates the ProvisionPolicies of the cluster on Provisions.
+package policy
+
+import (
+	"context"
+	"fmt"
+	"path"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+// ViolationError is returned for a Provision a rule of a ProvisionPolicy rejects.
+type ViolationError struct {
+	Policy string
+	Rule   string
+	Field  vmv1.ProvisionPolicyField
+	Value  string
+	// Pattern is the deny pattern the value matched, empty when the value
+	// matched none of the allowed ones.
+	Pattern string
+}
+
+func (e *ViolationError) Error() string {
+	if e.Pattern != "" {
+		return fmt.Sprintf("ProvisionPolicy %s rule %s: %s %q is denied by %q",
+			e.Policy, e.Rule, e.Field, e.Value, e.Pattern)
+	}
+	return fmt.Sprintf("ProvisionPolicy %s rule %s: %s %q is not allowed", e.Policy, e.Rule, e.Field, e.Value)
+}
+
+// Check returns a *ViolationError when a Provision breaks a rule of a
+// ProvisionPolicy selecting its namespace. For an update, old is the
+// Provision before it and only the fields that changed are checked, so
+// Provisions created before a policy can still be stopped or deleted.
+func Check(ctx context.Context, reader client.Reader, p, old *vmv1.Provision) error {
+	policies := &vmv1.ProvisionPolicyList{}
+	if err := reader.List(ctx, policies); err != nil {
+		return err
+	}
+	if len(policies.Items) == 0 {
+		return nil
+	}
+	namespace := &corev1.Namespace{}
+	if err := reader.Get(ctx, client.ObjectKey{Name: p.Namespace}, namespace); err != nil {
+		return err
+	}
+
+	for _, policy := range policies.Items {
+		selected, err := selects(policy.Spec.NamespaceSelector, namespace)
+		if err != nil {
+			return fmt.Errorf("ProvisionPolicy %s: %v", policy.Name, err)
+		}
+		if !selected {
+			continue
+		}
+		for _, rule := range policy.Spec.Rules {
+			value := fieldValue(p, rule.Field)
+			if old != nil && fieldValue(old, rule.Field) == value {
+				continue
+			}
+			if pattern, ok := matchAny(rule.Deny, value); ok {
+				return &ViolationError{Policy: policy.Name, Rule: rule.Name, Field: rule.Field, Value: value, Pattern: pattern}
+			}
+			if _, ok := matchAny(rule.Allow, value); len(rule.Allow) > 0 && !ok {
+				return &ViolationError{Policy: policy.Name, Rule: rule.Name, Field: rule.Field, Value: value}
+			}
+		}
+	}
+	return nil
+}
+
+func selects(selector *metav1.LabelSelector, namespace *corev1.Namespace) (bool, error) {
+	if selector == nil {
+		return true, nil
+	}
+	s, err := metav1.LabelSelectorAsSelector(selector)
+	if err != nil {
+		return false, err
+	}
+	return s.Matches(labels.Set(namespace.Labels)), nil
+}
+
+func fieldValue(p *vmv1.Provision, field vmv1.ProvisionPolicyField) string {
+	switch field {
+	case vmv1.ProvisionPolicyFieldRegionCode:
+		return p.Spec.RegionCode
+	case vmv1.ProvisionPolicyFieldServerImageProductCode:
+		return p.Spec.Server.ImageProductCode
+	case vmv1.ProvisionPolicyFieldServerProductCode:
+		return p.Spec.Server.ProductCode
+	case vmv1.ProvisionPolicyFieldVpcNo:
+		return p.Spec.VpcNo
+	case vmv1.ProvisionPolicyFieldFeeSystemTypeCode:
+		return p.Spec.FeeSystemTypeCode
+	}
+	return ""
+}
+
+// matchAny returns the first of patterns value matches. Malformed patterns match nothing.
+func matchAny(patterns []string, value string) (string, bool) {
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, value); ok {
+			return pattern, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package policy
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+var _ = Describe("Check", func() {
+	var reader client.Reader
+
+	BeforeEach(func() {
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(vmv1.AddToScheme(scheme)).To(Succeed())
+		reader = fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "data", Labels: map[string]string{"team": "data"}}},
+			&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "web"}},
+			&vmv1.ProvisionPolicy{
+				ObjectMeta: metav1.ObjectMeta{Name: "data-team"},
+				Spec: vmv1.ProvisionPolicySpec{
+					NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"team": "data"}},
+					Rules: []vmv1.ProvisionPolicyRule{
+						{Name: "korea-only", Field: vmv1.ProvisionPolicyFieldRegionCode, Allow: []string{"KR"}},
+						{Name: "no-gpu", Field: vmv1.ProvisionPolicyFieldServerProductCode, Deny: []string{"SVR.VSVR.GPU*"}},
+					},
+				},
+			},
+		).Build()
+	})
+
+	provision := func(namespace, regionCode, productCode string) *vmv1.Provision {
+		return &vmv1.Provision{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: "vm"},
+			Spec:       vmv1.ProvisionSpec{RegionCode: regionCode, Server: vmv1.Server{ProductCode: productCode}},
+		}
+	}
+
+	It("allows Provisions following the rules", func() {
+		Expect(Check(context.Background(), reader, provision("data", "KR", "SVR.VSVR.STAND.C002.M008.NET.SSD.B050.G002"), nil)).To(Succeed())
+	})
+
+	It("reports the violated rule", func() {
+		err := Check(context.Background(), reader, provision("data", "JPN", ""), nil)
+		Expect(err).To(MatchError(`ProvisionPolicy data-team rule korea-only: RegionCode "JPN" is not allowed`))
+
+		err = Check(context.Background(), reader, provision("data", "KR", "SVR.VSVR.GPU.T4.G001"), nil)
+		Expect(err).To(MatchError(ContainSubstring(`rule no-gpu: ServerProductCode "SVR.VSVR.GPU.T4.G001" is denied by "SVR.VSVR.GPU*"`)))
+	})
+
+	It("only applies to selected namespaces", func() {
+		Expect(Check(context.Background(), reader, provision("web", "JPN", ""), nil)).To(Succeed())
+	})
+
+	It("only checks fields an update changes", func() {
+		old := provision("data", "JPN", "")
+		p := old.DeepCopy()
+		p.Spec.Phase = vmv1.ProvisionPhaseStop
+		Expect(Check(context.Background(), reader, p, old)).To(Succeed())
+	})
+})
@@ -0,0 +1,66 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package powerschedule evaluates the power schedules of Provisions.
+package powerschedule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+// Schedule is a parsed vmv1.PowerSchedule.
+type Schedule struct {
+	start, stop cron.Schedule
+	location    *time.Location
+}
+
+// Parse parses the cron expressions and time zone of a power schedule.
+func Parse(spec *vmv1.PowerSchedule) (*Schedule, error) {
+	location := time.UTC
+	if spec.TimeZone != "" {
+		var err error
+		if location, err = time.LoadLocation(spec.TimeZone); err != nil {
+			return nil, fmt.Errorf("invalid power schedule time zone %q: %v", spec.TimeZone, err)
+		}
+	}
+	start, err := cron.ParseStandard(spec.Start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid power schedule start %q: %v", spec.Start, err)
+	}
+	stop, err := cron.ParseStandard(spec.Stop)
+	if err != nil {
+		return nil, fmt.Errorf("invalid power schedule stop %q: %v", spec.Stop, err)
+	}
+	return &Schedule{start: start, stop: stop, location: location}, nil
+}
+
+// At returns whether the server should be running at now and the next
+// transition after now. The server runs when the next stop comes before the
+// next start, that is when the last of the two to fire was a start.
+func (s *Schedule) At(now time.Time) (running bool, next vmv1.PowerTransition) {
+	now = now.In(s.location)
+	nextStart, nextStop := s.start.Next(now), s.stop.Next(now)
+	if nextStop.Before(nextStart) {
+		return true, vmv1.PowerTransition{Action: vmv1.PowerActionStop, Time: metav1.NewTime(nextStop)}
+	}
+	return false, vmv1.PowerTransition{Action: vmv1.PowerActionStart, Time: metav1.NewTime(nextStart)}
+}
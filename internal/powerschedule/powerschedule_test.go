@@ -0,0 +1,66 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package powerschedule
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+var _ = Describe("Schedule", func() {
+	var schedule *Schedule
+	seoul, _ := time.LoadLocation("Asia/Seoul")
+
+	BeforeEach(func() {
+		var err error
+		schedule, err = Parse(&vmv1.PowerSchedule{Start: "0 8 * * 1-5", Stop: "0 20 * * 1-5", TimeZone: "Asia/Seoul"})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("runs the server within a window", func() {
+		// Wednesday
+		running, next := schedule.At(time.Date(2024, 1, 3, 12, 0, 0, 0, seoul))
+		Expect(running).To(BeTrue())
+		Expect(next.Action).To(Equal(vmv1.PowerActionStop))
+		Expect(next.Time.Time).To(BeTemporally("==", time.Date(2024, 1, 3, 20, 0, 0, 0, seoul)))
+	})
+
+	It("stops the server over the weekend", func() {
+		// Saturday
+		running, next := schedule.At(time.Date(2024, 1, 6, 12, 0, 0, 0, seoul))
+		Expect(running).To(BeFalse())
+		Expect(next.Action).To(Equal(vmv1.PowerActionStart))
+		Expect(next.Time.Time).To(BeTemporally("==", time.Date(2024, 1, 8, 8, 0, 0, 0, seoul)))
+	})
+
+	It("evaluates the expressions in the time zone", func() {
+		// 12:00 in Seoul is 03:00 UTC
+		running, _ := schedule.At(time.Date(2024, 1, 3, 3, 0, 0, 0, time.UTC))
+		Expect(running).To(BeTrue())
+	})
+
+	It("rejects invalid schedules", func() {
+		_, err := Parse(&vmv1.PowerSchedule{Start: "0 8 * *", Stop: "0 20 * * *"})
+		Expect(err).To(MatchError(ContainSubstring("invalid power schedule start")))
+		_, err = Parse(&vmv1.PowerSchedule{Start: "0 8 * * *", Stop: "0 20 * * *", TimeZone: "Mars/Olympus"})
+		Expect(err).To(MatchError(ContainSubstring("time zone")))
+	})
+})
@@ -0,0 +1,62 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package powerschedule
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+// Window is a parsed vmv1.MaintenanceWindow.
+type Window struct {
+	start    cron.Schedule
+	duration time.Duration
+	location *time.Location
+}
+
+// ParseWindow parses the start, duration and time zone of a maintenance window.
+func ParseWindow(spec *vmv1.MaintenanceWindow) (*Window, error) {
+	location := time.UTC
+	if spec.TimeZone != "" {
+		var err error
+		if location, err = time.LoadLocation(spec.TimeZone); err != nil {
+			return nil, fmt.Errorf("invalid maintenance window time zone %q: %v", spec.TimeZone, err)
+		}
+	}
+	start, err := cron.ParseStandard(spec.Start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid maintenance window start %q: %v", spec.Start, err)
+	}
+	if spec.Duration.Duration <= 0 {
+		return nil, fmt.Errorf("invalid maintenance window duration %s: must be positive", spec.Duration.Duration)
+	}
+	return &Window{start: start, duration: spec.Duration.Duration, location: location}, nil
+}
+
+// Open returns whether the window is open at now and, when it is not, when
+// it opens next. The window is open when it started within the last duration.
+func (w *Window) Open(now time.Time) (bool, time.Time) {
+	now = now.In(w.location)
+	if start := w.start.Next(now.Add(-w.duration)); !start.After(now) {
+		return true, start
+	}
+	return false, w.start.Next(now)
+}
@@ -0,0 +1,57 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package powerschedule
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+var _ = Describe("Window", func() {
+	var window *Window
+	seoul, _ := time.LoadLocation("Asia/Seoul")
+
+	BeforeEach(func() {
+		var err error
+		// Saturdays from 02:00 to 06:00
+		window, err = ParseWindow(&vmv1.MaintenanceWindow{
+			Start: "0 2 * * 6", Duration: metav1.Duration{Duration: 4 * time.Hour}, TimeZone: "Asia/Seoul",
+		})
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("is open within the duration after the start", func() {
+		open, _ := window.Open(time.Date(2024, 1, 6, 5, 59, 0, 0, seoul))
+		Expect(open).To(BeTrue())
+	})
+
+	It("reports when it opens next while closed", func() {
+		open, next := window.Open(time.Date(2024, 1, 6, 6, 0, 0, 0, seoul))
+		Expect(open).To(BeFalse())
+		Expect(next).To(BeTemporally("==", time.Date(2024, 1, 13, 2, 0, 0, 0, seoul)))
+	})
+
+	It("rejects a window without a duration", func() {
+		_, err := ParseWindow(&vmv1.MaintenanceWindow{Start: "0 2 * * 6"})
+		Expect(err).To(HaveOccurred())
+	})
+})
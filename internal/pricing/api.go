@@ -0,0 +1,128 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"vm.cloudclub.io/internal/ncp"
+)
+
+// APICatalog is a Catalog of the list prices of the billing API, read once
+// per region and refreshed at most every TTL.
+type APICatalog struct {
+	client *ncp.Client
+	TTL    time.Duration
+
+	mu      sync.Mutex
+	regions map[string]*regionRates
+}
+
+type regionRates struct {
+	fetched       time.Time
+	servers       map[string]Rate
+	blockStorages map[string]Rate
+}
+
+func NewAPICatalog(client *ncp.Client, ttl time.Duration) *APICatalog {
+	return &APICatalog{
+		client:  client,
+		TTL:     ttl,
+		regions: map[string]*regionRates{},
+	}
+}
+
+func (c *APICatalog) ServerRate(ctx context.Context, regionCode, serverProductCode string) (Rate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rates, err := c.region(ctx, regionCode)
+	if err != nil {
+		return Rate{}, err
+	}
+	rate, ok := rates.servers[serverProductCode]
+	if !ok {
+		return Rate{}, fmt.Errorf("%w: server %s in region %q", ErrNoPrice, serverProductCode, regionCode)
+	}
+	return rate, nil
+}
+
+// BlockStorageRate returns the rate of the block storage product whose
+// product type is volumeType.
+func (c *APICatalog) BlockStorageRate(ctx context.Context, regionCode, volumeType string) (Rate, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	rates, err := c.region(ctx, regionCode)
+	if err != nil {
+		return Rate{}, err
+	}
+	rate, ok := rates.blockStorages[volumeType]
+	if !ok {
+		return Rate{}, fmt.Errorf("%w: %s block storage in region %q", ErrNoPrice, volumeType, regionCode)
+	}
+	return rate, nil
+}
+
+// region returns the rates of a region, listing them when they are missing
+// or older than TTL. It must be called with mu held.
+func (c *APICatalog) region(ctx context.Context, regionCode string) (*regionRates, error) {
+	if rates, ok := c.regions[regionCode]; ok && time.Since(rates.fetched) < c.TTL {
+		return rates, nil
+	}
+	servers, err := c.client.GetProductPriceList(ctx, regionCode, ncp.ProductItemKindServer)
+	if err != nil {
+		return nil, err
+	}
+	blockStorages, err := c.client.GetProductPriceList(ctx, regionCode, ncp.ProductItemKindBlockStorage)
+	if err != nil {
+		return nil, err
+	}
+	rates := &regionRates{
+		fetched:       time.Now(),
+		servers:       map[string]Rate{},
+		blockStorages: map[string]Rate{},
+	}
+	for _, p := range servers {
+		rates.servers[p.ProductCode] = productRate(p)
+	}
+	for _, p := range blockStorages {
+		rates.blockStorages[p.ProductType.Code] = productRate(p)
+	}
+	c.regions[regionCode] = rates
+	return rates, nil
+}
+
+// productRate takes the meter rate and fixed sum prices of a product.
+func productRate(p ncp.ProductPrice) Rate {
+	var rate Rate
+	for _, price := range p.PriceList {
+		switch price.PriceType.Code {
+		case ncp.PriceTypeMeterRate:
+			rate.Hourly = price.Price
+		case ncp.PriceTypeFixedSum:
+			rate.Monthly = price.Price
+		default:
+			continue
+		}
+		rate.Currency = price.PayCurrency.Code
+	}
+	return rate
+}
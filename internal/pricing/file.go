@@ -0,0 +1,116 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"sigs.k8s.io/yaml"
+)
+
+// File is the format of a price file, for clusters that cannot reach the
+// billing API or want to apply negotiated prices:
+//
+//	currency: KRW
+//	servers:
+//	- productCode: SVR.VSVR.STAND.C002.M008.NET.SSD.B050.G002
+//	  hourly: 96
+//	  monthly: 62000
+//	blockStorages:
+//	- volumeType: SSD
+//	  hourly: 0.14
+//	  monthly: 100
+//
+// Entries without a regionCode apply to every region.
+type File struct {
+	Currency      string             `json:"currency"`
+	Servers       []FileServer       `json:"servers,omitempty"`
+	BlockStorages []FileBlockStorage `json:"blockStorages,omitempty"`
+}
+
+type FileServer struct {
+	RegionCode  string  `json:"regionCode,omitempty"`
+	ProductCode string  `json:"productCode"`
+	Hourly      float64 `json:"hourly,omitempty"`
+	Monthly     float64 `json:"monthly,omitempty"`
+}
+
+// FileBlockStorage is the price of one GB of a volume type.
+type FileBlockStorage struct {
+	RegionCode string  `json:"regionCode,omitempty"`
+	VolumeType string  `json:"volumeType"`
+	Hourly     float64 `json:"hourly,omitempty"`
+	Monthly    float64 `json:"monthly,omitempty"`
+}
+
+// FileCatalog is a Catalog of the prices of a File.
+type FileCatalog struct {
+	file File
+}
+
+// LoadFile reads a price file in YAML or JSON.
+func LoadFile(path string) (*FileCatalog, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	c := &FileCatalog{}
+	if err := yaml.UnmarshalStrict(data, &c.file); err != nil {
+		return nil, fmt.Errorf("error parsing price file %s: %v", path, err)
+	}
+	if c.file.Currency == "" {
+		return nil, fmt.Errorf("price file %s has no currency", path)
+	}
+	return c, nil
+}
+
+func (c *FileCatalog) ServerRate(ctx context.Context, regionCode, serverProductCode string) (Rate, error) {
+	var found *FileServer
+	for i := range c.file.Servers {
+		s := &c.file.Servers[i]
+		if s.ProductCode != serverProductCode || (s.RegionCode != "" && s.RegionCode != regionCode) {
+			continue
+		}
+		// a region specific price wins over one for every region
+		if found == nil || s.RegionCode != "" {
+			found = s
+		}
+	}
+	if found == nil {
+		return Rate{}, fmt.Errorf("%w: server %s in region %q", ErrNoPrice, serverProductCode, regionCode)
+	}
+	return Rate{Currency: c.file.Currency, Hourly: found.Hourly, Monthly: found.Monthly}, nil
+}
+
+func (c *FileCatalog) BlockStorageRate(ctx context.Context, regionCode, volumeType string) (Rate, error) {
+	var found *FileBlockStorage
+	for i := range c.file.BlockStorages {
+		b := &c.file.BlockStorages[i]
+		if b.VolumeType != volumeType || (b.RegionCode != "" && b.RegionCode != regionCode) {
+			continue
+		}
+		if found == nil || b.RegionCode != "" {
+			found = b
+		}
+	}
+	if found == nil {
+		return Rate{}, fmt.Errorf("%w: %s block storage in region %q", ErrNoPrice, volumeType, regionCode)
+	}
+	return Rate{Currency: c.file.Currency, Hourly: found.Hourly, Monthly: found.Monthly}, nil
+}
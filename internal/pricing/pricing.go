@@ -0,0 +1,61 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package pricing estimates what servers and block storages cost from NCP
+// product prices, read from the billing API or from a price file.
+package pricing
+
+import (
+	"context"
+	"errors"
+
+	"vm.cloudclub.io/internal/ncp"
+)
+
+// HoursPerMonth converts between hourly and monthly prices, as NCP does.
+const HoursPerMonth = 730
+
+// ErrNoPrice is returned for products the catalog has no price for.
+var ErrNoPrice = errors.New("no price known for product")
+
+// Rate is the price of a server product, or of one GB of block storage.
+// Hourly applies to the meter rate fee system, Monthly to the fixed sum one;
+// either is zero when the product is not offered with it.
+type Rate struct {
+	Currency string  `json:"currency"`
+	Hourly   float64 `json:"hourly,omitempty"`
+	Monthly  float64 `json:"monthly,omitempty"`
+}
+
+// Catalog looks up the rates of products in a region.
+type Catalog interface {
+	ServerRate(ctx context.Context, regionCode, serverProductCode string) (Rate, error)
+	// BlockStorageRate returns the rate of one GB of a volume type.
+	BlockStorageRate(ctx context.Context, regionCode, volumeType string) (Rate, error)
+}
+
+// Cost returns what quantity units of a product charged at rate cost per
+// hour and per month under feeSystemTypeCode. Fixed sum products are charged
+// by the month and meter rate ones, the default, by the hour; the price of
+// the other period is derived from it.
+func Cost(rate Rate, feeSystemTypeCode string, quantity float64) (hourly, monthly float64) {
+	if (feeSystemTypeCode == ncp.PriceTypeFixedSum && rate.Monthly > 0) || rate.Hourly == 0 {
+		monthly = rate.Monthly * quantity
+		return monthly / HoursPerMonth, monthly
+	}
+	hourly = rate.Hourly * quantity
+	return hourly, hourly * HoursPerMonth
+}
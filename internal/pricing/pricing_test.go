@@ -0,0 +1,79 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pricing
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cost", func() {
+	rate := Rate{Currency: "KRW", Hourly: 100, Monthly: 60000}
+
+	It("charges meter rate products by the hour", func() {
+		hourly, monthly := Cost(rate, "MTRAT", 2)
+		Expect(hourly).To(BeNumerically("==", 200))
+		Expect(monthly).To(BeNumerically("==", 200*HoursPerMonth))
+	})
+
+	It("charges fixed sum products by the month", func() {
+		hourly, monthly := Cost(rate, "FXSUM", 2)
+		Expect(monthly).To(BeNumerically("==", 120000))
+		Expect(hourly).To(BeNumerically("~", 120000.0/HoursPerMonth))
+	})
+
+	It("falls back to the price the product has", func() {
+		_, monthly := Cost(Rate{Monthly: 80}, "", 10)
+		Expect(monthly).To(BeNumerically("==", 800))
+	})
+})
+
+var _ = Describe("FileCatalog", func() {
+	It("prefers region specific prices", func() {
+		path := filepath.Join(GinkgoT().TempDir(), "prices.yaml")
+		Expect(os.WriteFile(path, []byte(`
+currency: KRW
+servers:
+- productCode: SVR.VSVR.STAND.C002.M008.NET.SSD.B050.G002
+  hourly: 96
+- productCode: SVR.VSVR.STAND.C002.M008.NET.SSD.B050.G002
+  regionCode: JPN
+  hourly: 120
+blockStorages:
+- volumeType: SSD
+  monthly: 100
+`), 0o600)).To(Succeed())
+
+		catalog, err := LoadFile(path)
+		Expect(err).NotTo(HaveOccurred())
+		ctx := context.Background()
+
+		rate, err := catalog.ServerRate(ctx, "KR", "SVR.VSVR.STAND.C002.M008.NET.SSD.B050.G002")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rate).To(Equal(Rate{Currency: "KRW", Hourly: 96}))
+		rate, err = catalog.ServerRate(ctx, "JPN", "SVR.VSVR.STAND.C002.M008.NET.SSD.B050.G002")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rate.Hourly).To(BeNumerically("==", 120))
+
+		_, err = catalog.BlockStorageRate(ctx, "KR", "HDD")
+		Expect(err).To(MatchError(ErrNoPrice))
+	})
+})
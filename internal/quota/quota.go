@@ -0,0 +1,207 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package quota enforces the VMQuotas of a namespace on its Provisions.
+package quota
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"sigs.k8s.io/controller-runtime/pkg/client"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+// Shape is the size of a server product.
+type Shape struct {
+	VCPU      int64
+	MemoryGiB int64
+	// BootGiB is the size of the boot volume included in the product.
+	BootGiB int64
+}
+
+// ParseShape reads the size of a server product from its code, e.g.
+// SVR.VSVR.STAND.C002.M008.NET.SSD.B050.G002 has 2 vCPU, 8 GiB of memory
+// and a 50 GiB boot volume.
+func ParseShape(serverProductCode string) (Shape, error) {
+	var shape Shape
+	for _, part := range strings.Split(serverProductCode, ".") {
+		if len(part) < 2 {
+			continue
+		}
+		n, err := strconv.ParseInt(part[1:], 10, 64)
+		if err != nil {
+			continue
+		}
+		switch part[0] {
+		case 'C':
+			shape.VCPU = n
+		case 'M':
+			shape.MemoryGiB = n
+		case 'B':
+			shape.BootGiB = n
+		}
+	}
+	if shape.VCPU == 0 || shape.MemoryGiB == 0 {
+		return Shape{}, fmt.Errorf("cannot tell the size of server product %q", serverProductCode)
+	}
+	return shape, nil
+}
+
+// Counted reports whether a Provision counts against quotas: those being
+// deleted do not.
+func Counted(p *vmv1.Provision) bool {
+	return p.DeletionTimestamp == nil && p.Spec.Phase != vmv1.ProvisionPhaseDelete
+}
+
+// Usage returns what a Provision uses. When the size of its server product
+// is unknown, only the server and its block storages are counted and the
+// error is returned alongside.
+func Usage(p *vmv1.Provision) (vmv1.VMQuotaUsage, error) {
+	usage := vmv1.VMQuotaUsage{Servers: 1}
+	shape, err := ParseShape(p.Spec.Server.ProductCode)
+	usage.VCPU, usage.MemoryGiB = shape.VCPU, shape.MemoryGiB
+
+	boot := shape.BootGiB
+	for _, m := range p.Spec.BlockStorageMappings {
+		if m.Order == 0 {
+			if m.BlockStorageSize > 0 {
+				boot = int64(m.BlockStorageSize)
+			}
+			continue
+		}
+		usage.StorageGiB += int64(m.BlockStorageSize)
+	}
+	usage.StorageGiB += boot
+	return usage, err
+}
+
+// NamespaceUsage sums the usage of the counted Provisions of a namespace
+// other than the one named except.
+func NamespaceUsage(ctx context.Context, reader client.Reader, namespace, except string) (vmv1.VMQuotaUsage, error) {
+	provisions := &vmv1.ProvisionList{}
+	if err := reader.List(ctx, provisions, client.InNamespace(namespace)); err != nil {
+		return vmv1.VMQuotaUsage{}, err
+	}
+	var used vmv1.VMQuotaUsage
+	for i := range provisions.Items {
+		p := &provisions.Items[i]
+		if p.Name == except || !Counted(p) {
+			continue
+		}
+		u, _ := Usage(p)
+		used = add(used, u)
+	}
+	return used, nil
+}
+
+func add(a, b vmv1.VMQuotaUsage) vmv1.VMQuotaUsage {
+	return vmv1.VMQuotaUsage{
+		Servers:    a.Servers + b.Servers,
+		VCPU:       a.VCPU + b.VCPU,
+		MemoryGiB:  a.MemoryGiB + b.MemoryGiB,
+		StorageGiB: a.StorageGiB + b.StorageGiB,
+	}
+}
+
+// ExceededError is returned for a Provision the VMQuotas of its namespace do not allow.
+type ExceededError struct {
+	Quota   string
+	Reasons []string
+}
+
+func (e *ExceededError) Error() string {
+	return fmt.Sprintf("exceeds VMQuota %s: %s", e.Quota, strings.Join(e.Reasons, ", "))
+}
+
+// Check returns an *ExceededError when a Provision does not fit the VMQuotas
+// of its namespace. For an update, old is the Provision before it: only
+// changes that grow its usage or change its products are checked, so a
+// Provision already over a lowered quota can still be stopped or edited.
+func Check(ctx context.Context, reader client.Reader, p, old *vmv1.Provision) error {
+	if !Counted(p) {
+		return nil
+	}
+	quotas := &vmv1.VMQuotaList{}
+	if err := reader.List(ctx, quotas, client.InNamespace(p.Namespace)); err != nil {
+		return err
+	}
+	if len(quotas.Items) == 0 {
+		return nil
+	}
+	used, err := NamespaceUsage(ctx, reader, p.Namespace, p.Name)
+	if err != nil {
+		return err
+	}
+
+	requested, shapeErr := Usage(p)
+	var previous vmv1.VMQuotaUsage
+	productChanged, imageChanged := true, true
+	if old != nil && Counted(old) {
+		previous, _ = Usage(old)
+		productChanged = old.Spec.Server.ProductCode != p.Spec.Server.ProductCode
+		imageChanged = old.Spec.Server.ImageProductCode != p.Spec.Server.ImageProductCode
+	}
+
+	for _, q := range quotas.Items {
+		var reasons []string
+		if productChanged && !allowed(q.Spec.AllowedServerProductCodes, p.Spec.Server.ProductCode) {
+			reasons = append(reasons, fmt.Sprintf("server product %q is not allowed", p.Spec.Server.ProductCode))
+		}
+		if imageChanged && !allowed(q.Spec.AllowedServerImageProductCodes, p.Spec.Server.ImageProductCode) {
+			reasons = append(reasons, fmt.Sprintf("server image %q is not allowed", p.Spec.Server.ImageProductCode))
+		}
+		if shapeErr != nil && productChanged && (q.Spec.Hard.VCPU != nil || q.Spec.Hard.MemoryGiB != nil) {
+			reasons = append(reasons, shapeErr.Error())
+		}
+		for _, l := range []struct {
+			name                      string
+			limit                     *int64
+			used, requested, previous int64
+		}{
+			{"servers", q.Spec.Hard.Servers, used.Servers, requested.Servers, previous.Servers},
+			{"vcpu", q.Spec.Hard.VCPU, used.VCPU, requested.VCPU, previous.VCPU},
+			{"memoryGiB", q.Spec.Hard.MemoryGiB, used.MemoryGiB, requested.MemoryGiB, previous.MemoryGiB},
+			{"storageGiB", q.Spec.Hard.StorageGiB, used.StorageGiB, requested.StorageGiB, previous.StorageGiB},
+		} {
+			if l.limit != nil && l.requested > l.previous && l.used+l.requested > *l.limit {
+				reasons = append(reasons, fmt.Sprintf("%s: requested %d, used %d, limited to %d",
+					l.name, l.requested, l.used, *l.limit))
+			}
+		}
+		if len(reasons) > 0 {
+			return &ExceededError{Quota: q.Name, Reasons: reasons}
+		}
+	}
+	return nil
+}
+
+// allowed reports whether code matches one of patterns, or patterns is empty.
+func allowed(patterns []string, code string) bool {
+	if len(patterns) == 0 {
+		return true
+	}
+	for _, pattern := range patterns {
+		if ok, _ := path.Match(pattern, code); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package quota
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/utils/pointer"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+const standard2x8 = "SVR.VSVR.STAND.C002.M008.NET.SSD.B050.G002"
+
+func provision(name, productCode string) *vmv1.Provision {
+	return &vmv1.Provision{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: name},
+		Spec: vmv1.ProvisionSpec{
+			Server: vmv1.Server{ProductCode: productCode, ImageProductCode: "SW.VSVR.OS.LNX64.UBNTU.SVR2004.B050"},
+			BlockStorageMappings: []vmv1.BlockStorageMapping{
+				{Order: 1, BlockStorageSize: 100},
+			},
+		},
+	}
+}
+
+var _ = Describe("ParseShape", func() {
+	It("reads vCPU, memory and boot volume from the product code", func() {
+		Expect(ParseShape(standard2x8)).To(Equal(Shape{VCPU: 2, MemoryGiB: 8, BootGiB: 50}))
+	})
+
+	It("rejects codes without a size", func() {
+		_, err := ParseShape("")
+		Expect(err).To(HaveOccurred())
+	})
+})
+
+var _ = Describe("Check", func() {
+	var scheme *runtime.Scheme
+
+	BeforeEach(func() {
+		scheme = runtime.NewScheme()
+		Expect(vmv1.AddToScheme(scheme)).To(Succeed())
+	})
+
+	check := func(p, old *vmv1.Provision, objects ...*vmv1.Provision) error {
+		quota := &vmv1.VMQuota{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "team-a", Name: "default"},
+			Spec: vmv1.VMQuotaSpec{
+				Hard:                      vmv1.VMQuotaLimits{VCPU: pointer.Int64(4), StorageGiB: pointer.Int64(400)},
+				AllowedServerProductCodes: []string{"SVR.VSVR.STAND.*"},
+			},
+		}
+		builder := fake.NewClientBuilder().WithScheme(scheme).WithObjects(quota)
+		for _, o := range objects {
+			builder = builder.WithObjects(o)
+		}
+		return Check(context.Background(), builder.Build(), p, old)
+	}
+
+	It("allows Provisions within the quota", func() {
+		Expect(check(provision("b", standard2x8), nil, provision("a", standard2x8))).To(Succeed())
+	})
+
+	It("rejects Provisions over the quota", func() {
+		err := check(provision("c", standard2x8), nil, provision("a", standard2x8), provision("b", standard2x8))
+		Expect(err).To(BeAssignableToTypeOf(&ExceededError{}))
+		Expect(err.Error()).To(ContainSubstring("vcpu: requested 2, used 4, limited to 4"))
+	})
+
+	It("rejects products that are not allowed", func() {
+		err := check(provision("a", "SVR.VSVR.HICPU.C002.M004.NET.SSD.B050.G002"), nil)
+		Expect(err).To(MatchError(ContainSubstring("is not allowed")))
+	})
+
+	It("allows updates that do not grow a Provision over a lowered quota", func() {
+		old := provision("c", standard2x8)
+		p := old.DeepCopy()
+		p.Spec.Phase = vmv1.ProvisionPhaseStop
+		Expect(check(p, old, provision("a", standard2x8), provision("b", standard2x8))).To(Succeed())
+	})
+
+	It("ignores Provisions being deleted", func() {
+		deleted := provision("b", standard2x8)
+		deleted.Spec.Phase = vmv1.ProvisionPhaseDelete
+		Expect(check(provision("c", standard2x8), nil, provision("a", standard2x8), deleted)).To(Succeed())
+	})
+})
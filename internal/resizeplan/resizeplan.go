@@ -0,0 +1,87 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package resizeplan decides the steps of a change of a server's product,
+// which NCP only allows on a stopped server.
+package resizeplan
+
+import (
+	"fmt"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+// Action is what a step of a resize does to the server.
+type Action string
+
+const (
+	// ActionNone does nothing, either to wait for the server or to move on.
+	ActionNone Action = ""
+	// ActionCallPreStopHook calls the resize's pre-stop hook, if any.
+	ActionCallPreStopHook Action = "CallPreStopHook"
+	ActionStop            Action = "Stop"
+	ActionChangeSpec      Action = "ChangeSpec"
+	ActionStart           Action = "Start"
+	// ActionFinish ends the resize.
+	ActionFinish Action = "Finish"
+)
+
+// Step is the next step of a resize.
+type Step struct {
+	Action Action
+	// Next is the step the resize is at once Action succeeded.
+	Next vmv1.ResizeStep
+	// Continue reports whether Next can act on the same server state, so the
+	// following step is decided without reading the server again.
+	Continue bool
+}
+
+// Next decides the step of the resize in status given the server's current
+// state. Steps after the pre-stop hook wait while the server is changing.
+func Next(status *vmv1.ResizeStatus, instance *ncp.ServerInstance) (Step, error) {
+	wait := Step{Action: ActionNone, Next: status.Step}
+	if status.Step != vmv1.ResizeStepPreStop && !instance.Stable() {
+		return wait, nil
+	}
+
+	switch status.Step {
+	case vmv1.ResizeStepPreStop:
+		return Step{Action: ActionCallPreStopHook, Next: vmv1.ResizeStepStopping, Continue: true}, nil
+
+	case vmv1.ResizeStepStopping:
+		if instance.ServerInstanceStatus.Code == ncp.ServerStatusStopped {
+			return Step{Action: ActionNone, Next: vmv1.ResizeStepChangingSpec, Continue: true}, nil
+		}
+		return Step{Action: ActionStop, Next: vmv1.ResizeStepStopping}, nil
+
+	case vmv1.ResizeStepChangingSpec:
+		switch {
+		case instance.ServerProductCode != status.ToProductCode:
+			return Step{Action: ActionChangeSpec, Next: vmv1.ResizeStepChangingSpec}, nil
+		case !status.Restart:
+			return Step{Action: ActionFinish, Next: vmv1.ResizeStepChangingSpec}, nil
+		}
+		return Step{Action: ActionNone, Next: vmv1.ResizeStepStarting, Continue: true}, nil
+
+	case vmv1.ResizeStepStarting:
+		if instance.ServerInstanceStatus.Code == ncp.ServerStatusRunning {
+			return Step{Action: ActionFinish, Next: vmv1.ResizeStepStarting}, nil
+		}
+		return Step{Action: ActionStart, Next: vmv1.ResizeStepStarting}, nil
+	}
+	return wait, fmt.Errorf("unknown resize step %q", status.Step)
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package resizeplan
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/ncp"
+)
+
+const (
+	fromProduct = "SVR.VSVR.STAND.C002.M008.NET.SSD.B050.G002"
+	toProduct   = "SVR.VSVR.STAND.C004.M016.NET.SSD.B050.G002"
+)
+
+func server(status, operation, product string) *ncp.ServerInstance {
+	return &ncp.ServerInstance{
+		ServerInstanceStatus:    ncp.CommonCode{Code: status},
+		ServerInstanceOperation: ncp.CommonCode{Code: operation},
+		ServerProductCode:       product,
+	}
+}
+
+var _ = Describe("Next", func() {
+	DescribeTable("decides the step of a resize",
+		func(step vmv1.ResizeStep, restart bool, instance *ncp.ServerInstance, want Step) {
+			status := &vmv1.ResizeStatus{Step: step, FromProductCode: fromProduct, ToProductCode: toProduct, Restart: restart}
+			Expect(Next(status, instance)).To(Equal(want))
+		},
+		Entry("calls the pre-stop hook even while the server is changing",
+			vmv1.ResizeStepPreStop, true, server(ncp.ServerStatusRunning, "RESTA", fromProduct),
+			Step{Action: ActionCallPreStopHook, Next: vmv1.ResizeStepStopping, Continue: true}),
+		Entry("stops a running server",
+			vmv1.ResizeStepStopping, true, server(ncp.ServerStatusRunning, ncp.ServerOperationNone, fromProduct),
+			Step{Action: ActionStop, Next: vmv1.ResizeStepStopping}),
+		Entry("waits while the server is stopping",
+			vmv1.ResizeStepStopping, true, server(ncp.ServerStatusRunning, "STOP", fromProduct),
+			Step{Action: ActionNone, Next: vmv1.ResizeStepStopping}),
+		Entry("moves on to the spec change once stopped",
+			vmv1.ResizeStepStopping, true, server(ncp.ServerStatusStopped, ncp.ServerOperationNone, fromProduct),
+			Step{Action: ActionNone, Next: vmv1.ResizeStepChangingSpec, Continue: true}),
+		Entry("changes the product of a stopped server",
+			vmv1.ResizeStepChangingSpec, true, server(ncp.ServerStatusStopped, ncp.ServerOperationNone, fromProduct),
+			Step{Action: ActionChangeSpec, Next: vmv1.ResizeStepChangingSpec}),
+		Entry("waits while the product is changing",
+			vmv1.ResizeStepChangingSpec, true, server(ncp.ServerStatusStopped, "SPCHG", fromProduct),
+			Step{Action: ActionNone, Next: vmv1.ResizeStepChangingSpec}),
+		Entry("moves on to the start once changed when the server was running",
+			vmv1.ResizeStepChangingSpec, true, server(ncp.ServerStatusStopped, ncp.ServerOperationNone, toProduct),
+			Step{Action: ActionNone, Next: vmv1.ResizeStepStarting, Continue: true}),
+		Entry("finishes once changed when the server was stopped",
+			vmv1.ResizeStepChangingSpec, false, server(ncp.ServerStatusStopped, ncp.ServerOperationNone, toProduct),
+			Step{Action: ActionFinish, Next: vmv1.ResizeStepChangingSpec}),
+		Entry("starts the resized server",
+			vmv1.ResizeStepStarting, true, server(ncp.ServerStatusStopped, ncp.ServerOperationNone, toProduct),
+			Step{Action: ActionStart, Next: vmv1.ResizeStepStarting}),
+		Entry("finishes once the server runs again",
+			vmv1.ResizeStepStarting, true, server(ncp.ServerStatusRunning, ncp.ServerOperationNone, toProduct),
+			Step{Action: ActionFinish, Next: vmv1.ResizeStepStarting}),
+	)
+
+	It("rejects an unknown step", func() {
+		status := &vmv1.ResizeStatus{Step: "Rebooting"}
+		_, err := Next(status, server(ncp.ServerStatusRunning, ncp.ServerOperationNone, fromProduct))
+		Expect(err).To(MatchError(ContainSubstring("Rebooting")))
+	})
+})
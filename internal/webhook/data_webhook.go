@@ -0,0 +1,79 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+// datalog is for logging in this package.
+var datalog = logf.Log.WithName("data-resource")
+
+// DataValidator rejects Data that shrink their volume.
+type DataValidator struct{}
+
+// SetupWebhookWithManager registers the webhook with the Manager.
+func (v *DataValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&vmv1.Data{}).
+		WithValidator(v).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-vm-cloudclub-io-v1-data,mutating=false,failurePolicy=fail,sideEffects=None,groups=vm.cloudclub.io,resources=data,verbs=update,versions=v1,name=vdata.kb.io,admissionReviewVersions=v1
+
+var _ admission.CustomValidator = &DataValidator{}
+
+// ValidateCreate implements admission.CustomValidator.
+func (v *DataValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+// ValidateUpdate implements admission.CustomValidator. The size may not drop
+// below the previous spec or the size the volume already has.
+func (v *DataValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	data, ok := newObj.(*vmv1.Data)
+	if !ok {
+		return nil, fmt.Errorf("expected a Data but got %T", newObj)
+	}
+	old, ok := oldObj.(*vmv1.Data)
+	if !ok {
+		return nil, fmt.Errorf("expected a Data but got %T", oldObj)
+	}
+	datalog.V(1).Info("validate update", "namespace", data.Namespace, "name", data.Name)
+	size := old.Spec.BlockStorageSize
+	if old.Status.BlockStorageSize > size {
+		size = old.Status.BlockStorageSize
+	}
+	if want := data.Spec.BlockStorageSize; want != 0 && want < size {
+		return nil, fmt.Errorf("blockStorageSize cannot shrink from %d to %d GiB", size, want)
+	}
+	return nil, nil
+}
+
+// ValidateDelete implements admission.CustomValidator.
+func (v *DataValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
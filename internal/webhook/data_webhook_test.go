@@ -0,0 +1,51 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+var _ = Describe("DataValidator", func() {
+	data := func(specSize, statusSize int) *vmv1.Data {
+		return &vmv1.Data{
+			Spec:   vmv1.DataSpec{BlockStorageSize: specSize},
+			Status: vmv1.DataStatus{BlockStorageSize: statusSize},
+		}
+	}
+
+	DescribeTable("ValidateUpdate",
+		func(old *vmv1.Data, size int, allowed bool) {
+			_, err := (&DataValidator{}).ValidateUpdate(context.Background(), old, data(size, 0))
+			if allowed {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(ContainSubstring("cannot shrink")))
+			}
+		},
+		Entry("grows the volume", data(100, 100), 200, true),
+		Entry("keeps the size", data(100, 100), 100, true),
+		Entry("shrinks below the previous spec", data(100, 0), 50, false),
+		Entry("shrinks below the size the volume has", data(50, 100), 80, false),
+		Entry("leaves the size to the snapshot it restores", data(100, 100), 0, true),
+	)
+})
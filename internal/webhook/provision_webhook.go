@@ -0,0 +1,183 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package webhook holds the admission webhooks of the vm.cloudclub.io API.
+package webhook
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/webhook/admission"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+	"vm.cloudclub.io/internal/policy"
+	"vm.cloudclub.io/internal/powerschedule"
+	"vm.cloudclub.io/internal/quota"
+)
+
+// provisionlog is for logging in this package.
+var provisionlog = logf.Log.WithName("provision-resource")
+
+// ProvisionValidator rejects Provisions with an invalid power schedule,
+// maintenance window or expiry, that shrink a disk, that break a
+// ProvisionPolicy or that the VMQuotas of their namespace do not allow.
+type ProvisionValidator struct {
+	Client client.Reader
+}
+
+// SetupWebhookWithManager registers the webhook with the Manager.
+func (v *ProvisionValidator) SetupWebhookWithManager(mgr ctrl.Manager) error {
+	return ctrl.NewWebhookManagedBy(mgr).
+		For(&vmv1.Provision{}).
+		WithValidator(v).
+		Complete()
+}
+
+//+kubebuilder:webhook:path=/validate-vm-cloudclub-io-v1-provision,mutating=false,failurePolicy=fail,sideEffects=None,groups=vm.cloudclub.io,resources=provisions,verbs=create;update,versions=v1,name=vprovision.kb.io,admissionReviewVersions=v1
+
+var _ admission.CustomValidator = &ProvisionValidator{}
+
+// ValidateCreate implements admission.CustomValidator.
+func (v *ProvisionValidator) ValidateCreate(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	p, ok := obj.(*vmv1.Provision)
+	if !ok {
+		return nil, fmt.Errorf("expected a Provision but got %T", obj)
+	}
+	provisionlog.V(1).Info("validate create", "namespace", p.Namespace, "name", p.Name)
+	if err := validatePowerSchedule(p); err != nil {
+		return nil, err
+	}
+	if err := validateResize(p); err != nil {
+		return nil, err
+	}
+	if err := validateExpiry(p); err != nil {
+		return nil, err
+	}
+	if err := policy.Check(ctx, v.Client, p, nil); err != nil {
+		return nil, err
+	}
+	return nil, quota.Check(ctx, v.Client, p, nil)
+}
+
+// ValidateUpdate implements admission.CustomValidator.
+func (v *ProvisionValidator) ValidateUpdate(ctx context.Context, oldObj, newObj runtime.Object) (admission.Warnings, error) {
+	p, ok := newObj.(*vmv1.Provision)
+	if !ok {
+		return nil, fmt.Errorf("expected a Provision but got %T", newObj)
+	}
+	old, ok := oldObj.(*vmv1.Provision)
+	if !ok {
+		return nil, fmt.Errorf("expected a Provision but got %T", oldObj)
+	}
+	provisionlog.V(1).Info("validate update", "namespace", p.Namespace, "name", p.Name)
+	if err := validatePowerSchedule(p); err != nil {
+		return nil, err
+	}
+	if err := validateResize(p); err != nil {
+		return nil, err
+	}
+	if err := validateExpiry(p); err != nil {
+		return nil, err
+	}
+	if err := validateBlockStorageSizes(p, old); err != nil {
+		return nil, err
+	}
+	if err := policy.Check(ctx, v.Client, p, old); err != nil {
+		return nil, err
+	}
+	return nil, quota.Check(ctx, v.Client, p, old)
+}
+
+// ValidateDelete implements admission.CustomValidator.
+func (v *ProvisionValidator) ValidateDelete(ctx context.Context, obj runtime.Object) (admission.Warnings, error) {
+	return nil, nil
+}
+
+func validatePowerSchedule(p *vmv1.Provision) error {
+	if p.Spec.PowerSchedule == nil {
+		return nil
+	}
+	_, err := powerschedule.Parse(p.Spec.PowerSchedule)
+	return err
+}
+
+func validateResize(p *vmv1.Provision) error {
+	if p.Spec.Resize == nil {
+		return nil
+	}
+	if hook := p.Spec.Resize.PreStopHook; hook != nil {
+		if err := validateHookURL(hook.URL); err != nil {
+			return err
+		}
+	}
+	if p.Spec.Resize.MaintenanceWindow == nil {
+		return nil
+	}
+	_, err := powerschedule.ParseWindow(p.Spec.Resize.MaintenanceWindow)
+	return err
+}
+
+// validateHookURL rejects pre-stop hook URLs that are not absolute http or
+// https URLs.
+func validateHookURL(hookURL string) error {
+	u, err := url.Parse(hookURL)
+	if err != nil {
+		return fmt.Errorf("resize.preStopHook.url: %v", err)
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("resize.preStopHook.url %q must be an absolute http or https URL", hookURL)
+	}
+	return nil
+}
+
+// validateBlockStorageSizes rejects mappings that shrink a disk below its
+// previous or current size, since NCP volumes can only grow.
+func validateBlockStorageSizes(p, old *vmv1.Provision) error {
+	current := map[int]int{}
+	for _, m := range old.Spec.BlockStorageMappings {
+		current[m.Order] = m.BlockStorageSize
+	}
+	for _, b := range old.Status.BlockStorages {
+		if b.BlockStorageSize > current[b.Order] {
+			current[b.Order] = b.BlockStorageSize
+		}
+	}
+	for _, m := range p.Spec.BlockStorageMappings {
+		if size, ok := current[m.Order]; ok && m.BlockStorageSize != 0 && m.BlockStorageSize < size {
+			return fmt.Errorf("blockStorageMappingList order %d: blockStorageSize cannot shrink from %d to %d GiB",
+				m.Order, size, m.BlockStorageSize)
+		}
+	}
+	return nil
+}
+
+func validateExpiry(p *vmv1.Provision) error {
+	value, ok := p.Annotations[vmv1.ExpiresAtAnnotation]
+	if !ok {
+		return nil
+	}
+	if _, err := time.Parse(time.RFC3339, value); err != nil {
+		return fmt.Errorf("annotation %s must be an RFC 3339 timestamp: %v", vmv1.ExpiresAtAnnotation, err)
+	}
+	return nil
+}
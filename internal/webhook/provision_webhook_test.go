@@ -0,0 +1,84 @@
+/*
+Copyright 2023.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package webhook
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	vmv1 "vm.cloudclub.io/api/v1"
+)
+
+var _ = Describe("validateBlockStorageSizes", func() {
+	provision := func(sizes ...int) *vmv1.Provision {
+		p := &vmv1.Provision{}
+		for i, size := range sizes {
+			p.Spec.BlockStorageMappings = append(p.Spec.BlockStorageMappings,
+				vmv1.BlockStorageMapping{Order: i + 1, BlockStorageSize: size})
+		}
+		return p
+	}
+	observed := func(p *vmv1.Provision, sizes ...int) *vmv1.Provision {
+		for i, size := range sizes {
+			p.Status.BlockStorages = append(p.Status.BlockStorages,
+				vmv1.BlockStorageStatus{Order: i + 1, BlockStorageSize: size})
+		}
+		return p
+	}
+
+	DescribeTable("validates the sizes of an update",
+		func(old, p *vmv1.Provision, allowed bool) {
+			err := validateBlockStorageSizes(p, old)
+			if allowed {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(ContainSubstring("cannot shrink")))
+			}
+		},
+		Entry("grows a disk", provision(100, 50), provision(100, 80), true),
+		Entry("adds a disk", provision(100), provision(100, 10), true),
+		Entry("shrinks below the previous spec", provision(100, 50), provision(100, 40), false),
+		Entry("shrinks below the size the disk has", observed(provision(50), 100), provision(80), false),
+		Entry("leaves the size to the snapshot it restores", observed(provision(100), 100), provision(0), true),
+	)
+
+	It("names the disk that shrinks", func() {
+		err := validateBlockStorageSizes(provision(100, 40), provision(100, 50))
+		Expect(err).To(MatchError("blockStorageMappingList order 2: blockStorageSize cannot shrink from 50 to 40 GiB"))
+	})
+})
+
+var _ = Describe("validateResize", func() {
+	DescribeTable("validates the pre-stop hook URL",
+		func(url string, allowed bool) {
+			p := &vmv1.Provision{}
+			p.Spec.Resize = &vmv1.ResizePolicy{PreStopHook: &vmv1.PreStopHook{URL: url}}
+			err := validateResize(p)
+			if allowed {
+				Expect(err).NotTo(HaveOccurred())
+			} else {
+				Expect(err).To(MatchError(ContainSubstring("resize.preStopHook.url")))
+			}
+		},
+		Entry("http", "http://drain.default.svc:8080/hook", true),
+		Entry("https", "https://hooks.example.com/resize", true),
+		Entry("another scheme", "file:///etc/passwd", false),
+		Entry("no host", "http:///hook", false),
+		Entry("a relative URL", "/hook", false),
+		Entry("a malformed URL", "http://[::1", false),
+	)
+})